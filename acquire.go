@@ -0,0 +1,201 @@
+package main
+
+import (
+	"image"
+	"time"
+
+	"github.com/nonoo/jampec/track"
+	"gocv.io/x/gocv"
+)
+
+const acquisitionRectMargin = 10
+
+// Acquisition looks for a moving point source on the frames, and starts the tracker on it when one is
+// found. Moving pixels are found by frame differencing or background subtraction.
+type acquisition struct {
+	method   string
+	acquirer *track.Acquirer
+
+	gray      gocv.Mat
+	prevGray  gocv.Mat
+	mask      gocv.Mat
+	labels    gocv.Mat
+	stats     gocv.Mat
+	centroids gocv.Mat
+	mog2      *gocv.BackgroundSubtractorMOG2
+	knn       *gocv.BackgroundSubtractorKNN
+
+	lastFrameTime time.Time
+	// The last hint, only used by the overlay.
+	hint *track.AcquisitionHint
+}
+
+func validAcquisitionMethod(method string) bool {
+	return method == "diff" || method == "mog2" || method == "knn"
+}
+
+func newAcquisition(config DevConfig) *acquisition {
+	a := &acquisition{
+		method:    config.Acquisition.Method,
+		acquirer:  track.NewAcquirer(config.Acquisition.AcquisitionConfig),
+		gray:      gocv.NewMat(),
+		prevGray:  gocv.NewMat(),
+		mask:      gocv.NewMat(),
+		labels:    gocv.NewMat(),
+		stats:     gocv.NewMat(),
+		centroids: gocv.NewMat(),
+	}
+	switch a.method {
+	case "mog2":
+		b := gocv.NewBackgroundSubtractorMOG2WithParams(config.Acquisition.History, 16, false)
+		a.mog2 = &b
+	case "knn":
+		b := gocv.NewBackgroundSubtractorKNNWithParams(config.Acquisition.History, 400, false)
+		a.knn = &b
+	}
+	return a
+}
+
+func (a *acquisition) close() {
+	a.gray.Close()
+	a.prevGray.Close()
+	a.mask.Close()
+	a.labels.Close()
+	a.stats.Close()
+	a.centroids.Close()
+	if a.mog2 != nil {
+		a.mog2.Close()
+	}
+	if a.knn != nil {
+		a.knn.Close()
+	}
+}
+
+// Processes a frame captured at frameTime and returns the rectangle of the found target. The capture times
+// give the blob speeds, as replayed frames don't arrive at the rate they were captured.
+func (a *acquisition) update(img gocv.Mat, frameTime time.Time, threshold int,
+	hint *track.AcquisitionHint) (image.Rectangle, bool) {
+
+	var dt float64
+	if !a.lastFrameTime.IsZero() {
+		dt = frameTime.Sub(a.lastFrameTime).Seconds()
+	}
+	a.lastFrameTime = frameTime
+	a.hint = hint
+
+	if img.Channels() == 1 {
+		img.CopyTo(&a.gray)
+	} else {
+		gocv.CvtColor(img, &a.gray, gocv.ColorBGRToGray)
+	}
+
+	switch {
+	case a.mog2 != nil:
+		a.mog2.Apply(a.gray, &a.mask)
+	case a.knn != nil:
+		a.knn.Apply(a.gray, &a.mask)
+	default:
+		if a.prevGray.Empty() {
+			a.gray.CopyTo(&a.prevGray)
+			return image.Rectangle{}, false
+		}
+		// Saturating subtraction only keeps the new position of bright moving objects, not the old one.
+		gocv.Subtract(a.gray, a.prevGray, &a.mask)
+		gocv.Threshold(a.mask, &a.mask, float32(threshold), 255, gocv.ThresholdBinary)
+		a.gray.CopyTo(&a.prevGray)
+	}
+
+	return a.acquirer.Update(a.blobs(), hint, dt)
+}
+
+// Returns the connected regions of the foreground mask.
+func (a *acquisition) blobs() []track.Blob {
+	n := gocv.ConnectedComponentsWithStats(a.mask, &a.labels, &a.stats, &a.centroids)
+	var res []track.Blob
+	// Label 0 is the background.
+	for i := 1; i < n; i++ {
+		left := int(a.stats.GetIntAt(i, int(gocv.CC_STAT_LEFT)))
+		top := int(a.stats.GetIntAt(i, int(gocv.CC_STAT_TOP)))
+		w := int(a.stats.GetIntAt(i, int(gocv.CC_STAT_WIDTH)))
+		h := int(a.stats.GetIntAt(i, int(gocv.CC_STAT_HEIGHT)))
+		res = append(res, track.Blob{
+			// OpenCV uses pixel centers at integer coordinates.
+			X:      a.centroids.GetDoubleAt(i, 0) + 0.5,
+			Y:      a.centroids.GetDoubleAt(i, 1) + 0.5,
+			Bounds: image.Rect(left, top, left+w, top+h),
+			Area:   int(a.stats.GetIntAt(i, int(gocv.CC_STAT_AREA))),
+		})
+	}
+	return res
+}
+
+func (s *camStruct) toggleAcquisition() {
+	if s.acquisition != nil {
+		log.Print("cam ", s.nr, " acquisition stopped")
+		s.stopAcquisition()
+		return
+	}
+	log.Print("cam ", s.nr, " acquisition started")
+	s.acquisition = newAcquisition(s.config)
+	// Stopping the tracker so the acquired target is tracked from scratch.
+	s.selectedRect = image.Rectangle{}
+	s.reinitTrackerChan <- &image.Rectangle{}
+}
+
+func (s *camStruct) stopAcquisition() {
+	s.acquisition.close()
+	s.acquisition = nil
+}
+
+func (s *camStruct) updateAcquisition(td *trackData) {
+	rect, ok := s.acquisition.update(td.img, td.frameTime, s.config.Acquisition.Threshold,
+		s.acquisitionHint(td.frameTime))
+	if !ok {
+		return
+	}
+	log.Print("cam ", s.nr, " acquired target at ", rect)
+	s.stopAcquisition()
+	// Some background around the target is needed by the trackers.
+	rect = rect.Inset(-acquisitionRectMargin).Intersect(image.Rectangle{Max: s.imgSize})
+	s.reinitTrackerChan <- &rect
+}
+
+// Returns the expected position and velocity of the target satellite on the frame captured at t, or nil if
+// there's no target or mount position. The mount is assumed to be still, as it usually waits for the
+// satellite during acquisition.
+func (s *camStruct) acquisitionHint(t time.Time) *track.AcquisitionHint {
+	if s.target == nil {
+		return nil
+	}
+	pos, ok := s.mountPosition()
+	if !ok {
+		return nil
+	}
+	pred, err := s.predictTarget(t)
+	if err != nil {
+		return nil
+	}
+	x, y, ok := s.guideCtrl.PixelOffset(azDiff(pred.Az, pos.Az), pred.El-pos.El)
+	if !ok {
+		return nil
+	}
+	vx, vy, _ := s.guideCtrl.PixelOffset(pred.AzRate, pred.ElRate)
+	b := s.guideCtrl.Boresight(s.imgSize.X, s.imgSize.Y)
+	return &track.AcquisitionHint{X: b.X + x, Y: b.Y + y, VX: vx, VY: vy}
+}
+
+func (s *camStruct) drawAcquisition(img *gocv.Mat) {
+	c := s.selectedRectColor
+	gocv.PutText(img, "ACQ", image.Point{X: 85, Y: 20}, gocv.FontHersheyPlain, 1.4, c, 1)
+
+	if h := s.acquisition.hint; h != nil {
+		p := image.Pt(int(h.X), int(h.Y))
+		if r := s.config.Acquisition.SearchRadius; r > 0 {
+			gocv.Circle(img, p, int(r), c, 1)
+		}
+		gocv.ArrowedLine(img, p, image.Pt(int(h.X+h.VX), int(h.Y+h.VY)), c, 1)
+	}
+	for _, cand := range s.acquisition.acquirer.Candidates() {
+		gocv.Rectangle(img, cand.Bounds.Inset(-3), s.trackerRectColor, 1)
+	}
+}
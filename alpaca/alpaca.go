@@ -0,0 +1,162 @@
+// Package alpaca implements a client for the ASCOM Alpaca REST API.
+package alpaca
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Alpaca error numbers.
+const (
+	ErrNotImplemented       = 0x400
+	ErrInvalidValue         = 0x401
+	ErrValueNotSet          = 0x402
+	ErrNotConnected         = 0x407
+	ErrInvalidWhileParked   = 0x408
+	ErrInvalidWhileSlaved   = 0x409
+	ErrInvalidOperation     = 0x40b
+	ErrActionNotImplemented = 0x40c
+)
+
+// Error is a non-zero error number returned by a device.
+type Error struct {
+	Number  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("alpaca: error 0x%x: %s", e.Number, e.Message)
+}
+
+// The envelope of every response.
+type response struct {
+	Value               json.RawMessage
+	ClientTransactionID uint32
+	ServerTransactionID uint32
+	ErrorNumber         int
+	ErrorMessage        string
+}
+
+// Client sends requests to an Alpaca server.
+type Client struct {
+	base     string
+	http     *http.Client
+	clientID uint32
+	// The last transaction id.
+	transactionID uint32
+}
+
+// NewClient returns a client of the Alpaca server at addr (host:port).
+func NewClient(addr string) *Client {
+	return &Client{
+		base:     "http://" + addr,
+		http:     &http.Client{Timeout: 5 * time.Second},
+		clientID: uint32(rand.New(rand.NewSource(time.Now().UnixNano())).Int31n(65535) + 1),
+	}
+}
+
+func (c *Client) params(params url.Values) url.Values {
+	v := url.Values{}
+	for k, p := range params {
+		v[k] = p
+	}
+	v.Set("ClientID", strconv.FormatUint(uint64(c.clientID), 10))
+	v.Set("ClientTransactionID", strconv.FormatUint(uint64(atomic.AddUint32(&c.transactionID, 1)), 10))
+	return v
+}
+
+// Sends a GET request to path and decodes the value of the response into value.
+func (c *Client) get(path string, params url.Values, value interface{}) error {
+	resp, err := c.http.Get(c.base + path + "?" + c.params(params).Encode())
+	if err != nil {
+		return err
+	}
+	return decodeResponse(resp, value)
+}
+
+// Sends a PUT request to path with the form encoded parameters.
+func (c *Client) put(path string, params url.Values) error {
+	req, err := http.NewRequest(http.MethodPut, c.base+path, strings.NewReader(c.params(params).Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	return decodeResponse(resp, nil)
+}
+
+func decodeResponse(resp *http.Response, value interface{}) error {
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	// Invalid requests are answered with a plain text message.
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("alpaca: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var r response
+	if err := json.Unmarshal(body, &r); err != nil {
+		return fmt.Errorf("alpaca: invalid response: %w", err)
+	}
+	if r.ErrorNumber != 0 {
+		return &Error{Number: r.ErrorNumber, Message: r.ErrorMessage}
+	}
+	if value == nil {
+		return nil
+	}
+	if err := json.Unmarshal(r.Value, value); err != nil {
+		return fmt.Errorf("alpaca: invalid value: %w", err)
+	}
+	return nil
+}
+
+// Device is a device of an Alpaca server, with the methods common to all device types.
+type Device struct {
+	client *Client
+	path   string
+}
+
+// NewDevice returns the device with the given type (like "telescope") and number.
+func NewDevice(client *Client, deviceType string, number int) Device {
+	return Device{client: client, path: fmt.Sprintf("/api/v1/%s/%d/", strings.ToLower(deviceType), number)}
+}
+
+func (d Device) get(method string, params url.Values, value interface{}) error {
+	return d.client.get(d.path+method, params, value)
+}
+
+func (d Device) put(method string, params url.Values) error {
+	return d.client.put(d.path+method, params)
+}
+
+func (d Device) Connected() (bool, error) {
+	var v bool
+	err := d.get("connected", nil, &v)
+	return v, err
+}
+
+func (d Device) SetConnected(connected bool) error {
+	return d.put("connected", url.Values{"Connected": {strconv.FormatBool(connected)}})
+}
+
+func (d Device) Name() (string, error) {
+	var v string
+	err := d.get("name", nil, &v)
+	return v, err
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,206 @@
+package alpaca
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type request struct {
+	method string
+	path   string
+	params map[string]string
+}
+
+// A stand-in Alpaca server which records the requests, and answers with the value set for the path.
+type fakeServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []request
+	// Values of the responses by path, or an *Error to answer with.
+	values map[string]interface{}
+	// The last transaction id of each client.
+	transactionIDs map[string]uint64
+	t              *testing.T
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	f := &fakeServer{values: make(map[string]interface{}), transactionIDs: make(map[string]uint64), t: t}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.handle))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeServer) client() *Client {
+	return NewClient(strings.TrimPrefix(f.URL, "http://"))
+}
+
+func (f *fakeServer) handle(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	req := request{method: r.Method, path: r.URL.Path, params: make(map[string]string)}
+	for k := range r.Form {
+		if k != "ClientID" && k != "ClientTransactionID" {
+			req.params[k] = r.Form.Get(k)
+		}
+	}
+	f.requests = append(f.requests, req)
+
+	// Transaction ids have to increase for each client.
+	id, err := strconv.ParseUint(r.Form.Get("ClientTransactionID"), 10, 32)
+	client := r.Form.Get("ClientID")
+	if err != nil || client == "" || id <= f.transactionIDs[client] {
+		http.Error(w, "invalid client or transaction id", http.StatusBadRequest)
+		return
+	}
+	f.transactionIDs[client] = id
+
+	v, ok := f.values[r.URL.Path]
+	if !ok && r.Method == http.MethodGet {
+		http.Error(w, "unknown method "+r.URL.Path, http.StatusBadRequest)
+		return
+	}
+	resp := map[string]interface{}{"ClientTransactionID": id, "ServerTransactionID": len(f.requests)}
+	if e, ok := v.(*Error); ok {
+		resp["ErrorNumber"], resp["ErrorMessage"] = e.Number, e.Message
+	} else if v != nil {
+		resp["Value"] = v
+	}
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		f.t.Error(err)
+	}
+}
+
+func (f *fakeServer) set(path string, v interface{}) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.values[path] = v
+}
+
+// Checks the requests received since the last call.
+func (f *fakeServer) expect(t *testing.T, reqs ...request) {
+	t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	got := fmt.Sprint(f.requests)
+	if want := fmt.Sprint(reqs); got != want {
+		t.Errorf("requests %s, want %s", got, want)
+	}
+	f.requests = nil
+}
+
+func TestErrors(t *testing.T) {
+	f := newFakeServer(t)
+	d := NewDevice(f.client(), "Telescope", 0)
+
+	f.set("/api/v1/telescope/0/connected", true)
+	if v, err := d.Connected(); err != nil || !v {
+		t.Errorf("connected %v, %v", v, err)
+	}
+
+	f.set("/api/v1/telescope/0/name", &Error{Number: ErrNotConnected, Message: "not connected"})
+	_, err := d.Name()
+	var e *Error
+	if !errors.As(err, &e) || e.Number != ErrNotConnected || e.Message != "not connected" {
+		t.Errorf("error %v", err)
+	}
+
+	f.set("/api/v1/telescope/0/connected", &Error{Number: ErrNotImplemented, Message: "read only"})
+	if err := d.SetConnected(true); !errors.As(err, &e) || e.Number != ErrNotImplemented {
+		t.Errorf("error %v", err)
+	}
+
+	// Not a device method, answered with a plain text message.
+	_, err = NewTelescope(f.client(), 1).Azimuth()
+	if err == nil || errors.As(err, &e) || !strings.Contains(err.Error(), "unknown method") {
+		t.Errorf("error %v", err)
+	}
+
+	f.set("/api/v1/telescope/0/name", 123)
+	if _, err := d.Name(); err == nil {
+		t.Error("invalid value accepted")
+	}
+}
+
+func TestTelescope(t *testing.T) {
+	f := newFakeServer(t)
+	tel := NewTelescope(f.client(), 0)
+	const p = "/api/v1/telescope/0/"
+
+	f.set(p+"azimuth", 123.5)
+	f.set(p+"altitude", 45.25)
+	f.set(p+"axisrates", []AxisRate{{Minimum: 0, Maximum: 3}, {Minimum: 4, Maximum: 6}})
+	if az, err := tel.Azimuth(); err != nil || az != 123.5 {
+		t.Errorf("azimuth %v, %v", az, err)
+	}
+	if el, err := tel.Altitude(); err != nil || el != 45.25 {
+		t.Errorf("altitude %v, %v", el, err)
+	}
+	rates, err := tel.AxisRates(AxisSecondary)
+	if err != nil || len(rates) != 2 || rates[1].Maximum != 6 {
+		t.Errorf("axis rates %v, %v", rates, err)
+	}
+	f.expect(t,
+		request{"GET", p + "azimuth", map[string]string{}},
+		request{"GET", p + "altitude", map[string]string{}},
+		request{"GET", p + "axisrates", map[string]string{"Axis": "1"}})
+
+	if err := tel.MoveAxis(AxisPrimary, -1.5); err != nil {
+		t.Error(err)
+	}
+	if err := tel.SlewToAltAzAsync(350, 10.5); err != nil {
+		t.Error(err)
+	}
+	if err := tel.SetTracking(false); err != nil {
+		t.Error(err)
+	}
+	if err := tel.AbortSlew(); err != nil {
+		t.Error(err)
+	}
+	f.expect(t,
+		request{"PUT", p + "moveaxis", map[string]string{"Axis": "0", "Rate": "-1.5"}},
+		request{"PUT", p + "slewtoaltazasync", map[string]string{"Azimuth": "350", "Altitude": "10.5"}},
+		request{"PUT", p + "tracking", map[string]string{"Tracking": "false"}},
+		request{"PUT", p + "abortslew", map[string]string{}})
+}
+
+func TestRotator(t *testing.T) {
+	f := newFakeServer(t)
+	r := NewRotator(f.client(), 2)
+	const p = "/api/v1/rotator/2/"
+
+	f.set(p+"position", 359.5)
+	f.set(p+"ismoving", false)
+	if v, err := r.Position(); err != nil || v != 359.5 {
+		t.Errorf("position %v, %v", v, err)
+	}
+	if v, err := r.IsMoving(); err != nil || v {
+		t.Errorf("moving %v, %v", v, err)
+	}
+	if err := r.Move(-2.25); err != nil {
+		t.Error(err)
+	}
+	if err := r.MoveAbsolute(10); err != nil {
+		t.Error(err)
+	}
+	if err := r.Halt(); err != nil {
+		t.Error(err)
+	}
+	f.expect(t,
+		request{"GET", p + "position", map[string]string{}},
+		request{"GET", p + "ismoving", map[string]string{}},
+		request{"PUT", p + "move", map[string]string{"Position": "-2.25"}},
+		request{"PUT", p + "moveabsolute", map[string]string{"Position": "10"}},
+		request{"PUT", p + "halt", map[string]string{}})
+}
@@ -0,0 +1,107 @@
+package alpaca
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DiscoveryPort    = 32227
+	discoveryMessage = "alpacadiscovery1"
+)
+
+// Discover broadcasts a discovery request on the LAN and returns the addresses (host:port) of the Alpaca
+// servers which answered within timeout.
+func Discover(timeout time.Duration) ([]string, error) {
+	return DiscoverAt(net.JoinHostPort(net.IPv4bcast.String(), strconv.Itoa(DiscoveryPort)), timeout)
+}
+
+// DiscoverAt sends the discovery request to the given broadcast or unicast address.
+func DiscoverAt(addr string, timeout time.Duration) ([]string, error) {
+	dst, err := net.ResolveUDPAddr("udp4", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.WriteToUDP([]byte(discoveryMessage), dst); err != nil {
+		return nil, err
+	}
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+
+	var res []string
+	seen := make(map[string]bool)
+	buf := make([]byte, 1024)
+	for {
+		n, from, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				return res, nil
+			}
+			return res, err
+		}
+		var resp struct {
+			AlpacaPort int
+		}
+		if err := json.Unmarshal(buf[:n], &resp); err != nil || resp.AlpacaPort == 0 {
+			continue
+		}
+		a := net.JoinHostPort(from.IP.String(), strconv.Itoa(resp.AlpacaPort))
+		if !seen[a] {
+			seen[a] = true
+			res = append(res, a)
+		}
+	}
+}
+
+// ConfiguredDevice is a device served by an Alpaca server.
+type ConfiguredDevice struct {
+	DeviceName   string
+	DeviceType   string
+	DeviceNumber int
+	UniqueID     string
+}
+
+// ConfiguredDevices returns the devices of the server.
+func (c *Client) ConfiguredDevices() ([]ConfiguredDevice, error) {
+	var v []ConfiguredDevice
+	err := c.get("/management/v1/configureddevices", nil, &v)
+	return v, err
+}
+
+// FindDevice discovers the Alpaca servers on the LAN, and returns the client of the first one which serves
+// a device with the given type, and the device.
+func FindDevice(deviceType string, timeout time.Duration) (*Client, ConfiguredDevice, error) {
+	addrs, err := Discover(timeout)
+	if err != nil {
+		return nil, ConfiguredDevice{}, err
+	}
+	for _, a := range addrs {
+		c := NewClient(a)
+		devices, err := c.ConfiguredDevices()
+		if err != nil {
+			continue
+		}
+		for _, d := range devices {
+			if strings.EqualFold(d.DeviceType, deviceType) {
+				return c, d, nil
+			}
+		}
+	}
+	return nil, ConfiguredDevice{}, fmt.Errorf("alpaca: no %s found on %d servers", deviceType, len(addrs))
+}
+
+// Addr returns the address of the server.
+func (c *Client) Addr() string {
+	return strings.TrimPrefix(c.base, "http://")
+}
@@ -0,0 +1,67 @@
+package alpaca
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Answers the discovery requests on a local port like an Alpaca server with the given replies.
+func fakeDiscoveryResponder(t *testing.T, replies ...string) string {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			n, from, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			if string(buf[:n]) != discoveryMessage {
+				continue
+			}
+			for _, r := range replies {
+				conn.WriteToUDP([]byte(r), from)
+			}
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestDiscoverAt(t *testing.T) {
+	// Invalid and duplicate replies are skipped.
+	addr := fakeDiscoveryResponder(t, `{"AlpacaPort":11111}`, `invalid`, `{"AlpacaPort":0}`,
+		`{"AlpacaPort":11111}`, `{"AlpacaPort":22222}`)
+	res, err := DiscoverAt(addr, 200*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"127.0.0.1:11111", "127.0.0.1:22222"}; !reflect.DeepEqual(res, want) {
+		t.Errorf("discovered %v, want %v", res, want)
+	}
+
+	res, err = DiscoverAt(fakeDiscoveryResponder(t), 100*time.Millisecond)
+	if err != nil || len(res) != 0 {
+		t.Errorf("discovered %v, %v without replies", res, err)
+	}
+}
+
+func TestConfiguredDevices(t *testing.T) {
+	f := newFakeServer(t)
+	devices := []ConfiguredDevice{
+		{DeviceName: "Mount", DeviceType: "Telescope", DeviceNumber: 0, UniqueID: "a"},
+		{DeviceName: "Rotator", DeviceType: "Rotator", DeviceNumber: 1, UniqueID: "b"},
+	}
+	f.set("/management/v1/configureddevices", devices)
+	res, err := f.client().ConfiguredDevices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(res, devices) {
+		t.Errorf("devices %+v", res)
+	}
+}
@@ -0,0 +1,38 @@
+package alpaca
+
+import "net/url"
+
+type Rotator struct {
+	Device
+}
+
+func NewRotator(client *Client, number int) *Rotator {
+	return &Rotator{NewDevice(client, "rotator", number)}
+}
+
+// Position returns the sky position angle of the rotator in degrees.
+func (r *Rotator) Position() (float64, error) {
+	var v float64
+	err := r.get("position", nil, &v)
+	return v, err
+}
+
+func (r *Rotator) IsMoving() (bool, error) {
+	var v bool
+	err := r.get("ismoving", nil, &v)
+	return v, err
+}
+
+// MoveAbsolute starts moving to the given position angle in degrees.
+func (r *Rotator) MoveAbsolute(position float64) error {
+	return r.put("moveabsolute", url.Values{"Position": {formatFloat(position)}})
+}
+
+// Move starts moving by the given angle in degrees relative to the current position.
+func (r *Rotator) Move(position float64) error {
+	return r.put("move", url.Values{"Position": {formatFloat(position)}})
+}
+
+func (r *Rotator) Halt() error {
+	return r.put("halt", nil)
+}
@@ -0,0 +1,96 @@
+package alpaca
+
+import (
+	"net/url"
+	"strconv"
+)
+
+// Axes of MoveAxis. On alt-az mounts the primary axis is the azimuth axis.
+const (
+	AxisPrimary   = 0
+	AxisSecondary = 1
+)
+
+// Tracking rates.
+const (
+	TrackingSidereal = 0
+	TrackingLunar    = 1
+	TrackingSolar    = 2
+	TrackingKing     = 3
+)
+
+// AxisRate is a range of rates supported by MoveAxis in degrees per second.
+type AxisRate struct {
+	Minimum float64
+	Maximum float64
+}
+
+type Telescope struct {
+	Device
+}
+
+func NewTelescope(client *Client, number int) *Telescope {
+	return &Telescope{NewDevice(client, "telescope", number)}
+}
+
+// Altitude returns the altitude of the telescope in degrees.
+func (t *Telescope) Altitude() (float64, error) {
+	var v float64
+	err := t.get("altitude", nil, &v)
+	return v, err
+}
+
+// Azimuth returns the azimuth of the telescope in degrees, north-referenced and positive east.
+func (t *Telescope) Azimuth() (float64, error) {
+	var v float64
+	err := t.get("azimuth", nil, &v)
+	return v, err
+}
+
+func (t *Telescope) Slewing() (bool, error) {
+	var v bool
+	err := t.get("slewing", nil, &v)
+	return v, err
+}
+
+// AxisRates returns the rates MoveAxis accepts on the axis.
+func (t *Telescope) AxisRates(axis int) ([]AxisRate, error) {
+	var v []AxisRate
+	err := t.get("axisrates", url.Values{"Axis": {strconv.Itoa(axis)}}, &v)
+	return v, err
+}
+
+// MoveAxis moves the axis at the given rate in degrees per second. Zero stops the axis.
+func (t *Telescope) MoveAxis(axis int, rate float64) error {
+	return t.put("moveaxis", url.Values{"Axis": {strconv.Itoa(axis)}, "Rate": {formatFloat(rate)}})
+}
+
+// SlewToAltAzAsync starts a slew to the given azimuth and altitude in degrees.
+func (t *Telescope) SlewToAltAzAsync(az, alt float64) error {
+	return t.put("slewtoaltazasync", url.Values{"Azimuth": {formatFloat(az)}, "Altitude": {formatFloat(alt)}})
+}
+
+func (t *Telescope) AbortSlew() error {
+	return t.put("abortslew", nil)
+}
+
+func (t *Telescope) Tracking() (bool, error) {
+	var v bool
+	err := t.get("tracking", nil, &v)
+	return v, err
+}
+
+func (t *Telescope) SetTracking(tracking bool) error {
+	return t.put("tracking", url.Values{"Tracking": {strconv.FormatBool(tracking)}})
+}
+
+// TrackingRate returns one of the Tracking constants.
+func (t *Telescope) TrackingRate() (int, error) {
+	var v int
+	err := t.get("trackingrate", nil, &v)
+	return v, err
+}
+
+func (t *Telescope) SetTrackingRate(rate int) error {
+	return t.put("trackingrate", url.Values{"TrackingRate": {strconv.Itoa(rate)}})
+}
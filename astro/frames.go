@@ -0,0 +1,49 @@
+package astro
+
+import (
+	"math"
+	"time"
+
+	"github.com/nonoo/jampec/sgp4"
+)
+
+// TEMEToECEF rotates a TEME position (km) and velocity (km/s) to the Earth fixed frame. Polar motion is
+// ignored.
+func TEMEToECEF(pos, vel sgp4.Vector, t time.Time) (sgp4.Vector, sgp4.Vector) {
+	g := GMST(t)
+	sinG, cosG := math.Sincos(g)
+
+	p := sgp4.Vector{
+		X: cosG*pos.X + sinG*pos.Y,
+		Y: -sinG*pos.X + cosG*pos.Y,
+		Z: pos.Z,
+	}
+	// The velocity also needs the Earth's rotation removed.
+	v := sgp4.Vector{
+		X: cosG*vel.X + sinG*vel.Y + earthRotationRate*p.Y,
+		Y: -sinG*vel.X + cosG*vel.Y - earthRotationRate*p.X,
+		Z: vel.Z,
+	}
+	return p, v
+}
+
+// GeodeticToECEF returns the Earth fixed position (km) of a point on the WGS-84 ellipsoid. Latitude and
+// longitude are in degrees, altitude is in meters.
+func GeodeticToECEF(lat, lon, alt float64) sgp4.Vector {
+	const (
+		a  = 6378.137
+		f  = 1 / 298.257223563
+		e2 = f * (2 - f)
+	)
+
+	sinLat, cosLat := math.Sincos(lat * deg2rad)
+	sinLon, cosLon := math.Sincos(lon * deg2rad)
+	n := a / math.Sqrt(1-e2*sinLat*sinLat)
+	h := alt / 1000
+
+	return sgp4.Vector{
+		X: (n + h) * cosLat * cosLon,
+		Y: (n + h) * cosLat * sinLon,
+		Z: (n*(1-e2) + h) * sinLat,
+	}
+}
@@ -0,0 +1,229 @@
+package astro
+
+import (
+	"math"
+	"time"
+
+	"github.com/nonoo/jampec/sgp4"
+)
+
+const (
+	passSearchStep = 20 * time.Second
+	passRefineStep = time.Second
+)
+
+// 1/phi, the ratio of the golden-section search.
+var invPhi = (math.Sqrt(5) - 1) / 2
+
+type PassEvent struct {
+	Time time.Time `json:"time"`
+	Az   float64   `json:"az"`
+	El   float64   `json:"el"`
+}
+
+// Pass is a period when the satellite is above the horizon. AOS is the acquisition of signal (rise), TCA
+// is the time of closest approach (maximum elevation) and LOS is the loss of signal (set). If the satellite
+// is already up at the start or still up at the end of the search window, AOS or LOS is clipped to it.
+type Pass struct {
+	AOS PassEvent `json:"aos"`
+	TCA PassEvent `json:"tca"`
+	LOS PassEvent `json:"los"`
+
+	// True if the satellite is in sunlight during any part of the pass.
+	Sunlit bool `json:"sunlit"`
+	// True if the satellite is sunlit while the Sun is below the darkness limit at the site and the
+	// satellite is above the minimum elevation, so it can be observed optically.
+	Visible bool `json:"visible"`
+}
+
+type PassOptions struct {
+	// Passes with a lower maximum elevation are skipped.
+	MinElevation float64
+	// The Sun must be below this elevation for the site to be considered dark.
+	SunElevationLimit float64
+}
+
+// PredictPasses returns the passes of the satellite over the site between start and end.
+func (s Site) PredictPasses(sat *sgp4.Satellite, start, end time.Time, opts PassOptions) ([]Pass, error) {
+	elAt := func(t time.Time) (float64, error) {
+		l, err := s.Look(sat, t)
+		return l.El, err
+	}
+
+	var res []Pass
+	prevEl, err := elAt(start)
+	if err != nil {
+		return nil, err
+	}
+	var pass *Pass
+	if prevEl >= 0 {
+		pass = &Pass{}
+		pass.AOS, err = s.passEvent(sat, start)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	prevT := start
+	// The sample before the previous one, to find elevation maxima between the samples.
+	prevPrevT, prevPrevEl := start, math.Inf(1)
+	for t := start.Add(passSearchStep); ; t = t.Add(passSearchStep) {
+		if t.After(end) {
+			t = end
+		}
+
+		el, err := elAt(t)
+		if err != nil {
+			return nil, err
+		}
+
+		if prevEl < 0 && el >= 0 {
+			pass = &Pass{}
+			pass.AOS, err = s.passEvent(sat, s.findHorizonCrossing(sat, prevT, t))
+			if err != nil {
+				return nil, err
+			}
+		} else if prevEl >= 0 && el < 0 && pass != nil {
+			pass.LOS, err = s.passEvent(sat, s.findHorizonCrossing(sat, prevT, t))
+			if err != nil {
+				return nil, err
+			}
+			if err = s.finishPass(sat, pass, opts, &res); err != nil {
+				return nil, err
+			}
+			pass = nil
+		} else if prevPrevEl < 0 && prevEl < 0 && el < 0 && prevEl >= prevPrevEl && prevEl >= el {
+			// Passes shorter than the search step may fall between the samples. The elevation maximum is
+			// somewhere between the samples around the highest one, so it's checked whether it's up.
+			if err := s.findShortPass(sat, prevPrevT, t, opts, &res); err != nil {
+				return nil, err
+			}
+		}
+
+		prevPrevEl, prevPrevT = prevEl, prevT
+		prevEl = el
+		prevT = t
+		if !t.Before(end) {
+			break
+		}
+	}
+
+	if pass != nil {
+		pass.LOS, err = s.passEvent(sat, end)
+		if err != nil {
+			return nil, err
+		}
+		if err = s.finishPass(sat, pass, opts, &res); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func (s Site) passEvent(sat *sgp4.Satellite, t time.Time) (PassEvent, error) {
+	l, err := s.Look(sat, t)
+	if err != nil {
+		return PassEvent{}, err
+	}
+	return PassEvent{Time: t, Az: l.Az, El: l.El}, nil
+}
+
+// Looks for a pass around the elevation maximum between t1 and t2, when the satellite is below the horizon
+// at both.
+func (s Site) findShortPass(sat *sgp4.Satellite, t1, t2 time.Time, opts PassOptions, res *[]Pass) error {
+	tMax, elMax := s.findMaxElevation(sat, t1, t2)
+	if elMax < 0 {
+		return nil
+	}
+	var pass Pass
+	var err error
+	pass.AOS, err = s.passEvent(sat, s.findHorizonCrossing(sat, t1, tMax))
+	if err != nil {
+		return err
+	}
+	pass.LOS, err = s.passEvent(sat, s.findHorizonCrossing(sat, tMax, t2))
+	if err != nil {
+		return err
+	}
+	return s.finishPass(sat, &pass, opts, res)
+}
+
+// Finds the time and value of the elevation maximum between t1 and t2 with golden-section search. The
+// elevation must have a single maximum in the interval.
+func (s Site) findMaxElevation(sat *sgp4.Satellite, t1, t2 time.Time) (time.Time, float64) {
+	elAt := func(t time.Time) float64 {
+		l, _ := s.Look(sat, t)
+		return l.El
+	}
+	at := func(f float64) time.Time {
+		return t1.Add(time.Duration(f * float64(t2.Sub(t1))))
+	}
+
+	// The interval is tracked as fractions of the original one.
+	a, b := 0.0, 1.0
+	c, d := b-invPhi*(b-a), a+invPhi*(b-a)
+	elC, elD := elAt(at(c)), elAt(at(d))
+	for time.Duration((b-a)*float64(t2.Sub(t1))) > passRefineStep {
+		if elC > elD {
+			b, d, elD = d, c, elC
+			c = b - invPhi*(b-a)
+			elC = elAt(at(c))
+		} else {
+			a, c, elC = c, d, elD
+			d = a + invPhi*(b-a)
+			elD = elAt(at(d))
+		}
+	}
+	if elC > elD {
+		return at(c), elC
+	}
+	return at(d), elD
+}
+
+// Finds the time of the horizon crossing between t1 and t2 with bisection. The returned time is always on
+// the side where the satellite is up.
+func (s Site) findHorizonCrossing(sat *sgp4.Satellite, t1, t2 time.Time) time.Time {
+	el1, _ := s.Look(sat, t1)
+	rising := el1.El < 0
+	for t2.Sub(t1) > passRefineStep {
+		mid := t1.Add(t2.Sub(t1) / 2)
+		l, _ := s.Look(sat, mid)
+		if (l.El >= 0) == rising {
+			t2 = mid
+		} else {
+			t1 = mid
+		}
+	}
+	if rising {
+		return t2
+	}
+	return t1
+}
+
+// Finds the TCA, calculates the visibility and appends the pass to res if it's high enough.
+func (s Site) finishPass(sat *sgp4.Satellite, pass *Pass, opts PassOptions, res *[]Pass) error {
+	pass.TCA = pass.AOS
+	for t := pass.AOS.Time; !t.After(pass.LOS.Time); t = t.Add(passRefineStep) {
+		pos, vel, err := sat.Propagate(t)
+		if err != nil {
+			return err
+		}
+		l := s.LookTEME(pos, vel, t)
+		if l.El > pass.TCA.El {
+			pass.TCA = PassEvent{Time: t, Az: l.Az, El: l.El}
+		}
+
+		if Sunlit(pos, t) {
+			pass.Sunlit = true
+			if !pass.Visible && l.El >= opts.MinElevation && s.SunElevation(t) < opts.SunElevationLimit {
+				pass.Visible = true
+			}
+		}
+	}
+
+	if pass.TCA.El < opts.MinElevation {
+		return nil
+	}
+	*res = append(*res, *pass)
+	return nil
+}
@@ -0,0 +1,105 @@
+package astro
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/nonoo/jampec/sgp4"
+)
+
+func issSatellite(t *testing.T) *sgp4.Satellite {
+	t.Helper()
+	tle, err := sgp4.ParseTLE("ISS", "1 25544U 98067A   21060.56949109  .00001264  00000-0  31140-4 0  9998",
+		"2 25544  51.6437 122.5164 0003024  49.1547  70.6613 15.48966856271773")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sat, err := sgp4.NewSatellite(tle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sat
+}
+
+// A grazing pass which is up for about 15 seconds, between two samples of the search.
+func TestShortPass(t *testing.T) {
+	sat := issSatellite(t)
+	site := Site{Latitude: 45, Longitude: -72.64}
+	start := sat.TLE.Epoch
+
+	passes, err := site.PredictPasses(sat, start, start.Add(3*time.Hour), PassOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(passes) != 1 {
+		t.Fatalf("%d passes, want 1", len(passes))
+	}
+	p := passes[0]
+	if d := p.LOS.Time.Sub(p.AOS.Time); d <= 0 || d >= passSearchStep {
+		t.Errorf("pass length %v", d)
+	}
+	if p.TCA.El < 0 || p.TCA.Time.Before(p.AOS.Time) || p.TCA.Time.After(p.LOS.Time) {
+		t.Errorf("wrong TCA %+v", p.TCA)
+	}
+	want := time.Date(2021, 3, 1, 13, 44, 33, 0, time.UTC)
+	if d := p.TCA.Time.Sub(want); d < -2*time.Second || d > 2*time.Second {
+		t.Errorf("TCA at %v, want %v", p.TCA.Time, want)
+	}
+}
+
+// Compares the passes to the elevations sampled every second.
+func TestPredictPasses(t *testing.T) {
+	sat := issSatellite(t)
+	site := Site{Latitude: 47.5, Longitude: 19}
+	start := sat.TLE.Epoch
+	end := start.Add(24 * time.Hour)
+
+	var want []Pass
+	var pass *Pass
+	for tt := start; !tt.After(end); tt = tt.Add(time.Second) {
+		l, err := site.Look(sat, tt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if l.El >= 0 && pass == nil {
+			pass = &Pass{AOS: PassEvent{Time: tt}, TCA: PassEvent{El: l.El}}
+		}
+		if pass == nil {
+			continue
+		}
+		if l.El > pass.TCA.El {
+			pass.TCA = PassEvent{Time: tt, El: l.El}
+		}
+		if l.El < 0 {
+			pass.LOS.Time = tt
+			want = append(want, *pass)
+			pass = nil
+		}
+	}
+
+	got, err := site.PredictPasses(sat, start, end, PassOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) || len(want) == 0 {
+		t.Fatalf("%d passes, want %d", len(got), len(want))
+	}
+	for i := range want {
+		for _, e := range []struct {
+			name      string
+			got, want time.Time
+		}{
+			{"AOS", got[i].AOS.Time, want[i].AOS.Time},
+			{"TCA", got[i].TCA.Time, want[i].TCA.Time},
+			{"LOS", got[i].LOS.Time, want[i].LOS.Time},
+		} {
+			if d := e.got.Sub(e.want); d < -2*time.Second || d > 2*time.Second {
+				t.Errorf("pass %d %s at %v, want %v", i, e.name, e.got, e.want)
+			}
+		}
+		if math.Abs(got[i].TCA.El-want[i].TCA.El) > 0.05 {
+			t.Errorf("pass %d max elevation %v, want %v", i, got[i].TCA.El, want[i].TCA.El)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package astro
+
+import "math"
+
+const (
+	StandardPressure    = 1010.0 // mbar
+	StandardTemperature = 10.0   // Celsius
+)
+
+// Refraction returns the atmospheric refraction in degrees for an object at the given true (airless)
+// elevation in degrees, using Saemundsson's formula scaled to the pressure (mbar) and temperature
+// (Celsius). The result should be added to the true elevation to get the apparent one.
+func Refraction(el, pressure, temperature float64) float64 {
+	if el < -1 {
+		return 0
+	}
+	// Result of the formula is in arc minutes.
+	r := 1.02 / math.Tan((el+10.3/(el+5.11))*deg2rad)
+	r *= pressure / 1010 * 283 / (273 + temperature)
+	if r < 0 {
+		return 0
+	}
+	return r / 60
+}
@@ -0,0 +1,41 @@
+package astro
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRefraction(t *testing.T) {
+	tests := []struct {
+		name        string
+		el          float64
+		pressure    float64
+		temperature float64
+		want        float64 // Arc minutes.
+	}{
+		{"horizon", 0, StandardPressure, StandardTemperature, 28.98},
+		{"10 degrees", 10, StandardPressure, StandardTemperature, 5.41},
+		{"45 degrees", 45, StandardPressure, StandardTemperature, 1.01},
+		{"zenith", 90, StandardPressure, StandardTemperature, 0},
+		{"just below the horizon", -1, StandardPressure, StandardTemperature, 38.79},
+		{"below the horizon", -2, StandardPressure, StandardTemperature, 0},
+		{"half pressure", 10, StandardPressure / 2, StandardTemperature, 2.70},
+		{"cold", 10, StandardPressure, -10, 5.82},
+		{"hot", 10, StandardPressure, 30, 5.05},
+	}
+	for _, tt := range tests {
+		if r := Refraction(tt.el, tt.pressure, tt.temperature) * 60; math.Abs(r-tt.want) > 0.01 {
+			t.Errorf("%s: refraction %.3f', want %.2f'", tt.name, r, tt.want)
+		}
+	}
+
+	// Refraction decreases with the elevation.
+	prev := math.Inf(1)
+	for el := -1.0; el <= 90; el += 0.5 {
+		r := Refraction(el, StandardPressure, StandardTemperature)
+		if r > prev || r < 0 {
+			t.Errorf("refraction %v at %v after %v", r, el, prev)
+		}
+		prev = r
+	}
+}
@@ -0,0 +1,13 @@
+package astro
+
+import "math"
+
+// ParallacticAngle returns the parallactic angle in degrees of the direction with the given azimuth and
+// elevation in degrees. This is the angle the field of an alt-az mount is rotated by relative to the
+// equatorial frame, positive west of the meridian.
+func (s Site) ParallacticAngle(az, el float64) float64 {
+	sinLat, cosLat := math.Sincos(s.Latitude * deg2rad)
+	sinAz, cosAz := math.Sincos(az * deg2rad)
+	sinEl, cosEl := math.Sincos(el * deg2rad)
+	return math.Atan2(-sinAz*cosLat, sinLat*cosEl-cosLat*sinEl*cosAz) * rad2deg
+}
@@ -0,0 +1,94 @@
+package astro
+
+import (
+	"math"
+	"time"
+
+	"github.com/nonoo/jampec/sgp4"
+)
+
+// Site is the observer location.
+type Site struct {
+	// Geodetic latitude and longitude in degrees, longitude is positive east.
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	// Altitude above the WGS-84 ellipsoid in meters.
+	Altitude float64 `json:"altitude"`
+
+	// If enabled, elevations are corrected for atmospheric refraction. Pressure (mbar) and temperature
+	// (Celsius) are optional, standard values are used if they are not set.
+	Refraction  bool     `json:"refraction"`
+	Pressure    *float64 `json:"pressure"`
+	Temperature *float64 `json:"temperature"`
+}
+
+// Topocentric holds the look angles from the site to an object. Angles are in degrees, azimuth is measured
+// from north towards east.
+type Topocentric struct {
+	Az        float64
+	El        float64
+	Range     float64 // km
+	RangeRate float64 // km/s, positive when receding
+}
+
+func (s Site) ECEF() sgp4.Vector {
+	return GeodeticToECEF(s.Latitude, s.Longitude, s.Altitude)
+}
+
+// LookECEF returns the look angles to an object with the given Earth fixed position (km) and velocity
+// (km/s).
+func (s Site) LookECEF(pos, vel sgp4.Vector) Topocentric {
+	site := s.ECEF()
+	rho := sgp4.Vector{X: pos.X - site.X, Y: pos.Y - site.Y, Z: pos.Z - site.Z}
+
+	sinLat, cosLat := math.Sincos(s.Latitude * deg2rad)
+	sinLon, cosLon := math.Sincos(s.Longitude * deg2rad)
+
+	south := sinLat*cosLon*rho.X + sinLat*sinLon*rho.Y - cosLat*rho.Z
+	east := -sinLon*rho.X + cosLon*rho.Y
+	zenith := cosLat*cosLon*rho.X + cosLat*sinLon*rho.Y + sinLat*rho.Z
+
+	r := rho.Norm()
+	res := Topocentric{
+		Az:        math.Atan2(east, -south) * rad2deg,
+		El:        math.Asin(zenith/r) * rad2deg,
+		Range:     r,
+		RangeRate: (rho.X*vel.X + rho.Y*vel.Y + rho.Z*vel.Z) / r,
+	}
+	// Rounding can make a tiny negative azimuth 360.
+	res.Az = math.Mod(res.Az+360, 360)
+	if s.Refraction {
+		res.El += Refraction(res.El, s.pressure(), s.temperature())
+	}
+	return res
+}
+
+// LookTEME returns the look angles to an object with the given TEME position (km) and velocity (km/s) at
+// time t.
+func (s Site) LookTEME(pos, vel sgp4.Vector, t time.Time) Topocentric {
+	p, v := TEMEToECEF(pos, vel, t)
+	return s.LookECEF(p, v)
+}
+
+// Look propagates the satellite to time t and returns its look angles.
+func (s Site) Look(sat *sgp4.Satellite, t time.Time) (Topocentric, error) {
+	pos, vel, err := sat.Propagate(t)
+	if err != nil {
+		return Topocentric{}, err
+	}
+	return s.LookTEME(pos, vel, t), nil
+}
+
+func (s Site) pressure() float64 {
+	if s.Pressure != nil {
+		return *s.Pressure
+	}
+	return StandardPressure
+}
+
+func (s Site) temperature() float64 {
+	if s.Temperature != nil {
+		return *s.Temperature
+	}
+	return StandardTemperature
+}
@@ -0,0 +1,114 @@
+package astro
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/nonoo/jampec/sgp4"
+)
+
+func checkVector(t *testing.T, name string, v, want sgp4.Vector, tol float64) {
+	t.Helper()
+	if math.Abs(v.X-want.X) > tol || math.Abs(v.Y-want.Y) > tol || math.Abs(v.Z-want.Z) > tol {
+		t.Errorf("%s is %+v, want %+v", name, v, want)
+	}
+}
+
+// Vallado, example 3-3.
+func TestGeodeticToECEF(t *testing.T) {
+	checkVector(t, "site", GeodeticToECEF(39.007, -104.883, 2187), sgp4.Vector{X: -1275.1219, Y: -4797.9890,
+		Z: 3994.2975}, 1e-4)
+	checkVector(t, "equator", GeodeticToECEF(0, 90, 1000), sgp4.Vector{Y: 6379.137}, 1e-9)
+	checkVector(t, "north pole", GeodeticToECEF(90, 0, 0), sgp4.Vector{Z: 6356.752314}, 1e-6)
+}
+
+// Vallado, example 3-15, without polar motion. The time is in UT1.
+func TestTEMEToECEF(t *testing.T) {
+	tm := time.Date(2004, 4, 6, 7, 51, 28, 386009000, time.UTC).Add(-439962 * time.Microsecond)
+	p, v := TEMEToECEF(sgp4.Vector{X: 5094.18016210, Y: 6127.64465950, Z: 6380.34453270},
+		sgp4.Vector{X: -4.746131487, Y: 0.785818041, Z: 5.531931288}, tm)
+	checkVector(t, "position", p, sgp4.Vector{X: -1033.47503130, Y: 7901.30558560, Z: 6380.34453270}, 1e-6)
+	checkVector(t, "velocity", v, sgp4.Vector{X: -3.225636520, Y: -2.872451450, Z: 5.531931288}, 1e-5)
+}
+
+// Returns the Earth fixed position of an object at the given look angles from the site, and a velocity with
+// the given range rate and a perpendicular component.
+func lookTarget(s Site, az, el, r, rangeRate float64) (sgp4.Vector, sgp4.Vector) {
+	sinLat, cosLat := math.Sincos(s.Latitude * deg2rad)
+	sinLon, cosLon := math.Sincos(s.Longitude * deg2rad)
+	east := sgp4.Vector{X: -sinLon, Y: cosLon}
+	north := sgp4.Vector{X: -sinLat * cosLon, Y: -sinLat * sinLon, Z: cosLat}
+	up := sgp4.Vector{X: cosLat * cosLon, Y: cosLat * sinLon, Z: sinLat}
+
+	sinAz, cosAz := math.Sincos(az * deg2rad)
+	sinEl, cosEl := math.Sincos(el * deg2rad)
+	dir := func(e, n, u float64) sgp4.Vector {
+		return sgp4.Vector{
+			X: e*east.X + n*north.X + u*up.X,
+			Y: e*east.Y + n*north.Y + u*up.Y,
+			Z: e*east.Z + n*north.Z + u*up.Z,
+		}
+	}
+	los := dir(cosEl*sinAz, cosEl*cosAz, sinEl)
+	perp := dir(cosAz, -sinAz, 0)
+
+	site := s.ECEF()
+	pos := sgp4.Vector{X: site.X + r*los.X, Y: site.Y + r*los.Y, Z: site.Z + r*los.Z}
+	vel := sgp4.Vector{X: rangeRate*los.X + 5*perp.X, Y: rangeRate*los.Y + 5*perp.Y, Z: rangeRate*los.Z + 5*perp.Z}
+	return pos, vel
+}
+
+func TestLookECEF(t *testing.T) {
+	// The site and the observation of Vallado, example 7-1.
+	vallado := Site{Latitude: 39.007, Longitude: -104.883, Altitude: 2187}
+	tests := []struct {
+		name string
+		site Site
+		want Topocentric
+	}{
+		{"vallado", vallado, Topocentric{Az: 205.6, El: 30.7, Range: 604.68, RangeRate: 2.08}},
+		{"north", Site{Latitude: 47.5, Longitude: 19, Altitude: 100}, Topocentric{Az: 0, El: 10, Range: 2000,
+			RangeRate: -3}},
+		{"east", Site{Latitude: -33.9, Longitude: 151.2}, Topocentric{Az: 90, El: 45, Range: 800}},
+		{"zenith", Site{Latitude: 0, Longitude: 0}, Topocentric{Az: 0, El: 90, Range: 400}},
+		{"below the horizon", vallado, Topocentric{Az: 300, El: -5, Range: 3000, RangeRate: 6}},
+	}
+	for _, tt := range tests {
+		pos, vel := lookTarget(tt.site, tt.want.Az, tt.want.El, tt.want.Range, tt.want.RangeRate)
+		l := tt.site.LookECEF(pos, vel)
+		if math.Abs(l.Az-tt.want.Az) > 1e-6 || math.Abs(l.El-tt.want.El) > 1e-6 ||
+			math.Abs(l.Range-tt.want.Range) > 1e-6 || math.Abs(l.RangeRate-tt.want.RangeRate) > 1e-9 {
+			t.Errorf("%s: look angles %+v, want %+v", tt.name, l, tt.want)
+		}
+	}
+
+	// The refraction is added to the elevation.
+	refr := vallado
+	refr.Refraction = true
+	temp := -20.0
+	refr.Temperature = &temp
+	pos, vel := lookTarget(vallado, 205.6, 30.7, 604.68, 0)
+	l := refr.LookECEF(pos, vel)
+	if want := 30.7 + Refraction(30.7, StandardPressure, temp); math.Abs(l.El-want) > 1e-6 || l.El <= 30.7 {
+		t.Errorf("refracted elevation %v, want %v", l.El, want)
+	}
+}
+
+func TestLookTEME(t *testing.T) {
+	// A geostationary satellite above the site's longitude is at the same look angles all day.
+	site := Site{Latitude: 47.5, Longitude: 19}
+	geo := GeodeticToECEF(0, 19, 35786e3)
+	start := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	for h := 0; h < 24; h += 3 {
+		tm := start.Add(time.Duration(h) * time.Hour)
+		g := GMST(tm)
+		sinG, cosG := math.Sincos(g)
+		pos := sgp4.Vector{X: cosG*geo.X - sinG*geo.Y, Y: sinG*geo.X + cosG*geo.Y}
+		vel := sgp4.Vector{X: -earthRotationRate * pos.Y, Y: earthRotationRate * pos.X}
+		l := site.LookTEME(pos, vel, tm)
+		if math.Abs(l.Az-180) > 1e-6 || math.Abs(l.El-35.45) > 0.01 || math.Abs(l.RangeRate) > 1e-9 {
+			t.Errorf("look angles %+v at %v", l, tm)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package astro
+
+import (
+	"math"
+	"time"
+
+	"github.com/nonoo/jampec/sgp4"
+)
+
+const AU = 149597870.7 // km
+
+// SunPosition returns the approximate geocentric inertial position of the Sun in km. The low precision
+// formula of the Astronomical Almanac is accurate to about 0.01 degrees, which is plenty for shadow and
+// twilight calculations.
+func SunPosition(t time.Time) sgp4.Vector {
+	n := JulianDate(t) - 2451545.0
+	l := 280.460 + 0.9856474*n
+	g := (357.528 + 0.9856003*n) * deg2rad
+	lambda := (l + 1.915*math.Sin(g) + 0.020*math.Sin(2*g)) * deg2rad
+	eps := (23.439 - 0.0000004*n) * deg2rad
+	r := (1.00014 - 0.01671*math.Cos(g) - 0.00014*math.Cos(2*g)) * AU
+
+	return sgp4.Vector{
+		X: r * math.Cos(lambda),
+		Y: r * math.Cos(eps) * math.Sin(lambda),
+		Z: r * math.Sin(eps) * math.Sin(lambda),
+	}
+}
+
+// Sunlit returns true if an object at the given inertial position (km) is not in the Earth's shadow. A
+// cylindrical shadow model is used.
+func Sunlit(pos sgp4.Vector, t time.Time) bool {
+	sun := SunPosition(t)
+	sn := sun.Norm()
+	// Projection of the position on the Sun direction.
+	s := (pos.X*sun.X + pos.Y*sun.Y + pos.Z*sun.Z) / sn
+	if s >= 0 {
+		return true
+	}
+	px := pos.X - s*sun.X/sn
+	py := pos.Y - s*sun.Y/sn
+	pz := pos.Z - s*sun.Z/sn
+	return math.Sqrt(px*px+py*py+pz*pz) > sgp4.EarthRadiusKm
+}
+
+// SunElevation returns the elevation of the Sun in degrees as seen from the site.
+func (s Site) SunElevation(t time.Time) float64 {
+	return s.LookTEME(SunPosition(t), sgp4.Vector{}, t).El
+}
@@ -0,0 +1,33 @@
+// Package astro contains the coordinate and time conversions needed to point at a satellite from an
+// observer site.
+package astro
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	twoPi   = 2 * math.Pi
+	deg2rad = math.Pi / 180
+	rad2deg = 180 / math.Pi
+
+	// Earth rotation rate in rad/s.
+	earthRotationRate = 7.292115146706979e-5
+)
+
+// JulianDate returns the Julian date of t. UT1 is approximated with UTC, the difference is below a second.
+func JulianDate(t time.Time) float64 {
+	return float64(t.Unix())/86400 + float64(t.Nanosecond())/86400e9 + 2440587.5
+}
+
+// GMST returns the Greenwich mean sidereal time in radians (IAU 1982 model, as used by SGP4).
+func GMST(t time.Time) float64 {
+	tut1 := (JulianDate(t) - 2451545.0) / 36525.0
+	temp := -6.2e-6*tut1*tut1*tut1 + 0.093104*tut1*tut1 + (876600.0*3600+8640184.812866)*tut1 + 67310.54841
+	temp = math.Mod(temp*deg2rad/240.0, twoPi)
+	if temp < 0 {
+		temp += twoPi
+	}
+	return temp
+}
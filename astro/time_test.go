@@ -0,0 +1,54 @@
+package astro
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestJulianDate(t *testing.T) {
+	tests := []struct {
+		t    time.Time
+		want float64
+	}{
+		{time.Date(2000, 1, 1, 12, 0, 0, 0, time.UTC), 2451545},
+		{time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC), 2440587.5},
+		// Vallado, example 3-4.
+		{time.Date(1996, 10, 26, 14, 20, 0, 0, time.UTC), 2450383.09722222},
+	}
+	for _, tt := range tests {
+		if jd := JulianDate(tt.t); math.Abs(jd-tt.want) > 1e-8 {
+			t.Errorf("julian date of %v is %.8f, want %.8f", tt.t, jd, tt.want)
+		}
+	}
+}
+
+func TestGMST(t *testing.T) {
+	tests := []struct {
+		t    time.Time
+		want float64 // Degrees.
+	}{
+		{time.Date(2000, 1, 1, 12, 0, 0, 0, time.UTC), 280.46061837},
+		// Vallado, example 3-5.
+		{time.Date(1992, 8, 20, 12, 14, 0, 0, time.UTC), 152.57878781},
+		// Sidereal time is the same in another time zone.
+		{time.Date(1992, 8, 20, 14, 14, 0, 0, time.FixedZone("CEST", 2*3600)), 152.57878781},
+	}
+	for _, tt := range tests {
+		if g := GMST(tt.t) * rad2deg; math.Abs(g-tt.want) > 1e-7 {
+			t.Errorf("gmst at %v is %.8f, want %.8f", tt.t, g, tt.want)
+		}
+	}
+
+	// A sidereal day is shorter than a solar day, the angle wraps around.
+	t0 := time.Date(2000, 1, 1, 12, 0, 0, 0, time.UTC)
+	siderealDay := time.Duration(math.Round(twoPi / earthRotationRate * 1e9))
+	if d := GMST(t0.Add(siderealDay)) - GMST(t0); math.Abs(d) > 1e-6 {
+		t.Errorf("gmst changed by %v in a sidereal day", d)
+	}
+	for h := 0; h < 48; h++ {
+		if g := GMST(t0.Add(time.Duration(h) * time.Hour)); g < 0 || g >= twoPi {
+			t.Errorf("gmst %v out of range", g)
+		}
+	}
+}
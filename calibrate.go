@@ -0,0 +1,172 @@
+package main
+
+import (
+	"math"
+	"time"
+
+	"github.com/nonoo/jampec/guide"
+	"github.com/nonoo/jampec/mount"
+)
+
+type calibrationState int
+
+const (
+	calibrationStateStart = calibrationState(iota)
+	calibrationStateAz
+	calibrationStateEl
+)
+
+const (
+	// The mount has arrived when it's closer to the goto position than this fraction of the step.
+	calibrationArrivalTolerance = 0.05
+	// The mount has also arrived if it stopped moving for this long after the goto started, as it may not
+	// reach the exact position.
+	calibrationStopTime = time.Second
+	// Position changes smaller than this (in degrees) don't count as moving.
+	calibrationStopThreshold = 0.001
+	// Calibration is aborted if the mount doesn't arrive in time.
+	calibrationGotoTimeout = time.Minute
+)
+
+// Calibration moves the mount by a known amount on each axis while a fixed target (a star for example) is
+// tracked, and solves for the pixel to axis transform from the resulting target movement.
+type calibration struct {
+	state calibrationState
+
+	// The current goto, and the last mount position change during it.
+	gotoPos      mount.Position
+	gotoTime     time.Time
+	lastPos      mount.Position
+	lastMoveTime time.Time
+	moved        bool
+	// When the mount arrived at the goto position, zero while it's on the way.
+	arrivedTime time.Time
+
+	startPix guide.Point
+	startPos mount.Position
+	azPix    guide.Point
+	azPos    mount.Position
+}
+
+func (s *camStruct) toggleCalibration(cmdChan chan mountCmd) {
+	if s.calibration != nil {
+		log.Print("cam ", s.nr, " calibration aborted")
+		s.stopCalibration(cmdChan)
+		return
+	}
+	if s.mount == nil {
+		log.Error("cam ", s.nr, " can't calibrate without a mount")
+		return
+	}
+	log.Print("cam ", s.nr, " calibration started")
+	s.calibration = &calibration{}
+}
+
+func (s *camStruct) stopCalibration(cmdChan chan mountCmd) {
+	if s.calibration.state != calibrationStateStart {
+		sendMountCmd(cmdChan, mountCmd{cmdType: mountCmdTypeGoto, pos: s.calibration.startPos})
+	}
+	s.calibration = nil
+}
+
+func (s *camStruct) updateCalibration(td *trackData, cmdChan chan mountCmd) {
+	c := s.calibration
+
+	if td.trackerRect.Empty() {
+		log.Error("cam ", s.nr, " calibration aborted, no tracked target")
+		s.stopCalibration(cmdChan)
+		return
+	}
+
+	pos, ok := s.mountPosition()
+	if !ok {
+		return
+	}
+	now := time.Now()
+	step := s.config.Calibration.Step
+
+	if c.state != calibrationStateStart {
+		if c.arrivedTime.IsZero() {
+			if !c.arrived(pos, step, now) {
+				if now.Sub(c.gotoTime) > calibrationGotoTimeout {
+					log.Error("cam ", s.nr, " calibration aborted, the mount didn't reach ", c.gotoPos)
+					s.stopCalibration(cmdChan)
+				}
+				return
+			}
+			c.arrivedTime = now
+		}
+		settle := time.Duration(s.config.Calibration.SettleTime * float64(time.Second))
+		if now.Sub(c.arrivedTime) < settle {
+			return
+		}
+	}
+	pix := td.center
+
+	switch c.state {
+	case calibrationStateStart:
+		s.resetGuiding()
+		c.startPix = pix
+		c.startPos = pos
+		c.startGoto(mount.Position{Az: pos.Az + step, El: pos.El}, pos, now, cmdChan)
+		c.state = calibrationStateAz
+	case calibrationStateAz:
+		c.azPix = pix
+		c.azPos = pos
+		c.startGoto(mount.Position{Az: c.startPos.Az, El: c.startPos.El + step}, pos, now, cmdChan)
+		c.state = calibrationStateEl
+	case calibrationStateEl:
+		da1 := [2]float64{azDiff(c.azPos.Az, c.startPos.Az), c.azPos.El - c.startPos.El}
+		da2 := [2]float64{azDiff(pos.Az, c.startPos.Az), pos.El - c.startPos.El}
+		dp1 := guide.Point{X: c.azPix.X - c.startPix.X, Y: c.azPix.Y - c.startPix.Y}
+		dp2 := guide.Point{X: pix.X - c.startPix.X, Y: pix.Y - c.startPix.Y}
+		s.stopCalibration(cmdChan)
+
+		m, err := guide.SolvePixelToAxis(da1, da2, dp1, dp2)
+		if err != nil {
+			log.Error("cam ", s.nr, " calibration failed: ", err)
+			return
+		}
+		log.Print("cam ", s.nr, " calibration done, pixel to axis transform: ", m)
+
+		s.config.Guide.PixelToAxis = &m
+		s.guideCtrl = guide.NewController(s.config.Guide)
+		if err := updateConfig(s.nr, func(c *DevConfig) { c.Guide.PixelToAxis = &m }, m, "guide", "pixelToAxis"); err != nil {
+			log.Error("cam ", s.nr, " can't save calibration: ", err)
+		}
+	}
+}
+
+func (c *calibration) startGoto(p, current mount.Position, now time.Time, cmdChan chan mountCmd) {
+	sendMountCmd(cmdChan, mountCmd{cmdType: mountCmdTypeGoto, pos: p})
+	c.gotoPos = p
+	c.gotoTime = now
+	c.lastPos = current
+	c.lastMoveTime = now
+	c.moved = false
+	c.arrivedTime = time.Time{}
+}
+
+// Returns true if the mount reached the goto position, or stopped after moving towards it.
+func (c *calibration) arrived(pos mount.Position, step float64, now time.Time) bool {
+	if positionDiff(pos, c.gotoPos) < calibrationArrivalTolerance*math.Abs(step) {
+		return true
+	}
+	if positionDiff(pos, c.lastPos) > calibrationStopThreshold {
+		c.lastPos = pos
+		c.lastMoveTime = now
+		c.moved = true
+		return false
+	}
+	return c.moved && now.Sub(c.lastMoveTime) >= calibrationStopTime
+}
+
+// Returns the larger of the axis differences of two positions in degrees.
+func positionDiff(a, b mount.Position) float64 {
+	return math.Max(math.Abs(azDiff(a.Az, b.Az)), math.Abs(a.El-b.El))
+}
+
+// Returns the difference of two azimuths in the [-180, 180] range.
+func azDiff(a, b float64) float64 {
+	return math.Remainder(a-b, 360)
+}
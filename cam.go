@@ -5,21 +5,71 @@ import (
 	"fmt"
 	"image"
 	"image/color"
-
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nonoo/jampec/alpaca"
+	"github.com/nonoo/jampec/event"
+	"github.com/nonoo/jampec/guide"
+	"github.com/nonoo/jampec/indi"
+	"github.com/nonoo/jampec/mount"
+	"github.com/nonoo/jampec/rotctld"
+	"github.com/nonoo/jampec/sgp4"
+	"github.com/nonoo/jampec/track"
 	"gocv.io/x/gocv"
-	"gocv.io/x/gocv/contrib"
 )
 
 type camStruct struct {
-	nr                int
-	config            DevConfig
-	ctrlOutChan       chan ctrlMsg
-	ctrlInChan        chan ctrlMsg
+	nr     int
+	config DevConfig
+	// Commands of the event bus.
+	events            *event.Subscription
 	stopRequestedChan chan bool
 	stopFinishedChan  chan bool
 
-	cam    *gocv.VideoCapture
-	window *gocv.Window
+	source frameSource
+	// The window, or in headless mode the console if keys are enabled. Empty in headless mode without keys.
+	frontends []frontend
+
+	indiClient *indi.Client
+	mount      mount.Mount
+
+	// Only used by the derotator loop.
+	rotator           *alpaca.Rotator
+	rotatorAngle      float64
+	rotatorAngleValid bool
+	rotatorErr        string
+
+	mountPosMutex sync.Mutex
+	mountPos      mount.Position
+	mountPosValid bool
+
+	guideCtrl     *guide.Controller
+	guiding       bool
+	lastGuideTime time.Time
+	jogging       bool
+	jogUntil      time.Time
+	calibration   *calibration
+	acquisition   *acquisition
+	recorder      *recorder
+	// Number of frames still to be saved by the current snapshot.
+	snapshotsLeft int
+
+	trackState      trackState
+	trackStateSince time.Time
+
+	target *sgp4.Satellite
+	// Position commanded by a rotctld client, nil if there's none.
+	commandedMutex sync.Mutex
+	commanded      *commandedTarget
+	commandedStop  bool
+	rotctldServer  *rotctld.Server
+
+	targetUp         bool
+	targetPrediction guide.Prediction
+	feedForward      *guide.FeedForward
 
 	imgSize       image.Point
 	showOrigImage bool
@@ -28,62 +78,48 @@ type camStruct struct {
 	controlActiveTrackerRectColor color.RGBA
 	controlActive                 bool
 
-	selectedRect          image.Rectangle
-	selectedRectColor     color.RGBA
-	selectedRectSelecting bool
+	selectedRect      image.Rectangle
+	selectedRectColor color.RGBA
 
 	reinitTrackerChan chan *image.Rectangle
+	trackerAlgorithm  string
+	// Sends the new algorithm to the tracker loop on switch.
+	trackerAlgorithmChan chan string
 }
 
-type trackData struct {
-	img  gocv.Mat
-	rect image.Rectangle
+// A frame read from the source.
+type camFrame struct {
+	img gocv.Mat
+	// Capture time.
+	time time.Time
+	nr   int
 }
 
-func (s *camStruct) onMouseClick(event gocv.MouseEventType, x, y int, flags gocv.MouseEventFlag) {
-	switch event {
-	case gocv.MouseEventLeftButtonDown:
-		s.selectedRectSelecting = true
-		s.selectedRect.Min.X = x
-		s.selectedRect.Min.Y = y
-		s.selectedRect.Max.X = x
-		s.selectedRect.Max.Y = y
-	case gocv.MouseEventLeftButtonUp:
-		if s.selectedRectSelecting {
-			s.selectedRect.Max.X = x
-			s.selectedRect.Max.Y = y
-			s.selectedRect = s.selectedRect.Canon()
-
-			if !s.selectedRect.Empty() {
-				if s.selectedRect.Max.X > s.imgSize.X {
-					s.selectedRect.Max.X = s.imgSize.X
-				}
-				if s.selectedRect.Max.Y > s.imgSize.Y {
-					s.selectedRect.Max.Y = s.imgSize.Y
-				}
-				s.reinitTrackerChan <- &s.selectedRect
-			}
-		}
-		s.selectedRectSelecting = false
-	case gocv.MouseEventMove:
-		if s.selectedRectSelecting {
-			s.selectedRect.Max.X = x
-			s.selectedRect.Max.Y = y
-		}
-	case gocv.MouseEventRightButtonUp: // Cancel
-		if !s.selectedRectSelecting {
-			s.selectedRect = image.Rectangle{}
-			s.reinitTrackerChan <- &s.selectedRect
-		}
-		s.selectedRectSelecting = false
-	}
+type trackData struct {
+	frameNr   int
+	frameTime time.Time
+	img       gocv.Mat
+	// The rectangle of the tracker if its measurement was accepted, empty otherwise.
+	rect image.Rectangle
+	// The rectangle of the tracker, even if its measurement was rejected.
+	trackerRect image.Rectangle
+	// Center of trackerRect, with subpixel precision if the tracker supports it.
+	center guide.Point
+	// Only set by the point source tracker.
+	centroid      *track.Centroid
+	trackerActive bool
+	// Filtered state of the target. Valid while the tracker follows the target, and for a while after it
+	// lost it.
+	filter      track.KalmanState
+	filterValid bool
 }
 
-func (s *camStruct) camReadLoop(imgChan chan gocv.Mat, errChan chan error, stopRequestedChan chan bool,
+func (s *camStruct) camReadLoop(imgChan chan camFrame, errChan chan error, stopRequestedChan chan bool,
 	stopFinishedChan chan bool) {
 
 	img := gocv.NewMat()
 	defer img.Close()
+	var nr int
 
 camReadLoop:
 	for {
@@ -93,18 +129,21 @@ camReadLoop:
 		default:
 		}
 
-		if ok := s.cam.Read(&img); !ok {
-			errChan <- errors.New("error reading camera")
+		if err := s.source.Read(&img); err != nil {
+			errChan <- err
 			<-stopRequestedChan
 			break camReadLoop
 		}
 		if img.Empty() {
 			continue
 		}
+		frame := camFrame{img: img.Clone(), time: time.Now(), nr: nr}
+		nr++
 
 		select {
-		case imgChan <- img.Clone():
+		case imgChan <- frame:
 		case <-stopRequestedChan:
+			frame.img.Close()
 			break camReadLoop
 		}
 	}
@@ -112,9 +151,10 @@ camReadLoop:
 	stopFinishedChan <- true
 }
 
-func (s *camStruct) trackLoop(imgToTrackChan chan gocv.Mat, trackDataChan chan *trackData,
+func (s *camStruct) trackLoop(imgToTrackChan chan camFrame, trackDataChan chan *trackData,
 	errChan chan error, stopRequestedChan chan bool, stopFinishedChan chan bool) {
 
+	var frame camFrame
 	var img gocv.Mat
 
 	img1 := gocv.NewMat()
@@ -125,15 +165,34 @@ func (s *camStruct) trackLoop(imgToTrackChan chan gocv.Mat, trackDataChan chan *
 	defer tmpImg.Close()
 
 	var reinitTrackerRect *image.Rectangle
-	var tracker gocv.Tracker
+	var tracker tracker
 	var trackerInitialized bool
+	var trackRect image.Rectangle
+	algorithm := s.config.Tracker.Algorithm
+	filter := track.NewKalman(s.config.Filter)
+	var lastFrameTime time.Time
+	var filterExpired bool
 
 trackLoop:
 	for {
 		select {
-		case img = <-imgToTrackChan:
+		case frame = <-imgToTrackChan:
+			img = frame.img
+			initialized := filter.Initialized()
+			if !lastFrameTime.IsZero() {
+				filter.Predict(frame.time.Sub(lastFrameTime).Seconds())
+			}
+			lastFrameTime = frame.time
+			filterExpired = initialized && !filter.Initialized()
 		case reinitTrackerRect = <-s.reinitTrackerChan:
 			continue
+		case algorithm = <-s.trackerAlgorithmChan:
+			// Continuing with the last tracked rectangle using the new algorithm.
+			if trackerInitialized && reinitTrackerRect == nil && !trackRect.Empty() {
+				r := trackRect
+				reinitTrackerRect = &r
+			}
+			continue
 		case <-stopRequestedChan:
 			break trackLoop
 		}
@@ -167,30 +226,74 @@ trackLoop:
 		}
 
 		if reinitTrackerRect != nil {
+			filter.Reset()
 			if trackerInitialized {
 				tracker.Close()
 				trackerInitialized = false
 			}
 			if !reinitTrackerRect.Empty() {
-				tracker = contrib.NewTrackerCSRT()
-				if !tracker.Init(img2, *reinitTrackerRect) {
-					errChan <- errors.New("can't init tracker")
+				var err error
+				tracker, err = newTracker(algorithm, s.config)
+				if err != nil {
+					errChan <- err
 					<-stopRequestedChan
 					break trackLoop
 				}
-				trackerInitialized = true
+				rect := *reinitTrackerRect
+				if _, ok := tracker.(*centroidTracker); !ok {
+					size := img2.Size()
+					rect = growTrackerRect(rect, s.config.Tracker.MinSize, image.Pt(size[1], size[0]))
+				}
+				// The point source tracker fails if there's no point source in the rectangle, that's not fatal.
+				if tracker.Init(img2, rect) {
+					trackerInitialized = true
+				} else {
+					tracker.Close()
+					log.Error("cam ", s.nr, " can't init ", algorithm, " tracker")
+				}
 			}
 			reinitTrackerRect = nil
 		}
 
-		var trackRect image.Rectangle
+		trackRect = image.Rectangle{}
+		var centroid *track.Centroid
 		if trackerInitialized {
-			trackRect, _ = tracker.Update(img2)
+			if p, ok := tracker.(interface{ predict(x, y float64) }); ok && filter.Initialized() {
+				fs := filter.State()
+				p.predict(fs.X, fs.Y)
+			}
+			var ok bool
+			trackRect, ok = tracker.Update(img2)
+			if !ok {
+				trackRect = image.Rectangle{}
+			}
+			if ct, ok := tracker.(*centroidTracker); ok && !trackRect.Empty() {
+				c := ct.centroid()
+				centroid = &c
+			}
 		}
 
 		td := trackData{
-			img:  img2.Clone(),
-			rect: trackRect,
+			frameNr:       frame.nr,
+			frameTime:     frame.time,
+			img:           img2.Clone(),
+			trackerRect:   trackRect,
+			centroid:      centroid,
+			trackerActive: trackerInitialized,
+		}
+		if centroid != nil {
+			td.center = guide.Point{X: centroid.X, Y: centroid.Y}
+		} else {
+			td.center.X, td.center.Y = rectCenter(trackRect)
+		}
+		// The filter is not restarted on the frame it expired on, so the target state machine notices it.
+		if !trackRect.Empty() && !filterExpired && s.acceptMeasurement(filter, td.center, centroid) {
+			td.rect = trackRect
+			filter.Correct(td.center.X, td.center.Y)
+		}
+		if trackerInitialized && filter.Initialized() {
+			td.filter = filter.State()
+			td.filterValid = true
 		}
 
 		select {
@@ -208,68 +311,171 @@ trackLoop:
 	stopFinishedChan <- true
 }
 
-// Returns true if exit is needed.
-func (s *camStruct) checkKeyPress() bool {
-	k := s.window.WaitKey(1)
-	if k >= '0' && k <= '9' {
-		s.ctrlOutChan <- ctrlMsg{msgType: ctrlMsgTypeActive, value1: k - '0'}
-	} else {
-		switch k {
-		case 27: // Esc
-			s.ctrlOutChan <- ctrlMsg{msgType: ctrlMsgTypeExit}
-			<-s.stopRequestedChan
-			return true
-		case 'o':
-			s.ctrlOutChan <- ctrlMsg{msgType: ctrlMsgTypeShowOriginalImage, value1: !s.showOrigImage}
+// Draws the filtered position with its uncertainty, and the velocity as an arrow pointing to where the
+// target will be in a second.
+func (s *camStruct) drawFilterState(img *gocv.Mat, fs track.KalmanState) {
+	c := s.trackerRectColor
+	if s.controlActive {
+		c = s.controlActiveTrackerRectColor
+	}
+	if fs.CoastTime > 0 {
+		c = s.selectedRectColor
+	}
+	p := image.Pt(int(fs.X), int(fs.Y))
+	r := int(2*fs.PositionStd()) + 3
+	gocv.Circle(img, p, r, c, 1)
+	gocv.ArrowedLine(img, p, image.Pt(int(fs.X+fs.VX), int(fs.Y+fs.VY)), c, 1)
+}
+
+// Handles a command of a front-end. Returns true if exit is needed.
+func (s *camStruct) control(cmd controlCmd, mountCmdChan chan mountCmd) bool {
+	switch cmd.cmdType {
+	case controlCmdTypeExit:
+		s.requestExit(fmt.Sprint("exit requested on cam ", s.nr), nil)
+		return true
+	case controlCmdTypeActivate:
+		bus.Publish(activateRequestedEvent{devNum: cmd.camNr})
+	case controlCmdTypeShowOriginalImage:
+		bus.Publish(showOriginalImageEvent{show: !s.showOrigImage})
+	case controlCmdTypeCalibrate:
+		bus.Publish(calibrateEvent{})
+	case controlCmdTypeSwitchTracker:
+		bus.Publish(switchTrackerEvent{})
+	case controlCmdTypeAcquire:
+		bus.Publish(acquireEvent{})
+	case controlCmdTypeRecord:
+		bus.Publish(recordEvent{})
+	case controlCmdTypeSnapshot:
+		bus.Publish(snapshotEvent{})
+	case controlCmdTypeSelectRect:
+		s.selectedRect = cmd.rect.Intersect(image.Rectangle{Max: s.imgSize})
+		if !s.selectedRect.Empty() {
+			s.reinitTrackerChan <- &s.selectedRect
 		}
+	case controlCmdTypeCancelSelection:
+		s.selectedRect = image.Rectangle{}
+		s.reinitTrackerChan <- &s.selectedRect
+		s.setTrackState(trackStateIdle)
+	case controlCmdTypeJog:
+		s.jog(cmd.azRate, cmd.elRate, mountCmdChan)
 	}
 	return false
 }
 
+func (s *camStruct) exitOnSourceError(err error) {
+	if errors.Is(err, io.EOF) {
+		log.Print("cam ", s.nr, " end of source")
+		err = nil
+	}
+	s.requestExit(fmt.Sprint("cam ", s.nr, " source stopped"), err)
+}
+
+// Waits until main stops the camera.
+func (s *camStruct) requestExit(reason string, err error) {
+	bus.Publish(exitRequestedEvent{reason: reason, err: err})
+	<-s.stopRequestedChan
+}
+
+// Handles an event of the bus.
+func (s *camStruct) handleEvent(e interface{}, mountCmdChan chan mountCmd) {
+	switch e := e.(type) {
+	case activateRequestedEvent:
+		active := false
+		if e.devNum == s.config.DevNum {
+			active = !s.controlActive
+		}
+		if active != s.controlActive {
+			s.controlActive = active
+			if !active && s.calibration != nil {
+				log.Print("cam ", s.nr, " calibration aborted, camera deactivated")
+				s.stopCalibration(mountCmdChan)
+			}
+			bus.Publish(camActivatedEvent{cam: s.nr, active: active})
+		}
+	case showOriginalImageEvent:
+		s.showOrigImage = e.show
+	case calibrateEvent:
+		if s.controlActive {
+			s.toggleCalibration(mountCmdChan)
+		}
+	case switchTrackerEvent:
+		s.trackerAlgorithm = nextTrackerAlgorithm(s.trackerAlgorithm)
+		log.Print("cam ", s.nr, " switching tracker to ", s.trackerAlgorithm)
+		s.trackerAlgorithmChan <- s.trackerAlgorithm
+	case acquireEvent:
+		s.toggleAcquisition()
+	case recordEvent:
+		s.toggleRecording()
+	case snapshotEvent:
+		s.startSnapshot()
+	}
+}
+
 func (s *camStruct) loop() {
-	camReadImgChan := make(chan gocv.Mat, 25)
+	camReadImgChan := make(chan camFrame, 25)
 	camReadErrChan := make(chan error)
 	camReadStopRequestedChan := make(chan bool)
 	camReadStopFinishedChan := make(chan bool)
 	go s.camReadLoop(camReadImgChan, camReadErrChan, camReadStopRequestedChan, camReadStopFinishedChan)
 
-	trackImgChan := make(chan gocv.Mat, 25)
+	trackImgChan := make(chan camFrame, 25)
 	trackDataChan := make(chan *trackData)
 	trackErrChan := make(chan error)
 	trackStopRequestedChan := make(chan bool)
 	trackStopFinishedChan := make(chan bool)
 	go s.trackLoop(trackImgChan, trackDataChan, trackErrChan, trackStopRequestedChan, trackStopFinishedChan)
 
+	var derotatorChan chan float64
+	derotatorStopRequestedChan := make(chan bool)
+	derotatorStopFinishedChan := make(chan bool)
+	if s.rotator != nil {
+		derotatorChan = make(chan float64, 1)
+		go s.derotatorLoop(derotatorChan, derotatorStopRequestedChan, derotatorStopFinishedChan)
+	}
+
+	mountCmdChan := make(chan mountCmd, 1)
+	mountStopRequestedChan := make(chan bool)
+	mountStopFinishedChan := make(chan bool)
+	if s.mount != nil {
+		go s.mountLoop(mountCmdChan, derotatorChan, mountStopRequestedChan, mountStopFinishedChan)
+	}
+
+	if s.config.Recorder.Auto {
+		s.toggleRecording()
+	}
+
 mainLoop:
 	for {
-		select {
-		case msg := <-s.ctrlInChan:
-			switch msg.msgType {
-			case ctrlMsgTypeActive:
-				v, _ := msg.value2.(bool)
-				if v {
-					s.controlActive = !s.controlActive
-				} else {
-					s.controlActive = false
-				}
-			case ctrlMsgTypeShowOriginalImage:
-				v, _ := msg.value1.(bool)
-				s.showOrigImage = v
+		// Handling all pending events before the next frame.
+	eventLoop:
+		for {
+			select {
+			case e := <-s.events.C:
+				s.handleEvent(e, mountCmdChan)
+			case err := <-camReadErrChan:
+				s.exitOnSourceError(err)
+				break mainLoop
+			case err := <-trackErrChan:
+				s.requestExit(fmt.Sprint("cam ", s.nr, " tracker error"), err)
+				break mainLoop
+			case <-s.stopRequestedChan:
+				break mainLoop
+			default:
+				break eventLoop
 			}
+		}
+
+		// The source may fail, or main may stop the camera, while waiting for the next frame.
+		var frame camFrame
+		select {
+		case frame = <-camReadImgChan:
 		case err := <-camReadErrChan:
-			s.ctrlOutChan <- ctrlMsg{msgType: ctrlMsgTypeExit, value1: err}
-			<-s.stopRequestedChan
-			break mainLoop
-		case err := <-trackErrChan:
-			s.ctrlOutChan <- ctrlMsg{msgType: ctrlMsgTypeExit, value1: err}
-			<-s.stopRequestedChan
+			s.exitOnSourceError(err)
 			break mainLoop
 		case <-s.stopRequestedChan:
 			break mainLoop
-		default:
 		}
-
-		origImg := <-camReadImgChan
+		origImg := frame.img
 
 		size := origImg.Size()
 		s.imgSize.X = size[1]
@@ -281,7 +487,23 @@ mainLoop:
 			img = &i
 		}
 
-		trackImgChan <- origImg
+		var recImg *gocv.Mat
+		if s.recorder != nil {
+			recImg = s.recorder.clone(origImg)
+		}
+		var snapshotImg *gocv.Mat
+		if s.snapshotsLeft > 0 {
+			i := origImg.Clone()
+			snapshotImg = &i
+		}
+
+		for _, f := range s.frontends {
+			if rf, ok := f.(rawFrameFrontend); ok && rf.WantsRaw() {
+				rf.ShowRaw(origImg)
+			}
+		}
+
+		trackImgChan <- frame
 
 		td := <-trackDataChan
 
@@ -289,9 +511,65 @@ mainLoop:
 			img = &td.img
 		}
 
+		s.updateTrackState(td)
+		if s.acquisition != nil {
+			s.updateAcquisition(td)
+		}
+
+		if s.calibration != nil {
+			s.updateCalibration(td, mountCmdChan)
+		} else if s.mount != nil {
+			s.updateGuiding(td, mountCmdChan)
+			s.updateCommandedTarget(mountCmdChan)
+			s.updateJog(mountCmdChan)
+		}
+
+		if recImg != nil {
+			s.recorder.add(recImg, s.recordMeta(td))
+		}
+		if snapshotImg != nil {
+			s.saveSnapshot(snapshotImg, td)
+		}
+
 		if s.controlActive {
 			gocv.PutText(img, "ACT", image.Point{X: 5, Y: 20}, gocv.FontHersheyPlain, 1.4,
 				s.controlActiveTrackerRectColor, 1)
+
+			b := s.guideCtrl.Boresight(s.imgSize.X, s.imgSize.Y)
+			bp := image.Pt(int(b.X), int(b.Y))
+			gocv.Line(img, bp.Add(image.Pt(-10, 0)), bp.Add(image.Pt(10, 0)), s.controlActiveTrackerRectColor, 1)
+			gocv.Line(img, bp.Add(image.Pt(0, -10)), bp.Add(image.Pt(0, 10)), s.controlActiveTrackerRectColor, 1)
+		}
+
+		if s.controlActive && s.targetUp {
+			p := s.targetPrediction
+			name := "rotctld"
+			if s.target != nil {
+				name = fmt.Sprint(s.target.TLE.SatNum)
+			}
+			text := fmt.Sprintf("%s az %.2f el %.2f corr %.0f%%", name, p.Az, p.El, s.feedForward.Weight()*100)
+			gocv.PutText(img, text, image.Point{X: 5, Y: s.imgSize.Y - 10}, gocv.FontHersheyPlain, 1.2,
+				s.controlActiveTrackerRectColor, 1)
+		}
+
+		if s.recorder != nil {
+			text := "REC"
+			if d := s.recorder.droppedFrames(); d > 0 {
+				text += fmt.Sprintf(" %d dropped", d)
+			}
+			gocv.PutText(img, text, image.Point{X: s.imgSize.X - 150, Y: 20}, gocv.FontHersheyPlain, 1.4,
+				s.selectedRectColor, 1)
+		}
+
+		gocv.PutText(img, s.trackState.String(), image.Point{X: 130, Y: 20}, gocv.FontHersheyPlain, 1.4,
+			s.trackStateColor(), 1)
+
+		if s.acquisition != nil {
+			s.drawAcquisition(img)
+		}
+
+		if s.calibration != nil {
+			gocv.PutText(img, "CAL", image.Point{X: 45, Y: 20}, gocv.FontHersheyPlain, 1.4, s.selectedRectColor, 1)
 		}
 
 		if !td.rect.Empty() {
@@ -303,29 +581,35 @@ mainLoop:
 			}
 
 			gocv.Rectangle(img, td.rect, *color, 2)
-			textSize := gocv.GetTextSize("Tracking", gocv.FontHersheyPlain, 1.2, 2)
+			text := "Tracking " + strings.ToUpper(s.trackerAlgorithm)
+			if td.centroid != nil {
+				text += fmt.Sprintf(" SNR %.0f flux %.0f", td.centroid.SNR, td.centroid.Flux)
+			}
+			textSize := gocv.GetTextSize(text, gocv.FontHersheyPlain, 1.2, 2)
 			pt := image.Pt(td.rect.Max.X-textSize.X, td.rect.Min.Y-5)
-			gocv.PutText(img, "Tracking", pt, gocv.FontHersheyPlain, 1.2, *color, 2)
+			gocv.PutText(img, text, pt, gocv.FontHersheyPlain, 1.2, *color, 2)
 		}
 
-		if s.selectedRectSelecting {
-			gocv.Rectangle(img, s.selectedRect, s.selectedRectColor, 2)
+		if td.filterValid {
+			s.drawFilterState(img, td.filter)
 		}
 
-		s.window.IMShow(*img)
+		for _, f := range s.frontends {
+			f.Show(*img)
+		}
 		img.Close()
-
-		// OpenCV does not indicate which window the key was pressed in so we only check keypresses
-		// in the first window.
-		if s.nr == 0 && s.checkKeyPress() {
-			break mainLoop
+		// The processed image is not shown when the original is, but it's still used by the acquisition.
+		if img != &td.img {
+			td.img.Close()
 		}
+		bus.Publish(frameAnnotatedEvent{status: s.status(td)})
 
-		// Window closed?
-		if s.window.GetWindowProperty(gocv.WindowPropertyFullscreen) < 0 {
-			s.ctrlOutChan <- ctrlMsg{msgType: ctrlMsgTypeExit}
-			<-s.stopRequestedChan
-			break mainLoop
+		for _, f := range s.frontends {
+			for _, cmd := range f.Poll() {
+				if s.control(cmd, mountCmdChan) {
+					break mainLoop
+				}
+			}
 		}
 	}
 
@@ -335,35 +619,89 @@ mainLoop:
 	trackStopRequestedChan <- true
 	<-trackStopFinishedChan
 
-	if s.cam != nil {
-		s.cam.Close()
+	if s.acquisition != nil {
+		s.stopAcquisition()
+	}
+	if s.recorder != nil {
+		s.recorder.stop()
+	}
+	snapshotWaitGroup.Wait()
+
+	if s.rotctldServer != nil {
+		s.rotctldServer.Close()
+	}
+	if s.mount != nil {
+		mountStopRequestedChan <- true
+		<-mountStopFinishedChan
+		s.mount.Close()
+	}
+	if s.rotator != nil {
+		derotatorStopRequestedChan <- true
+		<-derotatorStopFinishedChan
+	}
+
+	if s.source != nil {
+		s.source.Close()
 	}
-	if s.window != nil {
-		s.window.Close()
+	for _, f := range s.frontends {
+		f.Close()
 	}
+	s.events.Close()
 
 	s.stopFinishedChan <- true
 }
 
-func (s *camStruct) init(config DevConfig, nr int) error {
+func (s *camStruct) init(config DevConfig, nr int, headless, keys bool) error {
 	s.nr = nr
 	s.config = config
-	s.ctrlInChan = make(chan ctrlMsg)
-	s.ctrlOutChan = make(chan ctrlMsg)
+	// Lossy, as the cameras publish these events to themselves and to each other from their loops, and
+	// waiting for a full buffer would deadlock them. The loop handles all pending events on each frame, so
+	// events are only dropped if a camera is stuck.
+	s.events = bus.SubscribeLossy(16, activateRequestedEvent{}, showOriginalImageEvent{}, calibrateEvent{},
+		switchTrackerEvent{}, acquireEvent{}, recordEvent{}, snapshotEvent{})
 	s.reinitTrackerChan = make(chan *image.Rectangle)
+	s.trackerAlgorithm = s.config.Tracker.Algorithm
+	s.trackerAlgorithmChan = make(chan string)
 
 	var err error
-	s.cam, err = gocv.VideoCaptureDevice(s.config.DevNum)
+	s.source, err = openSource(s.config)
 	if err != nil {
-		return fmt.Errorf("can't open video capture device %d", s.config.DevNum)
+		return err
 	}
 
-	s.window = gocv.NewWindow(fmt.Sprint("jampec video", s.config.DevNum))
+	if s.config.Indi.Device != "" {
+		s.indiClient, err = getIndiClient(s.config.Indi.Server)
+		if err != nil {
+			return err
+		}
+		if err = s.indiClient.GetProperties(s.config.Indi.Device, ""); err != nil {
+			return fmt.Errorf("can't get properties of indi device %s: %w", s.config.Indi.Device, err)
+		}
+	}
 
-	s.window.ResizeWindow(s.config.WindowWidth, s.config.WindowHeight)
+	s.mount, err = s.openMount()
+	if err != nil {
+		return fmt.Errorf("can't open mount of cam %d: %w", s.nr, err)
+	}
+	if err = s.openRotator(); err != nil {
+		return fmt.Errorf("can't open rotator of cam %d: %w", s.nr, err)
+	}
+	if err = s.startRotctldServer(); err != nil {
+		return fmt.Errorf("can't start rotctld server of cam %d: %w", s.nr, err)
+	}
+	s.guideCtrl = guide.NewController(s.config.Guide)
+	s.feedForward = guide.NewFeedForward(s.config.Guide.FeedForward)
+	if err = s.loadTarget(); err != nil {
+		return fmt.Errorf("can't load target of cam %d: %w", s.nr, err)
+	}
 
-	// Implementation from https://github.com/hybridgroup/gocv/pull/603/commits/410d1a795b55b6bbca775b5e36401c65fb05ebc5
-	s.window.SetMouseCallback(s.onMouseClick)
+	if headless {
+		if keys {
+			s.frontends = append(s.frontends, newConsoleFrontend())
+		}
+	} else {
+		s.frontends = append(s.frontends, newWindowFrontend(s.config, keys))
+	}
 
 	s.selectedRectColor = color.RGBA{255, 0, 0, 0}
 	s.trackerRectColor = color.RGBA{100, 100, 100, 0}
@@ -1,8 +1,21 @@
 package main
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/nonoo/jampec/astro"
+	"github.com/nonoo/jampec/guide"
+	"github.com/nonoo/jampec/indi"
+	"github.com/nonoo/jampec/jsonedit"
+	"github.com/nonoo/jampec/rotctld"
+	"github.com/nonoo/jampec/track"
 )
 
 type DevConfig struct {
@@ -16,25 +29,189 @@ type DevConfig struct {
 		BinaryThreshold int  `json:"binaryThreshold"`
 		ErodeDilate     bool `json:"erodeDilate"`
 	} `json:"imageTransform"`
+	Source struct {
+		// Supported types: "device" (uses devNum), "file" (a video file), "pipeline" (a GStreamer or ffmpeg
+		// pipeline) and "sequence" (a directory of numbered image files).
+		Type string `json:"type"`
+		Path string `json:"path"`
+		// Capture backend of files and pipelines: "gstreamer", "ffmpeg" or empty for automatic.
+		API string `json:"api"`
+		// Frame rate of sequences, and of video files which don't store it.
+		FPS float64 `json:"fps"`
+		// File sources are read as fast as possible instead of at their frame rate.
+		Fast bool `json:"fast"`
+		Loop bool `json:"loop"`
+		// Seconds to skip at the start of file sources.
+		Start float64 `json:"start"`
+	} `json:"source"`
+	Recorder struct {
+		// Each recording is written to a new subdirectory of Dir.
+		Dir string `json:"dir"`
+		// "video" or "sequence" (lossless PNG files).
+		Format string `json:"format"`
+		// FourCC code and frame rate of videos.
+		Codec string  `json:"codec"`
+		FPS   float64 `json:"fps"`
+		// Start recording when the camera starts.
+		Auto bool `json:"auto"`
+		// Number of frames waiting to be written, more are dropped.
+		QueueSize int `json:"queueSize"`
+	} `json:"recorder"`
+	Snapshot struct {
+		// FITS files are written to Dir.
+		Dir string `json:"dir"`
+		// Number of consecutive frames saved by a snapshot.
+		Count int `json:"count"`
+		// Exposure time of the camera in seconds, written to EXPTIME. Not all capture backends can read it.
+		Exposure float64 `json:"exposure"`
+		// Written to INSTRUME, "cam <nr>" if not set.
+		Camera string `json:"camera"`
+	} `json:"snapshot"`
+	Tracker struct {
+		// Supported algorithms: "csrt", "kcf", "mil", "centroid".
+		Algorithm string `json:"algorithm"`
+		// Selected rectangles smaller than this many pixels are grown to this size before initializing the
+		// OpenCV trackers.
+		MinSize int `json:"minSize"`
+		// Settings of the point source tracker.
+		Centroid track.CentroidConfig `json:"centroid"`
+	} `json:"tracker"`
+	Acquisition struct {
+		// Moving pixels are found by "diff" (frame differencing), "mog2" or "knn" background subtraction.
+		Method string `json:"method"`
+		// Brightness increase threshold of frame differencing.
+		Threshold int `json:"threshold"`
+		// Number of frames the background subtractors learn from.
+		History int `json:"history"`
+		track.AcquisitionConfig
+	} `json:"acquisition"`
+	TrackState struct {
+		// Point source tracker measurements with lower SNR are rejected.
+		MinSNR float64 `json:"minSNR"`
+		// Measurements farther than this many standard deviations from the filter's prediction are rejected.
+		Gate float64 `json:"gate"`
+		// If set, acquisition is started ReacquireDelay seconds after the target is lost.
+		Reacquire      bool    `json:"reacquire"`
+		ReacquireDelay float64 `json:"reacquireDelay"`
+		// Acquisition gives up after this many seconds. Zero means never.
+		AcquireTimeout float64 `json:"acquireTimeout"`
+	} `json:"trackState"`
+	// Kalman filter over the tracked position.
+	Filter track.KalmanConfig `json:"filter"`
+	Indi   struct {
+		Server string `json:"server"`
+		Device string `json:"device"`
+	} `json:"indi"`
+	Mount struct {
+		// Supported types: "indi" (uses the indi section), "rotctld", "gs232", "easycomm", "lx200", "nexstar"
+		// and "alpaca". Empty means no mount.
+		Type string `json:"type"`
+		// Address of rotctld, of the Alpaca server, or of the TCP serial bridge of serial controllers if
+		// Device is not set. Alpaca servers are discovered if it's empty.
+		Address string `json:"address"`
+		// Device number of Alpaca telescopes.
+		DeviceNumber int `json:"deviceNumber"`
+		// Serial port and its baud rate of serial controllers.
+		Device string `json:"device"`
+		Baud   int    `json:"baud"`
+		// Axis rate of the rotator at the maximum speed of the move commands in degrees per second. If zero,
+		// rate moves are emulated with slews. The GS-232 elevation and both EasyComm II axes have no speed
+		// setting, they are switched on and off at the controller's speed, so for EasyComm any non-zero
+		// value only enables the move commands.
+		MaxRate float64 `json:"maxRate"`
+		// The LX200 mount has the :RA and :RE axis rate commands of the LX200GPS and Autostar II. Otherwise
+		// its axes are started and stopped at the slew rate selected on the hand controller.
+		RateCommands bool `json:"rateCommands"`
+		// Seconds between reading the mount position.
+		PollInterval float64 `json:"pollInterval"`
+	} `json:"mount"`
+	// Field de-rotator, following the parallactic angle at the mount's position.
+	Rotator struct {
+		// Supported types: "alpaca". Empty means no rotator.
+		Type string `json:"type"`
+		// Address of the Alpaca server, discovered if empty.
+		Address      string `json:"address"`
+		DeviceNumber int    `json:"deviceNumber"`
+		// Rotator position angle in degrees at zero parallactic angle.
+		Offset float64 `json:"offset"`
+		// Turn the rotator in the opposite direction.
+		Reverse bool `json:"reverse"`
+		// Rotator moves smaller than this in degrees are not sent.
+		Threshold float64 `json:"threshold"`
+	} `json:"rotator"`
+	// Rotctld server for clients like gpredict. The commanded position is used as the target, and the
+	// mount's position is reported back.
+	RotctldServer struct {
+		Listen string `json:"listen"`
+		// Seconds after the last command when the commanded position is dropped, and the target is
+		// followed again.
+		Timeout float64 `json:"timeout"`
+	} `json:"rotctldServer"`
+	Target struct {
+		// TLE file and catalog number of the satellite to follow. If not set, the mount is only guided
+		// optically.
+		TLE    string `json:"tle"`
+		SatNum int    `json:"satNum"`
+	} `json:"target"`
+	Guide       guide.Config `json:"guide"`
+	Calibration struct {
+		// Axis move in degrees.
+		Step float64 `json:"step"`
+		// Seconds to wait after the mount arrived at each calibration position before measuring the target
+		// position.
+		SettleTime float64 `json:"settleTime"`
+	} `json:"calibration"`
+}
+
+type Config struct {
+	Site astro.Site `json:"site"`
+	// No windows are created, commands are read from the standard input.
+	Headless bool `json:"headless"`
+	// Live view HTTP server, disabled if Listen is empty.
+	Server struct {
+		Listen      string  `json:"listen"`
+		JPEGQuality int     `json:"jpegQuality"`
+		MaxFPS      float64 `json:"maxFPS"`
+		// Origins of other sites (like "http://example.com:8080") which may use the WebSocket. Pages served
+		// by the live view server itself are always allowed.
+		AllowedOrigins []string `json:"allowedOrigins"`
+	} `json:"server"`
+	Cams []DevConfig `json:"cams"`
 }
 
-var configs []DevConfig
+var config Config
+var configFilename string
+var configMutex sync.Mutex
 
 func loadConfig(filename string) error {
-	f, err := os.Open(filename)
+	configFilename = filename
+
+	data, err := ioutil.ReadFile(filename)
 	if err != nil {
 		return err
 	}
 
-	defer f.Close()
-
-	err = json.NewDecoder(f).Decode(&configs)
-
+	if isLegacyConfig(data) {
+		err = json.Unmarshal(data, &config.Cams)
+	} else {
+		err = json.Unmarshal(data, &config)
+	}
 	if err != nil {
 		return err
 	}
 
+	if config.Site.Latitude < -90 || config.Site.Latitude > 90 {
+		return errors.New("invalid site latitude")
+	}
+	if config.Server.JPEGQuality <= 0 || config.Server.JPEGQuality > 100 {
+		config.Server.JPEGQuality = 80
+	}
+	if config.Server.MaxFPS <= 0 {
+		config.Server.MaxFPS = 10
+	}
+
 	// Checking some needed values.
+	configs := config.Cams
 	for i := range configs {
 		if configs[i].WindowWidth == 0 {
 			configs[i].WindowWidth = 1280
@@ -42,7 +219,151 @@ func loadConfig(filename string) error {
 		if configs[i].WindowHeight == 0 {
 			configs[i].WindowHeight = 720
 		}
+		if configs[i].Source.Type == "" {
+			configs[i].Source.Type = "device"
+		}
+		if !validSourceType(configs[i].Source.Type) {
+			return fmt.Errorf("unknown source type %q", configs[i].Source.Type)
+		}
+		if configs[i].Recorder.Dir == "" {
+			configs[i].Recorder.Dir = "recordings"
+		}
+		if configs[i].Recorder.Format == "" {
+			configs[i].Recorder.Format = "video"
+		}
+		if !validRecorderFormat(configs[i].Recorder.Format) {
+			return fmt.Errorf("unknown recorder format %q", configs[i].Recorder.Format)
+		}
+		if configs[i].Recorder.Codec == "" {
+			configs[i].Recorder.Codec = "MJPG"
+		}
+		if configs[i].Recorder.FPS == 0 {
+			configs[i].Recorder.FPS = defaultSourceFPS
+		}
+		if configs[i].Recorder.QueueSize == 0 {
+			configs[i].Recorder.QueueSize = 50
+		}
+		if configs[i].Snapshot.Dir == "" {
+			configs[i].Snapshot.Dir = "snapshots"
+		}
+		if configs[i].Snapshot.Count <= 0 {
+			configs[i].Snapshot.Count = 1
+		}
+		if configs[i].Tracker.Algorithm == "" {
+			configs[i].Tracker.Algorithm = defaultTrackerAlgorithm
+		}
+		configs[i].Tracker.Centroid.SetDefaults()
+		if configs[i].Acquisition.Method == "" {
+			configs[i].Acquisition.Method = "diff"
+		}
+		if !validAcquisitionMethod(configs[i].Acquisition.Method) {
+			return fmt.Errorf("unknown acquisition method %q", configs[i].Acquisition.Method)
+		}
+		if configs[i].Acquisition.Threshold == 0 {
+			configs[i].Acquisition.Threshold = 25
+		}
+		if configs[i].Acquisition.History == 0 {
+			configs[i].Acquisition.History = 100
+		}
+		configs[i].Acquisition.SetDefaults()
+		if configs[i].TrackState.Gate == 0 {
+			configs[i].TrackState.Gate = 5
+		}
+		configs[i].Filter.SetDefaults()
+		if err := configs[i].Filter.Validate(); err != nil {
+			return err
+		}
+		if !validTrackerAlgorithm(configs[i].Tracker.Algorithm) {
+			return fmt.Errorf("unknown tracker algorithm %q", configs[i].Tracker.Algorithm)
+		}
+		if configs[i].Indi.Server == "" {
+			configs[i].Indi.Server = indi.DefaultServer
+		}
+		if configs[i].Mount.Type == "rotctld" && configs[i].Mount.Address == "" {
+			configs[i].Mount.Address = rotctld.DefaultServer
+		}
+		if configs[i].Mount.Baud == 0 {
+			configs[i].Mount.Baud = 9600
+		}
+		if configs[i].Mount.PollInterval <= 0 {
+			configs[i].Mount.PollInterval = 0.2
+		}
+		if configs[i].RotctldServer.Timeout <= 0 {
+			configs[i].RotctldServer.Timeout = 30
+		}
+		if configs[i].Rotator.Threshold <= 0 {
+			configs[i].Rotator.Threshold = 0.1
+		}
+		configs[i].Guide.SetDefaults()
+		if configs[i].Calibration.Step == 0 {
+			configs[i].Calibration.Step = 0.5
+		}
+		if configs[i].Calibration.SettleTime == 0 {
+			configs[i].Calibration.SettleTime = 1
+		}
 	}
 
 	return nil
 }
+
+// Changes the config of the given device, and sets value at the path of JSON keys under the device's object
+// in the config file. The rest of the file is left as it is. The file is replaced atomically, so it's not
+// corrupted if writing fails.
+func updateConfig(nr int, update func(c *DevConfig), value interface{}, path ...string) error {
+	configMutex.Lock()
+	defer configMutex.Unlock()
+
+	update(&config.Cams[nr])
+
+	data, err := ioutil.ReadFile(configFilename)
+	if err != nil {
+		return err
+	}
+	p := []interface{}{"cams", nr}
+	if isLegacyConfig(data) {
+		p = []interface{}{nr}
+	}
+	for _, k := range path {
+		p = append(p, k)
+	}
+	data, err = jsonedit.Set(data, value, p...)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(configFilename, data)
+}
+
+// Older config files only contain the array of device configs.
+func isLegacyConfig(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte("["))
+}
+
+// Writes the data to a temporary file in the same directory, and renames it over the file.
+func writeFileAtomic(filename string, data []byte) error {
+	mode := os.FileMode(0644)
+	if fi, err := os.Stat(filename); err == nil {
+		mode = fi.Mode().Perm()
+	}
+
+	f, err := ioutil.TempFile(filepath.Dir(filename), filepath.Base(filename)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(f.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), filename)
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"net"
+	"time"
+
+	"github.com/nonoo/jampec/alpaca"
+	"github.com/nonoo/jampec/guide"
+	"github.com/nonoo/jampec/indi"
+	"github.com/nonoo/jampec/mount"
+	"github.com/nonoo/jampec/serial"
+	"github.com/nonoo/jampec/sgp4"
+)
+
+type mountCmdType int
+
+const (
+	mountCmdTypeMove = mountCmdType(iota)
+	mountCmdTypeGoto
+)
+
+type mountCmd struct {
+	cmdType mountCmdType
+	azRate  float64
+	elRate  float64
+	pos     mount.Position
+}
+
+func (s *camStruct) openMount() (mount.Mount, error) {
+	switch s.config.Mount.Type {
+	case "":
+		return nil, nil
+	case "indi":
+		if s.indiClient == nil {
+			return nil, errors.New("indi mount needs an indi device")
+		}
+		// The mount looks up the shared client again if the connection is lost.
+		server := s.config.Indi.Server
+		return mount.NewIndi(func() (*indi.Client, error) {
+			return getIndiClient(server)
+		}, s.config.Indi.Device)
+	case "rotctld":
+		m, err := mount.NewRotctld(s.config.Mount.Address, s.config.Mount.MaxRate)
+		if err != nil {
+			return nil, fmt.Errorf("can't connect to rotctld %s: %w", s.config.Mount.Address, err)
+		}
+		return m, nil
+	case "gs232", "easycomm", "lx200", "nexstar":
+		return s.openSerialMount()
+	case "alpaca":
+		c, nr, err := openAlpacaDevice(s.config.Mount.Address, "Telescope", s.config.Mount.DeviceNumber)
+		if err != nil {
+			return nil, err
+		}
+		m, err := mount.NewAlpaca(alpaca.NewTelescope(c, nr))
+		if err != nil {
+			return nil, fmt.Errorf("can't connect alpaca telescope: %w", err)
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("unknown mount type %s", s.config.Mount.Type)
+}
+
+// Opens the mounts connected to a serial port or a TCP serial bridge.
+func (s *camStruct) openSerialMount() (mount.Mount, error) {
+	port, err := s.openMountPort()
+	if err != nil {
+		return nil, err
+	}
+
+	var m mount.Mount
+	switch s.config.Mount.Type {
+	case "gs232":
+		m, err = mount.NewGS232(port, s.config.Mount.MaxRate)
+	case "easycomm":
+		var e *mount.EasyComm
+		if e, err = mount.NewEasyComm(port, s.config.Mount.MaxRate > 0); err == nil {
+			if v, err := e.Version(); err == nil {
+				log.Print("cam ", s.nr, " easycomm controller version ", v)
+			}
+			m = e
+		}
+	case "lx200":
+		m, err = mount.NewLX200(port, s.config.Mount.RateCommands)
+	case "nexstar":
+		m, err = mount.NewNexStar(port)
+	}
+	if err != nil {
+		port.Close()
+		return nil, fmt.Errorf("%s controller doesn't answer: %w", s.config.Mount.Type, err)
+	}
+	return m, nil
+}
+
+// Opens the serial port of the mount's controller, or connects to its TCP serial bridge.
+func (s *camStruct) openMountPort() (mount.Port, error) {
+	if s.config.Mount.Device != "" {
+		port, err := serial.Open(s.config.Mount.Device, s.config.Mount.Baud)
+		if err != nil {
+			return nil, fmt.Errorf("can't open %s: %w", s.config.Mount.Device, err)
+		}
+		return port, nil
+	}
+	if s.config.Mount.Address == "" {
+		return nil, errors.New("mount needs a device or an address")
+	}
+	conn, err := net.DialTimeout("tcp", s.config.Mount.Address, 5*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("can't connect to %s: %w", s.config.Mount.Address, err)
+	}
+	return conn, nil
+}
+
+// Sends the latest command to the mount loop, replacing the previous one if it was not sent yet.
+func sendMountCmd(cmdChan chan mountCmd, cmd mountCmd) {
+	select {
+	case <-cmdChan:
+	default:
+	}
+	cmdChan <- cmd
+}
+
+// Returns the last polled mount position.
+func (s *camStruct) mountPosition() (mount.Position, bool) {
+	s.mountPosMutex.Lock()
+	defer s.mountPosMutex.Unlock()
+	return s.mountPos, s.mountPosValid
+}
+
+func (s *camStruct) resetGuiding() {
+	s.guiding = false
+	s.guideCtrl.Reset()
+	s.feedForward.Reset()
+}
+
+// Calculates the axis rates when control is active. Without a target the mount is driven only by the
+// tracked position. With a target satellite, or a position commanded by a rotctld client, the mount follows
+// the target's trajectory, and the tracked position is only used as a correction on top of it.
+func (s *camStruct) updateGuiding(td *trackData, cmdChan chan mountCmd) {
+	now := time.Now()
+	var pred guide.Prediction
+	var hasTarget bool
+	var err error
+	if s.controlActive {
+		pred, hasTarget, err = s.predictCoarseTarget(now)
+	}
+	if !s.controlActive || (!hasTarget && !s.trackState.locked()) {
+		if s.guiding {
+			s.resetGuiding()
+			sendMountCmd(cmdChan, mountCmd{cmdType: mountCmdTypeMove})
+		}
+		return
+	}
+
+	var dt float64
+	if s.guiding {
+		dt = now.Sub(s.lastGuideTime).Seconds()
+	}
+	s.guiding = true
+	s.lastGuideTime = now
+
+	// The filtered position is used, which is still predicted while coasting.
+	locked := s.trackState.locked() && td.filterValid
+	var corr guide.Rates
+	if locked {
+		b := s.guideCtrl.Boresight(s.imgSize.X, s.imgSize.Y)
+		corr.Az, corr.El = s.guideCtrl.Update(td.filter.X-b.X, td.filter.Y-b.Y, dt)
+	} else {
+		s.guideCtrl.Reset()
+	}
+
+	cmd := mountCmd{cmdType: mountCmdTypeMove}
+	if !hasTarget {
+		cmd.azRate, cmd.elRate = corr.Az, corr.El
+		sendMountCmd(cmdChan, cmd)
+		return
+	}
+
+	if err != nil || pred.El < 0 {
+		if s.targetUp {
+			log.Print("cam ", s.nr, " target is not up, stopping mount")
+			s.targetUp = false
+		}
+		s.feedForward.Reset()
+		sendMountCmd(cmdChan, cmd)
+		return
+	}
+	s.targetUp = true
+	s.targetPrediction = pred
+
+	mountPos, mountPosValid := s.mountPosition()
+	r := s.feedForward.Update(pred, mountPos, mountPosValid, corr, locked, dt)
+	cmd.azRate, cmd.elRate = r.Az, r.El
+	sendMountCmd(cmdChan, cmd)
+}
+
+// Returns the position and rates of the target: the position commanded by a rotctld client, or the
+// prediction of the target satellite. Returns false if there's neither.
+func (s *camStruct) predictCoarseTarget(t time.Time) (guide.Prediction, bool, error) {
+	if p, ok := s.commandedPrediction(t); ok {
+		return p, true, nil
+	}
+	if s.target == nil {
+		return guide.Prediction{}, false, nil
+	}
+	p, err := s.predictTarget(t)
+	return p, true, err
+}
+
+// Returns the predicted look angles and rates of the target satellite from the site.
+func (s *camStruct) predictTarget(t time.Time) (guide.Prediction, error) {
+	l1, err := config.Site.Look(s.target, t)
+	if err != nil {
+		return guide.Prediction{}, err
+	}
+	l2, err := config.Site.Look(s.target, t.Add(time.Second))
+	if err != nil {
+		return guide.Prediction{}, err
+	}
+	return guide.Prediction{
+		Az:     l1.Az,
+		El:     l1.El,
+		AzRate: azDiff(l2.Az, l1.Az),
+		ElRate: l2.El - l1.El,
+	}, nil
+}
+
+// Loads the target satellite from the configured TLE file.
+func (s *camStruct) loadTarget() error {
+	if s.config.Target.TLE == "" {
+		return nil
+	}
+	tles, err := sgp4.LoadCatalog(s.config.Target.TLE)
+	if err != nil {
+		return err
+	}
+	tle, ok := sgp4.FindSatNum(tles, s.config.Target.SatNum)
+	if !ok {
+		return fmt.Errorf("satellite %d not found in %s", s.config.Target.SatNum, s.config.Target.TLE)
+	}
+	s.target, err = sgp4.NewSatellite(tle)
+	if err != nil {
+		return err
+	}
+	log.Print("cam ", s.nr, " target: ", tle.SatNum, " ", tle.Name, ", epoch ", tle.Epoch)
+	return nil
+}
+
+// Jog commands have to be repeated, otherwise the mount stops after this.
+const jogTimeout = time.Second
+
+// Moves the mount manually, when control is not active.
+func (s *camStruct) jog(azRate, elRate float64, cmdChan chan mountCmd) {
+	if s.mount == nil {
+		log.Error("cam ", s.nr, " has no mount to jog")
+		return
+	}
+	if s.controlActive || s.calibration != nil {
+		log.Error("cam ", s.nr, " can't jog while control is active")
+		return
+	}
+	limit := func(v, l float64) float64 {
+		return math.Max(-l, math.Min(l, v))
+	}
+	azRate = limit(azRate, s.config.Guide.Az.OutputLimit)
+	elRate = limit(elRate, s.config.Guide.El.OutputLimit)
+
+	s.jogging = azRate != 0 || elRate != 0
+	s.jogUntil = time.Now().Add(jogTimeout)
+	sendMountCmd(cmdChan, mountCmd{cmdType: mountCmdTypeMove, azRate: azRate, elRate: elRate})
+}
+
+// Stops jogging if the commands are not repeated.
+func (s *camStruct) updateJog(cmdChan chan mountCmd) {
+	if !s.jogging {
+		return
+	}
+	if s.controlActive {
+		// Guiding took over the mount.
+		s.jogging = false
+		return
+	}
+	if time.Now().After(s.jogUntil) {
+		s.jogging = false
+		sendMountCmd(cmdChan, mountCmd{cmdType: mountCmdTypeMove})
+	}
+}
+
+func rectCenter(rect image.Rectangle) (x, y float64) {
+	return float64(rect.Min.X+rect.Max.X) / 2, float64(rect.Min.Y+rect.Max.Y) / 2
+}
+
+// The parallactic angles are sent to derotatorChan if it's not nil.
+func (s *camStruct) mountLoop(cmdChan chan mountCmd, derotatorChan chan float64, stopRequestedChan chan bool,
+	stopFinishedChan chan bool) {
+	ticker := time.NewTicker(time.Duration(s.config.Mount.PollInterval * float64(time.Second)))
+	defer ticker.Stop()
+
+mountLoop:
+	for {
+		select {
+		case cmd := <-cmdChan:
+			var err error
+			switch cmd.cmdType {
+			case mountCmdTypeMove:
+				err = s.mount.Move(cmd.azRate, cmd.elRate)
+			case mountCmdTypeGoto:
+				err = s.mount.Goto(cmd.pos)
+			}
+			if err != nil {
+				log.Error("cam ", s.nr, " mount command error: ", err)
+			}
+		case <-ticker.C:
+			pos, err := s.mount.Position()
+			s.mountPosMutex.Lock()
+			s.mountPos = pos
+			s.mountPosValid = err == nil
+			s.mountPosMutex.Unlock()
+			bus.Publish(mountTelemetryEvent{cam: s.nr, pos: pos, time: time.Now(), err: err})
+			if err == nil && derotatorChan != nil {
+				sendDerotatorAngle(derotatorChan, config.Site.ParallacticAngle(pos.Az, pos.El))
+			}
+		case <-stopRequestedChan:
+			break mountLoop
+		}
+	}
+
+	if err := s.mount.Stop(); err != nil {
+		log.Error("cam ", s.nr, " mount stop error: ", err)
+	}
+
+	stopFinishedChan <- true
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/nonoo/jampec/alpaca"
+	"github.com/nonoo/jampec/mount"
+)
+
+// How long UDP discovery waits for the Alpaca servers to answer.
+const alpacaDiscoveryTimeout = 2 * time.Second
+
+// Returns the client of the Alpaca server at addr, or discovers a server with a device of the given type
+// if addr is empty. Also returns the number of the device.
+func openAlpacaDevice(addr, deviceType string, number int) (*alpaca.Client, int, error) {
+	if addr != "" {
+		return alpaca.NewClient(addr), number, nil
+	}
+	c, d, err := alpaca.FindDevice(deviceType, alpacaDiscoveryTimeout)
+	if err != nil {
+		return nil, 0, err
+	}
+	log.Print("found alpaca ", deviceType, " ", d.DeviceName, " on ", c.Addr())
+	return c, d.DeviceNumber, nil
+}
+
+func (s *camStruct) openRotator() error {
+	switch s.config.Rotator.Type {
+	case "":
+		return nil
+	case "alpaca":
+	default:
+		return fmt.Errorf("unknown rotator type %s", s.config.Rotator.Type)
+	}
+	if s.mount == nil {
+		return errors.New("rotator needs a mount")
+	}
+
+	c, nr, err := openAlpacaDevice(s.config.Rotator.Address, "Rotator", s.config.Rotator.DeviceNumber)
+	if err != nil {
+		return err
+	}
+	r := alpaca.NewRotator(c, nr)
+	if err := r.SetConnected(true); err != nil {
+		return fmt.Errorf("can't connect rotator: %w", err)
+	}
+	s.rotator = r
+	return nil
+}
+
+// Turns the rotator to cancel the field rotation. The mount loop sends the parallactic angle at the mount's
+// position after each poll, replacing the unread one, so the slow Alpaca requests don't delay the polls.
+func (s *camStruct) derotatorLoop(angleChan chan float64, stopRequestedChan chan bool, stopFinishedChan chan bool) {
+derotatorLoop:
+	for {
+		select {
+		case q := <-angleChan:
+			s.updateDerotation(q)
+		case <-stopRequestedChan:
+			break derotatorLoop
+		}
+	}
+
+	stopFinishedChan <- true
+}
+
+// Sends the parallactic angle to the derotator loop, dropping the one it didn't read yet.
+func sendDerotatorAngle(angleChan chan float64, q float64) {
+	select {
+	case <-angleChan:
+	default:
+	}
+	angleChan <- q
+}
+
+func (s *camStruct) updateDerotation(q float64) {
+	if s.config.Rotator.Reverse {
+		q = -q
+	}
+	angle := mount.NormalizeAz(s.config.Rotator.Offset + q)
+
+	if err := s.moveRotator(angle); err != nil {
+		// Only logging the first of the same errors, as this is retried on every poll.
+		if err.Error() != s.rotatorErr {
+			log.Error("cam ", s.nr, " rotator error: ", err)
+			s.rotatorErr = err.Error()
+		}
+		// The position is read again, as the move may have been done.
+		s.rotatorAngleValid = false
+		return
+	}
+	s.rotatorErr = ""
+}
+
+// Moves the rotator to the position angle the shortest way, so it doesn't turn almost a full revolution
+// when the angle crosses zero.
+func (s *camStruct) moveRotator(angle float64) error {
+	if !s.rotatorAngleValid {
+		pos, err := s.rotator.Position()
+		if err != nil {
+			return err
+		}
+		s.rotatorAngle = pos
+		s.rotatorAngleValid = true
+	}
+	delta := azDiff(angle, s.rotatorAngle)
+	if math.Abs(delta) < s.config.Rotator.Threshold {
+		return nil
+	}
+	// Relative moves would add up while the rotator is still moving. The angle is sent again after the
+	// next poll.
+	moving, err := s.rotator.IsMoving()
+	if err != nil {
+		return err
+	}
+	if moving {
+		return nil
+	}
+	if err := s.rotator.Move(delta); err != nil {
+		return err
+	}
+	s.rotatorAngle = angle
+	return nil
+}
@@ -0,0 +1,102 @@
+// Package event implements a publish/subscribe event bus. Events are values of concrete types, and
+// subscribers receive the events of the types they subscribed to.
+package event
+
+import (
+	"reflect"
+	"sync"
+)
+
+type Bus struct {
+	mutex sync.Mutex
+	subs  map[reflect.Type][]*Subscription
+}
+
+func NewBus() *Bus {
+	return &Bus{subs: make(map[reflect.Type][]*Subscription)}
+}
+
+// Subscription receives the events on C until it's closed.
+type Subscription struct {
+	C <-chan interface{}
+
+	c     chan interface{}
+	lossy bool
+	bus   *Bus
+	types []reflect.Type
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Subscribe returns a subscription to the events with the same type as the given ones. Publishing blocks
+// while the buffer of size events is full.
+func (b *Bus) Subscribe(size int, events ...interface{}) *Subscription {
+	return b.subscribe(size, false, events)
+}
+
+// SubscribeLossy is like Subscribe, but events are dropped if the buffer is full, so a slow subscriber
+// doesn't slow down the publishers.
+func (b *Bus) SubscribeLossy(size int, events ...interface{}) *Subscription {
+	return b.subscribe(size, true, events)
+}
+
+func (b *Bus) subscribe(size int, lossy bool, events []interface{}) *Subscription {
+	s := &Subscription{
+		c:     make(chan interface{}, size),
+		lossy: lossy,
+		bus:   b,
+		done:  make(chan struct{}),
+	}
+	s.C = s.c
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for _, e := range events {
+		t := reflect.TypeOf(e)
+		s.types = append(s.types, t)
+		b.subs[t] = append(b.subs[t], s)
+	}
+	return s
+}
+
+// Publish sends the event to the subscribers of its type.
+func (b *Bus) Publish(e interface{}) {
+	b.mutex.Lock()
+	subs := append([]*Subscription(nil), b.subs[reflect.TypeOf(e)]...)
+	b.mutex.Unlock()
+
+	for _, s := range subs {
+		if s.lossy {
+			select {
+			case s.c <- e:
+			default:
+			}
+			continue
+		}
+		select {
+		case s.c <- e:
+		case <-s.done:
+		}
+	}
+}
+
+// Close stops the delivery of events. C is not closed, as publishers may still be sending.
+func (s *Subscription) Close() {
+	s.closeOnce.Do(func() {
+		close(s.done)
+
+		b := s.bus
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+		for _, t := range s.types {
+			subs := b.subs[t]
+			for i := range subs {
+				if subs[i] == s {
+					b.subs[t] = append(subs[:i:i], subs[i+1:]...)
+					break
+				}
+			}
+		}
+	})
+}
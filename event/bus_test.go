@@ -0,0 +1,158 @@
+package event
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testEvent struct {
+	n int
+}
+
+type otherEvent struct{}
+
+func receive(t *testing.T, s *Subscription) interface{} {
+	t.Helper()
+	select {
+	case e := <-s.C:
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("no event")
+		return nil
+	}
+}
+
+func expectNone(t *testing.T, s *Subscription) {
+	t.Helper()
+	select {
+	case e := <-s.C:
+		t.Fatalf("unexpected event %v", e)
+	default:
+	}
+}
+
+func TestDelivery(t *testing.T) {
+	b := NewBus()
+	s1 := b.Subscribe(4, testEvent{})
+	s2 := b.Subscribe(4, testEvent{}, otherEvent{})
+	defer s1.Close()
+	defer s2.Close()
+
+	b.Publish(testEvent{n: 1})
+	b.Publish(otherEvent{})
+	// Only the types are matched, not the values.
+	b.Publish(&testEvent{n: 2})
+
+	if e := receive(t, s1); e != (testEvent{n: 1}) {
+		t.Errorf("s1 got %v", e)
+	}
+	expectNone(t, s1)
+	if e := receive(t, s2); e != (testEvent{n: 1}) {
+		t.Errorf("s2 got %v", e)
+	}
+	if e := receive(t, s2); e != (otherEvent{}) {
+		t.Errorf("s2 got %v", e)
+	}
+	expectNone(t, s2)
+}
+
+func TestBlocking(t *testing.T) {
+	b := NewBus()
+	s := b.Subscribe(1, testEvent{})
+	defer s.Close()
+
+	b.Publish(testEvent{n: 1})
+	published := make(chan bool)
+	go func() {
+		b.Publish(testEvent{n: 2})
+		close(published)
+	}()
+
+	select {
+	case <-published:
+		t.Fatal("publishing didn't block on a full buffer")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// No events are lost.
+	for i := 1; i <= 2; i++ {
+		if e := receive(t, s); e != (testEvent{n: i}) {
+			t.Errorf("got %v, want %d", e, i)
+		}
+	}
+	select {
+	case <-published:
+	case <-time.After(time.Second):
+		t.Fatal("publishing is still blocked")
+	}
+}
+
+func TestBlockingClose(t *testing.T) {
+	b := NewBus()
+	s := b.Subscribe(1, testEvent{})
+	b.Publish(testEvent{})
+
+	published := make(chan bool)
+	go func() {
+		b.Publish(testEvent{})
+		close(published)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	s.Close()
+
+	select {
+	case <-published:
+	case <-time.After(time.Second):
+		t.Fatal("closing the subscription didn't unblock the publisher")
+	}
+}
+
+func TestLossy(t *testing.T) {
+	b := NewBus()
+	s := b.SubscribeLossy(2, testEvent{})
+	defer s.Close()
+
+	done := make(chan bool)
+	go func() {
+		for i := 1; i <= 5; i++ {
+			b.Publish(testEvent{n: i})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("publishing blocked on a lossy subscription")
+	}
+
+	// The events which didn't fit in the buffer are dropped.
+	for i := 1; i <= 2; i++ {
+		if e := receive(t, s); e != (testEvent{n: i}) {
+			t.Errorf("got %v, want %d", e, i)
+		}
+	}
+	expectNone(t, s)
+}
+
+func TestUnsubscribe(t *testing.T) {
+	b := NewBus()
+	s1 := b.Subscribe(4, testEvent{}, otherEvent{})
+	s2 := b.Subscribe(4, testEvent{})
+	defer s2.Close()
+
+	s1.Close()
+	// Closing again does nothing.
+	s1.Close()
+
+	b.Publish(testEvent{n: 1})
+	b.Publish(otherEvent{})
+	expectNone(t, s1)
+	if e := receive(t, s2); e != (testEvent{n: 1}) {
+		t.Errorf("s2 got %v", e)
+	}
+
+	if n := len(b.subs[reflect.TypeOf(testEvent{})]) + len(b.subs[reflect.TypeOf(otherEvent{})]); n != 1 {
+		t.Errorf("%d subscriptions left, want 1", n)
+	}
+}
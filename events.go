@@ -0,0 +1,59 @@
+package main
+
+import (
+	"time"
+
+	"github.com/nonoo/jampec/event"
+	"github.com/nonoo/jampec/mount"
+)
+
+// Connects the cameras, the front-ends and main.
+var bus = event.NewBus()
+
+// Asks main to stop the cameras and exit. Err is set if jampec exits because of an error.
+type exitRequestedEvent struct {
+	reason string
+	err    error
+}
+
+// Asks the camera with the given DevNum to toggle its active state, and the other cameras to become
+// inactive.
+type activateRequestedEvent struct {
+	devNum int
+}
+
+// Published by a camera when it becomes active or inactive.
+type camActivatedEvent struct {
+	cam    int
+	active bool
+}
+
+// Commands handled by all cameras.
+type showOriginalImageEvent struct {
+	show bool
+}
+type calibrateEvent struct{}
+type switchTrackerEvent struct{}
+type acquireEvent struct{}
+type recordEvent struct{}
+type snapshotEvent struct{}
+
+type trackStateChangedEvent struct {
+	cam  int
+	from trackState
+	to   trackState
+	time time.Time
+}
+
+// Published after a frame was processed and annotated, with the state of the camera.
+type frameAnnotatedEvent struct {
+	status camStatus
+}
+
+// Published on each mount position poll.
+type mountTelemetryEvent struct {
+	cam  int
+	pos  mount.Position
+	time time.Time
+	err  error
+}
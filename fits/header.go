@@ -0,0 +1,157 @@
+// Package fits writes images to FITS files with a single primary HDU. See
+// https://fits.gsfc.nasa.gov/fits_standard.html for the standard.
+package fits
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	cardSize  = 80
+	blockSize = 2880
+)
+
+// Card is a keyword record of the header.
+type Card struct {
+	Key string
+	// bool, int, int64, float64, string or time.Time (written as a UTC date string). Cards with NaN or
+	// infinite values are left out of the file.
+	Value   interface{}
+	Comment string
+}
+
+// Header holds the keywords written after the mandatory ones, in order.
+type Header struct {
+	cards []Card
+}
+
+// Set adds a keyword, or replaces its value and comment if it's already set. Keys are converted to upper
+// case and truncated to 8 characters.
+func (h *Header) Set(key string, value interface{}, comment string) {
+	key = normalizeKey(key)
+	for i := range h.cards {
+		if h.cards[i].Key == key {
+			h.cards[i].Value = value
+			h.cards[i].Comment = comment
+			return
+		}
+	}
+	h.cards = append(h.cards, Card{Key: key, Value: value, Comment: comment})
+}
+
+// Get returns the value of a keyword.
+func (h *Header) Get(key string) (interface{}, bool) {
+	key = normalizeKey(key)
+	for _, c := range h.cards {
+		if c.Key == key {
+			return c.Value, true
+		}
+	}
+	return nil, false
+}
+
+// AddComment adds a COMMENT card.
+func (h *Header) AddComment(text string) {
+	h.cards = append(h.cards, Card{Key: "COMMENT", Comment: text})
+}
+
+// Cards returns the keywords in the order they are written.
+func (h *Header) Cards() []Card {
+	return h.cards
+}
+
+func normalizeKey(key string) string {
+	key = strings.ToUpper(key)
+	if len(key) > 8 {
+		key = key[:8]
+	}
+	return key
+}
+
+// DateFormat is the format of date keywords like DATE-OBS.
+const DateFormat = "2006-01-02T15:04:05.000000"
+
+func (c Card) format() (string, error) {
+	for _, r := range c.Key {
+		if !(r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return "", fmt.Errorf("fits: invalid keyword %q", c.Key)
+		}
+	}
+
+	if c.Key == "COMMENT" || c.Key == "HISTORY" || c.Value == nil {
+		return pad(fmt.Sprintf("%-8s  %s", c.Key, ascii(c.Comment)), cardSize), nil
+	}
+
+	var v string
+	switch val := c.Value.(type) {
+	case bool:
+		v = fmt.Sprintf("%20s", "F")
+		if val {
+			v = fmt.Sprintf("%20s", "T")
+		}
+	case int:
+		v = fmt.Sprintf("%20d", val)
+	case int64:
+		v = fmt.Sprintf("%20d", val)
+	case float64:
+		if math.IsNaN(val) || math.IsInf(val, 0) {
+			return "", fmt.Errorf("fits: keyword %s has non-finite value %v", c.Key, val)
+		}
+		v = fmt.Sprintf("%20s", formatFloat(val))
+	case string:
+		v = quote(val)
+	case time.Time:
+		v = quote(val.UTC().Format(DateFormat))
+	default:
+		return "", fmt.Errorf("fits: unsupported value type %T of keyword %s", c.Value, c.Key)
+	}
+
+	s := fmt.Sprintf("%-8s= %s", c.Key, v)
+	if c.Comment != "" && len(s)+3 < cardSize {
+		s += " / " + ascii(c.Comment)
+	}
+	return pad(s, cardSize), nil
+}
+
+func formatFloat(v float64) string {
+	s := strings.ToUpper(strconv.FormatFloat(v, 'G', -1, 64))
+	if !strings.ContainsAny(s, ".E") {
+		s += ".0"
+	}
+	return s
+}
+
+// Strings are quoted, and padded to at least 8 characters.
+func quote(s string) string {
+	s = strings.ReplaceAll(ascii(s), "'", "''")
+	// 80 characters minus the key, the value indicator and the quotes.
+	if len(s) > 68 {
+		s = s[:68]
+		// Not splitting an escaped quote, which would leave an odd number of quotes at the end.
+		if n := len(s) - len(strings.TrimRight(s, "'")); n%2 == 1 {
+			s = s[:67]
+		}
+	}
+	return "'" + fmt.Sprintf("%-8s", s) + "'"
+}
+
+// Only printable ASCII characters are allowed in the header.
+func ascii(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 32 || r > 126 {
+			return '?'
+		}
+		return r
+	}, s)
+}
+
+func pad(s string, size int) string {
+	if len(s) > size {
+		return s[:size]
+	}
+	return s + strings.Repeat(" ", size-len(s))
+}
@@ -0,0 +1,89 @@
+package fits
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCardFormat(t *testing.T) {
+	long := strings.Repeat("a", 100)
+	tests := []struct {
+		name string
+		card Card
+		want string
+		err  bool
+	}{
+		{"true", Card{Key: "SIMPLE", Value: true, Comment: "conforms"},
+			"SIMPLE  =                    T / conforms", false},
+		{"false", Card{Key: "EXTEND", Value: false}, "EXTEND  =                    F", false},
+		{"int", Card{Key: "NAXIS1", Value: 640}, "NAXIS1  =                  640", false},
+		{"negative int64", Card{Key: "BITPIX", Value: int64(-32)}, "BITPIX  =                  -32", false},
+		{"float", Card{Key: "EXPTIME", Value: 0.25}, "EXPTIME =                 0.25", false},
+		{"integral float", Card{Key: "SITEELEV", Value: 100.0}, "SITEELEV=                100.0", false},
+		{"small float", Card{Key: "X", Value: 1.5e-20}, "X       =              1.5E-20", false},
+		{"large float", Card{Key: "X", Value: -2e21}, "X       =               -2E+21", false},
+		{"nan", Card{Key: "X", Value: math.NaN()}, "", true},
+		{"inf", Card{Key: "X", Value: math.Inf(1)}, "", true},
+		{"short string", Card{Key: "OBJECT", Value: "ISS"}, "OBJECT  = 'ISS     '", false},
+		{"quote", Card{Key: "OBSERVER", Value: "O'Brien"}, "OBSERVER= 'O''Brien'", false},
+		{"non-ascii", Card{Key: "OBSERVER", Value: "Nagy Péter"}, "OBSERVER= 'Nagy P?ter'", false},
+		{"date", Card{Key: "DATE-OBS", Value: time.Date(2021, 3, 1, 21, 30, 15, 123456789,
+			time.FixedZone("CET", 3600))}, "DATE-OBS= '2021-03-01T20:30:15.123456'", false},
+		{"truncated string", Card{Key: "LONG", Value: long},
+			"LONG    = '" + long[:68] + "'", false},
+		{"truncated at an escaped quote", Card{Key: "LONG", Value: long[:67] + "'"},
+			"LONG    = '" + long[:67] + "'", false},
+		{"truncated after escaped quotes", Card{Key: "LONG", Value: long[:65] + "''"},
+			"LONG    = '" + long[:65] + "'''", false},
+		{"truncated comment", Card{Key: "NAXIS", Value: 2, Comment: long},
+			("NAXIS   =                    2 / " + long)[:80], false},
+		{"comment card", Card{Key: "COMMENT", Comment: "some text"}, "COMMENT   some text", false},
+		{"lower case key", Card{Key: "naxis", Value: 2}, "", true},
+		{"key with space", Card{Key: "BAD KEY", Value: 2}, "", true},
+		{"key with dot", Card{Key: "A.B", Value: 2}, "", true},
+		{"unsupported type", Card{Key: "X", Value: float32(1)}, "", true},
+	}
+	for _, tt := range tests {
+		s, err := tt.card.format()
+		if (err != nil) != tt.err {
+			t.Errorf("%s: error %v", tt.name, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if len(s) != cardSize {
+			t.Errorf("%s: card length %d", tt.name, len(s))
+		}
+		if got := strings.TrimRight(s, " "); got != strings.TrimRight(tt.want, " ") {
+			t.Errorf("%s:\n%q\nwant\n%q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHeader(t *testing.T) {
+	var h Header
+	h.Set("exptime", 1.0, "exposure")
+	h.Set("instrument", "cam", "")
+	h.AddComment("text")
+	h.Set("EXPTIME", 2.0, "exposure time")
+
+	cards := h.Cards()
+	if len(cards) != 3 {
+		t.Fatalf("cards %+v", cards)
+	}
+	if c := cards[0]; c.Key != "EXPTIME" || c.Value != 2.0 || c.Comment != "exposure time" {
+		t.Errorf("replaced card %+v", c)
+	}
+	if cards[1].Key != "INSTRUME" || cards[2].Key != "COMMENT" {
+		t.Errorf("keys %s, %s", cards[1].Key, cards[2].Key)
+	}
+	if v, ok := h.Get("Instrument"); !ok || v != "cam" {
+		t.Errorf("get %v, %v", v, ok)
+	}
+	if _, ok := h.Get("OBJECT"); ok {
+		t.Error("get of a missing keyword")
+	}
+}
@@ -0,0 +1,180 @@
+package fits
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+// Image is the data of the primary HDU. Pixels are stored row by row with the planes of a pixel
+// interleaved, the first row is the top of the image.
+type Image struct {
+	Width  int
+	Height int
+	// 1 for mono and 3 for RGB images.
+	Planes int
+
+	// Only one of these is set, it selects BITPIX 8, 16 or -32.
+	Data8     []uint8
+	Data16    []uint16
+	DataFloat []float32
+}
+
+func (img Image) bitpix() int {
+	switch {
+	case img.Data16 != nil:
+		return 16
+	case img.DataFloat != nil:
+		return -32
+	}
+	return 8
+}
+
+func (img Image) validate() error {
+	if img.Width <= 0 || img.Height <= 0 || img.Planes <= 0 {
+		return fmt.Errorf("fits: invalid image size %dx%dx%d", img.Width, img.Height, img.Planes)
+	}
+	n := img.Width * img.Height * img.Planes
+	var l int
+	switch img.bitpix() {
+	case 8:
+		l = len(img.Data8)
+	case 16:
+		l = len(img.Data16)
+	case -32:
+		l = len(img.DataFloat)
+	}
+	if l != n {
+		return fmt.Errorf("fits: image has %d values instead of %d", l, n)
+	}
+	return nil
+}
+
+// WriteFile writes the image with the header to a new file.
+func WriteFile(filename string, img Image, h *Header) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	err = Write(f, img, h)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(filename)
+	}
+	return err
+}
+
+// Write writes the image as the primary HDU with the keywords of h, which can be nil.
+func Write(w io.Writer, img Image, h *Header) error {
+	if err := img.validate(); err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+
+	cards := []Card{
+		{Key: "SIMPLE", Value: true, Comment: "conforms to the FITS standard"},
+		{Key: "BITPIX", Value: img.bitpix()},
+		{Key: "NAXIS", Value: 2},
+		{Key: "NAXIS1", Value: img.Width},
+		{Key: "NAXIS2", Value: img.Height},
+	}
+	if img.Planes > 1 {
+		cards[2].Value = 3
+		cards = append(cards, Card{Key: "NAXIS3", Value: img.Planes})
+	}
+	if img.bitpix() == 16 {
+		// Values are stored as signed integers.
+		cards = append(cards, Card{Key: "BZERO", Value: 32768}, Card{Key: "BSCALE", Value: 1})
+	}
+	cards = append(cards, Card{Key: "ROWORDER", Value: "TOP-DOWN", Comment: "first row is the top"})
+	if h != nil {
+		for _, c := range h.cards {
+			if reservedKeys[c.Key] {
+				return fmt.Errorf("fits: keyword %s is set by the writer", c.Key)
+			}
+			// Non-finite values can't be written, the keyword is left out to still save the image.
+			if v, ok := c.Value.(float64); ok && (math.IsNaN(v) || math.IsInf(v, 0)) {
+				continue
+			}
+			cards = append(cards, c)
+		}
+	}
+	cards = append(cards, Card{Key: "END"})
+
+	var n int
+	for _, c := range cards {
+		var s string
+		if c.Key == "END" {
+			s = pad("END", cardSize)
+		} else {
+			var err error
+			if s, err = c.format(); err != nil {
+				return err
+			}
+		}
+		if _, err := bw.WriteString(s); err != nil {
+			return err
+		}
+		n += cardSize
+	}
+	if err := padBlock(bw, n, ' '); err != nil {
+		return err
+	}
+
+	n, err := writeData(bw, img)
+	if err != nil {
+		return err
+	}
+	if err := padBlock(bw, n, 0); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+var reservedKeys = map[string]bool{
+	"SIMPLE": true, "BITPIX": true, "NAXIS": true, "NAXIS1": true, "NAXIS2": true, "NAXIS3": true,
+	"BZERO": true, "BSCALE": true, "END": true, "EXTEND": true, "ROWORDER": true,
+}
+
+// The data is written plane by plane, big endian.
+func writeData(w *bufio.Writer, img Image) (int, error) {
+	var n int
+	b := make([]byte, 4)
+	pixels := img.Width * img.Height
+	for p := 0; p < img.Planes; p++ {
+		for i := 0; i < pixels; i++ {
+			j := i*img.Planes + p
+			var s []byte
+			switch img.bitpix() {
+			case 8:
+				s = b[:1]
+				s[0] = img.Data8[j]
+			case 16:
+				s = b[:2]
+				binary.BigEndian.PutUint16(s, uint16(int16(int32(img.Data16[j])-32768)))
+			case -32:
+				s = b[:4]
+				binary.BigEndian.PutUint32(s, math.Float32bits(img.DataFloat[j]))
+			}
+			if _, err := w.Write(s); err != nil {
+				return n, err
+			}
+			n += len(s)
+		}
+	}
+	return n, nil
+}
+
+func padBlock(w *bufio.Writer, n int, c byte) error {
+	if r := n % blockSize; r != 0 {
+		_, err := w.WriteString(strings.Repeat(string(c), blockSize-r))
+		return err
+	}
+	return nil
+}
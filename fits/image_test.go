@@ -0,0 +1,204 @@
+package fits
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Splits the written file to the header cards up to END, and the data after the header blocks.
+func parseFile(t *testing.T, b []byte) ([]string, []byte) {
+	t.Helper()
+	if len(b)%blockSize != 0 {
+		t.Fatalf("file size %d is not a multiple of %d", len(b), blockSize)
+	}
+	var cards []string
+	for i := 0; ; i += cardSize {
+		if i+cardSize > len(b) {
+			t.Fatal("no END card")
+		}
+		c := string(b[i : i+cardSize])
+		cards = append(cards, strings.TrimRight(c, " "))
+		if strings.TrimRight(c, " ") == "END" {
+			headerEnd := (i/blockSize + 1) * blockSize
+			if strings.Trim(string(b[i+cardSize:headerEnd]), " ") != "" {
+				t.Error("header is not padded with spaces")
+			}
+			return cards, b[headerEnd:]
+		}
+	}
+}
+
+// Returns the value of the card with the key, without the comment.
+func cardValue(cards []string, key string) (string, bool) {
+	for _, c := range cards {
+		if strings.HasPrefix(c, key+strings.Repeat(" ", 8-len(key))+"=") {
+			v := c[10:]
+			if i := strings.Index(v, " /"); i >= 0 {
+				v = v[:i]
+			}
+			return strings.TrimSpace(v), true
+		}
+	}
+	return "", false
+}
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		name  string
+		img   Image
+		cards map[string]string
+		// The data without padding.
+		data []byte
+	}{
+		{
+			name:  "8 bit mono",
+			img:   Image{Width: 3, Height: 2, Planes: 1, Data8: []uint8{1, 2, 3, 4, 5, 6}},
+			cards: map[string]string{"BITPIX": "8", "NAXIS": "2", "NAXIS1": "3", "NAXIS2": "2"},
+			data:  []byte{1, 2, 3, 4, 5, 6},
+		},
+		{
+			name:  "16 bit mono",
+			img:   Image{Width: 2, Height: 2, Planes: 1, Data16: []uint16{0, 1, 32768, 65535}},
+			cards: map[string]string{"BITPIX": "16", "NAXIS": "2", "BZERO": "32768", "BSCALE": "1"},
+			// Stored as signed integers with the offset of BZERO.
+			data: []byte{0x80, 0x00, 0x80, 0x01, 0x00, 0x00, 0x7f, 0xff},
+		},
+		{
+			name:  "float mono",
+			img:   Image{Width: 2, Height: 1, Planes: 1, DataFloat: []float32{1, -0.5}},
+			cards: map[string]string{"BITPIX": "-32", "NAXIS": "2"},
+			data:  []byte{0x3f, 0x80, 0x00, 0x00, 0xbf, 0x00, 0x00, 0x00},
+		},
+		{
+			// The interleaved planes are written one after the other.
+			name: "8 bit rgb",
+			img: Image{Width: 2, Height: 2, Planes: 3, Data8: []uint8{
+				1, 2, 3, 4, 5, 6,
+				7, 8, 9, 10, 11, 12,
+			}},
+			cards: map[string]string{"BITPIX": "8", "NAXIS": "3", "NAXIS1": "2", "NAXIS2": "2", "NAXIS3": "3"},
+			data:  []byte{1, 4, 7, 10, 2, 5, 8, 11, 3, 6, 9, 12},
+		},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := Write(&buf, tt.img, nil); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		cards, data := parseFile(t, buf.Bytes())
+		if cards[0] != "SIMPLE  =                    T / conforms to the FITS standard" ||
+			!strings.HasPrefix(cards[1], "BITPIX") || !strings.HasPrefix(cards[2], "NAXIS ") {
+			t.Errorf("%s: mandatory keywords out of order: %q", tt.name, cards[:3])
+		}
+		for k, want := range tt.cards {
+			if v, ok := cardValue(cards, k); v != want {
+				t.Errorf("%s: %s = %q, %v, want %q", tt.name, k, v, ok, want)
+			}
+		}
+		if _, ok := cardValue(cards, "NAXIS3"); ok != (tt.img.Planes > 1) {
+			t.Errorf("%s: NAXIS3 %v", tt.name, ok)
+		}
+		if _, ok := cardValue(cards, "BZERO"); ok != (tt.img.Data16 != nil) {
+			t.Errorf("%s: BZERO %v", tt.name, ok)
+		}
+		if !bytes.Equal(data[:len(tt.data)], tt.data) {
+			t.Errorf("%s: data % x, want % x", tt.name, data[:len(tt.data)], tt.data)
+		}
+		if len(data) != blockSize || bytes.Count(data[len(tt.data):], []byte{0}) != blockSize-len(tt.data) {
+			t.Errorf("%s: data is not padded with zeros to a block", tt.name)
+		}
+	}
+}
+
+func TestWriteHeader(t *testing.T) {
+	img := Image{Width: 1, Height: 1, Planes: 1, Data8: []uint8{0}}
+	h := &Header{}
+	h.Set("OBJECT", "ISS", "target")
+	h.Set("TRKSNR", math.NaN(), "centroid SNR")
+	h.Set("TRKFLUX", math.Inf(1), "centroid flux")
+	h.Set("EXPTIME", 0.01, "")
+	// Enough cards to need a second header block.
+	for i := 0; i < 40; i++ {
+		h.AddComment("comment")
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, img, h); err != nil {
+		t.Fatal(err)
+	}
+	cards, data := parseFile(t, buf.Bytes())
+	if len(data) != blockSize || buf.Len() != 3*blockSize {
+		t.Errorf("file size %d", buf.Len())
+	}
+	if v, _ := cardValue(cards, "OBJECT"); v != "'ISS     '" {
+		t.Errorf("OBJECT = %q", v)
+	}
+	if v, _ := cardValue(cards, "EXPTIME"); v != "0.01" {
+		t.Errorf("EXPTIME = %q", v)
+	}
+	// Non-finite values are left out.
+	for _, k := range []string{"TRKSNR", "TRKFLUX"} {
+		if _, ok := cardValue(cards, k); ok {
+			t.Errorf("%s is written", k)
+		}
+	}
+
+	for _, k := range []string{"SIMPLE", "BITPIX", "NAXIS", "NAXIS1", "NAXIS3", "BZERO", "BSCALE", "END",
+		"EXTEND", "ROWORDER"} {
+		h := &Header{}
+		h.Set(k, 1, "")
+		if err := Write(&bytes.Buffer{}, img, h); err == nil {
+			t.Errorf("reserved keyword %s accepted", k)
+		}
+	}
+	h = &Header{}
+	h.Set("BAD KEY", 1, "")
+	if err := Write(&bytes.Buffer{}, img, h); err == nil {
+		t.Error("invalid keyword accepted")
+	}
+}
+
+func TestInvalidImage(t *testing.T) {
+	tests := []Image{
+		{Width: 0, Height: 1, Planes: 1, Data8: []uint8{}},
+		{Width: 2, Height: 2, Planes: 1, Data8: []uint8{1, 2, 3}},
+		{Width: 2, Height: 2, Planes: 3, Data16: []uint16{1, 2, 3, 4}},
+		{Width: 1, Height: 1, Planes: 0},
+	}
+	for _, img := range tests {
+		if err := Write(&bytes.Buffer{}, img, nil); err == nil {
+			t.Errorf("image %+v accepted", img)
+		}
+	}
+
+	// The file is removed on error.
+	filename := filepath.Join(t.TempDir(), "invalid.fits")
+	if err := WriteFile(filename, tests[1], nil); err == nil {
+		t.Error("invalid image written")
+	}
+	if _, err := os.Stat(filename); !os.IsNotExist(err) {
+		t.Errorf("file left after error: %v", err)
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "test.fits")
+	img := Image{Width: 2, Height: 1, Planes: 1, Data16: []uint16{100, 200}}
+	if err := WriteFile(filename, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, data := parseFile(t, b)
+	if v := int16(binary.BigEndian.Uint16(data[2:])); int(v)+32768 != 200 {
+		t.Errorf("second value %d", int(v)+32768)
+	}
+}
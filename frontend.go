@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"strings"
+	"sync"
+
+	"gocv.io/x/gocv"
+)
+
+type controlCmdType int
+
+const (
+	controlCmdTypeExit              = controlCmdType(iota)
+	controlCmdTypeActivate          // camNr: DevNum of the camera to activate
+	controlCmdTypeShowOriginalImage // toggles
+	controlCmdTypeCalibrate
+	controlCmdTypeSwitchTracker
+	controlCmdTypeAcquire
+	controlCmdTypeRecord
+	controlCmdTypeSnapshot
+	controlCmdTypeSelectRect // rect: the target in image coordinates
+	controlCmdTypeCancelSelection
+	controlCmdTypeJog // azRate, elRate: in degrees per second, zero stops
+)
+
+// A command of the operator, sent by a front-end to its camera.
+type controlCmd struct {
+	cmdType controlCmdType
+	camNr   int
+	rect    image.Rectangle
+	azRate  float64
+	elRate  float64
+}
+
+// Returns the command of a key.
+func keyControlCmd(k int) (controlCmd, bool) {
+	if k >= '0' && k <= '9' {
+		return controlCmd{cmdType: controlCmdTypeActivate, camNr: k - '0'}, true
+	}
+	switch k {
+	case 27: // Esc
+		return controlCmd{cmdType: controlCmdTypeExit}, true
+	case 'o':
+		return controlCmd{cmdType: controlCmdTypeShowOriginalImage}, true
+	case 'c':
+		return controlCmd{cmdType: controlCmdTypeCalibrate}, true
+	case 't':
+		return controlCmd{cmdType: controlCmdTypeSwitchTracker}, true
+	case 'a':
+		return controlCmd{cmdType: controlCmdTypeAcquire}, true
+	case 'r':
+		return controlCmd{cmdType: controlCmdTypeRecord}, true
+	case 's':
+		return controlCmd{cmdType: controlCmdTypeSnapshot}, true
+	}
+	return controlCmd{}, false
+}
+
+// A frontend displays the annotated frames of a camera and passes the operator's commands to it. Its
+// methods are called from the camera's loop.
+type frontend interface {
+	// Show displays the annotated frame. The frontend has to copy img if it needs it after returning.
+	Show(img gocv.Mat)
+	// Poll returns the commands received since the last call without blocking.
+	Poll() []controlCmd
+	Close()
+}
+
+// Implemented by front-ends which also show the original frames.
+type rawFrameFrontend interface {
+	// WantsRaw returns true if ShowRaw has to be called with the next frame.
+	WantsRaw() bool
+	ShowRaw(img gocv.Mat)
+}
+
+// Displays the frames in a HighGUI window.
+type windowFrontend struct {
+	window *gocv.Window
+	// OpenCV does not indicate which window the key was pressed in so keypresses are checked only in one
+	// window.
+	keys bool
+
+	// Set by the mouse callback, which is called by HighGUI in the goroutine checking keypresses.
+	mutex     sync.Mutex
+	cmds      []controlCmd
+	selecting bool
+	selection image.Rectangle
+	imgSize   image.Point
+
+	selectionColor color.RGBA
+}
+
+func newWindowFrontend(config DevConfig, keys bool) *windowFrontend {
+	f := &windowFrontend{
+		window:         gocv.NewWindow(fmt.Sprint("jampec video", config.DevNum)),
+		keys:           keys,
+		selectionColor: color.RGBA{255, 0, 0, 0},
+	}
+	f.window.ResizeWindow(config.WindowWidth, config.WindowHeight)
+
+	// Implementation from https://github.com/hybridgroup/gocv/pull/603/commits/410d1a795b55b6bbca775b5e36401c65fb05ebc5
+	f.window.SetMouseCallback(f.onMouseClick)
+	return f
+}
+
+func (f *windowFrontend) onMouseClick(event gocv.MouseEventType, x, y int, flags gocv.MouseEventFlag) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	switch event {
+	case gocv.MouseEventLeftButtonDown:
+		f.selecting = true
+		f.selection = image.Rect(x, y, x, y)
+	case gocv.MouseEventLeftButtonUp:
+		if f.selecting {
+			f.selection.Max = image.Pt(x, y)
+			r := f.selection.Canon().Intersect(image.Rectangle{Max: f.imgSize})
+			if !r.Empty() {
+				f.cmds = append(f.cmds, controlCmd{cmdType: controlCmdTypeSelectRect, rect: r})
+			}
+		}
+		f.selecting = false
+	case gocv.MouseEventMove:
+		if f.selecting {
+			f.selection.Max = image.Pt(x, y)
+		}
+	case gocv.MouseEventRightButtonUp: // Cancel
+		if !f.selecting {
+			f.cmds = append(f.cmds, controlCmd{cmdType: controlCmdTypeCancelSelection})
+		}
+		f.selecting = false
+	}
+}
+
+func (f *windowFrontend) Show(img gocv.Mat) {
+	f.mutex.Lock()
+	size := img.Size()
+	f.imgSize = image.Pt(size[1], size[0])
+	selecting := f.selecting
+	selection := f.selection.Canon()
+	f.mutex.Unlock()
+
+	if selecting {
+		i := img.Clone()
+		defer i.Close()
+		gocv.Rectangle(&i, selection, f.selectionColor, 2)
+		f.window.IMShow(i)
+		return
+	}
+	f.window.IMShow(img)
+}
+
+func (f *windowFrontend) Poll() []controlCmd {
+	var cmds []controlCmd
+	if f.keys {
+		if cmd, ok := keyControlCmd(f.window.WaitKey(1)); ok {
+			cmds = append(cmds, cmd)
+		}
+	}
+
+	f.mutex.Lock()
+	cmds = append(f.cmds, cmds...)
+	f.cmds = nil
+	f.mutex.Unlock()
+
+	// Window closed?
+	if f.window.GetWindowProperty(gocv.WindowPropertyFullscreen) < 0 {
+		cmds = append(cmds, controlCmd{cmdType: controlCmdTypeExit})
+	}
+	return cmds
+}
+
+func (f *windowFrontend) Close() {
+	f.window.Close()
+}
+
+// Reads commands from the standard input in headless mode. Each character of a line is handled as a key
+// pressed in a window, and "q" exits.
+type consoleFrontend struct {
+	cmdChan chan controlCmd
+}
+
+func newConsoleFrontend() *consoleFrontend {
+	f := &consoleFrontend{cmdChan: make(chan controlCmd, 10)}
+	go f.readLoop()
+	return f
+}
+
+func (f *consoleFrontend) readLoop() {
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "q" {
+			f.cmdChan <- controlCmd{cmdType: controlCmdTypeExit}
+			continue
+		}
+		for _, k := range line {
+			if cmd, ok := keyControlCmd(int(k)); ok {
+				f.cmdChan <- cmd
+			} else {
+				log.Error("unknown command ", string(k))
+			}
+		}
+	}
+}
+
+func (f *consoleFrontend) Show(img gocv.Mat) {}
+
+func (f *consoleFrontend) Poll() []controlCmd {
+	var cmds []controlCmd
+	for {
+		select {
+		case cmd := <-f.cmdChan:
+			cmds = append(cmds, cmd)
+		default:
+			return cmds
+		}
+	}
+}
+
+func (f *consoleFrontend) Close() {}
@@ -0,0 +1,41 @@
+package guide
+
+import (
+	"errors"
+	"math"
+)
+
+// SolvePixelToAxis calculates the pixel to axis matrix from two calibration moves. daN is the axis
+// displacement (az, el) of the mount in degrees and dpN is the resulting displacement of a fixed target in
+// the image. The moves must not be parallel.
+//
+// Moving the mount by da shifts the target by P*da pixels, so to bring a target with pixel error e to the
+// boresight the mount needs to move by -P^-1*e. The returned matrix is -P^-1.
+func SolvePixelToAxis(da1, da2 [2]float64, dp1, dp2 Point) ([2][2]float64, error) {
+	// P = DP * DA^-1, so -P^-1 = -DA * DP^-1.
+	dpDet := dp1.X*dp2.Y - dp2.X*dp1.Y
+	daDet := da1[0]*da2[1] - da2[0]*da1[1]
+	if math.Abs(daDet) < 1e-9 {
+		return [2][2]float64{}, errors.New("calibration moves are parallel")
+	}
+	if math.Abs(dpDet) < 1e-6 {
+		return [2][2]float64{}, errors.New("target did not move enough in the image")
+	}
+
+	dpInv := [2][2]float64{
+		{dp2.Y / dpDet, -dp2.X / dpDet},
+		{-dp1.Y / dpDet, dp1.X / dpDet},
+	}
+	da := [2][2]float64{
+		{da1[0], da2[0]},
+		{da1[1], da2[1]},
+	}
+
+	var m [2][2]float64
+	for i := 0; i < 2; i++ {
+		for j := 0; j < 2; j++ {
+			m[i][j] = -(da[i][0]*dpInv[0][j] + da[i][1]*dpInv[1][j])
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,97 @@
+package guide
+
+type Point struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+type Config struct {
+	// Pixel where the target should be kept. If nil, the frame center is used.
+	Boresight *Point `json:"boresight"`
+	// Degrees per pixel, used when PixelToAxis is not set. Image y grows downwards, so it's negated for
+	// the elevation axis.
+	PixelScale float64 `json:"pixelScale"`
+	// Maps pixel offsets (x, y) to axis offsets (az, el) in degrees: az = m[0][0]*x + m[0][1]*y,
+	// el = m[1][0]*x + m[1][1]*y.
+	PixelToAxis *[2][2]float64 `json:"pixelToAxis"`
+
+	Az PIDConfig `json:"az"`
+	El PIDConfig `json:"el"`
+
+	FeedForward FeedForwardConfig `json:"feedForward"`
+}
+
+func (c *Config) SetDefaults() {
+	if c.PixelScale == 0 {
+		c.PixelScale = 0.002
+	}
+	for _, p := range []*PIDConfig{&c.Az, &c.El} {
+		if p.Kp == 0 && p.Ki == 0 && p.Kd == 0 {
+			p.Kp = 1
+		}
+		if p.OutputLimit == 0 {
+			p.OutputLimit = 5
+		}
+	}
+	c.FeedForward.SetDefaults()
+}
+
+// Transform returns the pixel to axis matrix in use.
+func (c *Config) Transform() [2][2]float64 {
+	if c.PixelToAxis != nil {
+		return *c.PixelToAxis
+	}
+	return [2][2]float64{{c.PixelScale, 0}, {0, -c.PixelScale}}
+}
+
+// Controller holds a PID controller for each mount axis.
+type Controller struct {
+	config Config
+	az     *PID
+	el     *PID
+}
+
+func NewController(config Config) *Controller {
+	return &Controller{
+		config: config,
+		az:     NewPID(config.Az),
+		el:     NewPID(config.El),
+	}
+}
+
+func (c *Controller) Reset() {
+	c.az.Reset()
+	c.el.Reset()
+}
+
+// Boresight returns the pixel the target should be kept at for the given frame size.
+func (c *Controller) Boresight(width, height int) Point {
+	if c.config.Boresight != nil {
+		return *c.config.Boresight
+	}
+	return Point{X: float64(width) / 2, Y: float64(height) / 2}
+}
+
+// AxisError converts a pixel error (target position minus boresight) to axis errors in degrees.
+func (c *Controller) AxisError(errX, errY float64) (az, el float64) {
+	m := c.config.Transform()
+	return m[0][0]*errX + m[0][1]*errY, m[1][0]*errX + m[1][1]*errY
+}
+
+// Update takes the pixel error (target position minus boresight) and the elapsed time in seconds, and
+// returns the axis rates in degrees per second which move the target towards the boresight.
+func (c *Controller) Update(errX, errY, dt float64) (azRate, elRate float64) {
+	azErr, elErr := c.AxisError(errX, errY)
+	return c.az.Update(azErr, dt), c.el.Update(elErr, dt)
+}
+
+// PixelOffset converts axis offsets in degrees to a pixel offset, the inverse of AxisError. Returns false if
+// the pixel to axis transform is singular.
+func (c *Controller) PixelOffset(az, el float64) (x, y float64, ok bool) {
+	m := c.config.Transform()
+	det := m[0][0]*m[1][1] - m[0][1]*m[1][0]
+	if det == 0 {
+		return 0, 0, false
+	}
+	return (m[1][1]*az - m[0][1]*el) / det, (-m[1][0]*az + m[0][0]*el) / det, true
+}
@@ -0,0 +1,113 @@
+package guide
+
+import (
+	"math"
+
+	"github.com/nonoo/jampec/mount"
+)
+
+type FeedForwardConfig struct {
+	// Gain of the mount position loop which follows the prediction, in 1/s.
+	PositionGain float64 `json:"positionGain"`
+	// Seconds it takes to fully blend in the optical correction after the tracker locks. The correction and
+	// its weight also decay back to zero with this time constant after the target is lost.
+	BlendTime float64 `json:"blendTime"`
+	// Commanded axis rates are clamped to this value in degrees per second.
+	MaxRate float64 `json:"maxRate"`
+}
+
+func (c *FeedForwardConfig) SetDefaults() {
+	if c.PositionGain == 0 {
+		c.PositionGain = 1
+	}
+	if c.BlendTime == 0 {
+		c.BlendTime = 2
+	}
+	if c.MaxRate == 0 {
+		c.MaxRate = 10
+	}
+}
+
+// Prediction is the predicted position (degrees) and rate (degrees per second) of the target.
+type Prediction struct {
+	Az     float64
+	El     float64
+	AzRate float64
+	ElRate float64
+}
+
+type Rates struct {
+	Az float64
+	El float64
+}
+
+// FeedForward makes the mount follow a predicted trajectory, with the optical correction applied on top as
+// an offset from the prediction.
+type FeedForward struct {
+	config FeedForwardConfig
+
+	offset mount.Position
+	weight float64
+}
+
+func NewFeedForward(config FeedForwardConfig) *FeedForward {
+	return &FeedForward{config: config}
+}
+
+func (f *FeedForward) Reset() {
+	f.offset = mount.Position{}
+	f.weight = 0
+}
+
+// Offset returns the current optical correction relative to the prediction in degrees.
+func (f *FeedForward) Offset() mount.Position {
+	return f.offset
+}
+
+// Weight returns how much of the optical correction is blended in, between 0 and 1.
+func (f *FeedForward) Weight() float64 {
+	return f.weight
+}
+
+// Update returns the axis rates for the mount. mountPos is the current mount position, it's ignored if
+// mountPosValid is false. corr is the output of the optical controller, it's only used if the tracker is
+// locked. dt is the time elapsed since the last update in seconds.
+func (f *FeedForward) Update(pred Prediction, mountPos mount.Position, mountPosValid bool, corr Rates, locked bool,
+	dt float64) Rates {
+
+	if locked {
+		if f.config.BlendTime > 0 {
+			f.weight = math.Min(1, f.weight+dt/f.config.BlendTime)
+		} else {
+			f.weight = 1
+		}
+		f.offset.Az += f.weight * corr.Az * dt
+		f.offset.El += f.weight * corr.El * dt
+	} else {
+		// The weight ramps down together with the offset, so a short dropout doesn't restart the blending
+		// from zero.
+		decay := 0.0
+		if f.config.BlendTime > 0 {
+			decay = math.Exp(-dt / f.config.BlendTime)
+		}
+		f.weight *= decay
+		f.offset.Az *= decay
+		f.offset.El *= decay
+	}
+
+	r := Rates{Az: pred.AzRate, El: pred.ElRate}
+	if mountPosValid {
+		r.Az += f.config.PositionGain * math.Remainder(pred.Az+f.offset.Az-mountPos.Az, 360)
+		r.El += f.config.PositionGain * (pred.El + f.offset.El - mountPos.El)
+	}
+	if locked {
+		r.Az += f.weight * corr.Az
+		r.El += f.weight * corr.El
+	}
+
+	if f.config.MaxRate > 0 {
+		r.Az = clamp(r.Az, f.config.MaxRate)
+		r.El = clamp(r.El, f.config.MaxRate)
+	}
+	return r
+}
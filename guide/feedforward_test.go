@@ -0,0 +1,100 @@
+package guide
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nonoo/jampec/mount"
+)
+
+func TestFeedForwardBlend(t *testing.T) {
+	f := NewFeedForward(FeedForwardConfig{PositionGain: 1, BlendTime: 2, MaxRate: 10})
+	pred := Prediction{Az: 100, El: 30, AzRate: 0.5}
+	corr := Rates{Az: 0.1, El: -0.2}
+
+	var r Rates
+	for i := 1; i <= 10; i++ {
+		r = f.Update(pred, mount.Position{}, false, corr, true, 0.1)
+		if want := float64(i) * 0.05; math.Abs(f.Weight()-want) > 1e-9 {
+			t.Fatalf("weight after %d frames %v, want %v", i, f.Weight(), want)
+		}
+	}
+	// Halfway blended in, the correction is applied with half weight on top of the predicted rate.
+	if math.Abs(r.Az-0.55) > 1e-9 || math.Abs(r.El+0.1) > 1e-9 {
+		t.Errorf("rates %+v", r)
+	}
+
+	for i := 0; i < 20; i++ {
+		f.Update(pred, mount.Position{}, false, corr, true, 0.1)
+	}
+	if f.Weight() != 1 {
+		t.Errorf("weight %v, want 1", f.Weight())
+	}
+	if o := f.Offset(); o.Az <= 0 || o.El >= 0 {
+		t.Errorf("offset %+v doesn't follow the correction", o)
+	}
+}
+
+func TestFeedForwardDecay(t *testing.T) {
+	f := NewFeedForward(FeedForwardConfig{PositionGain: 1, BlendTime: 2, MaxRate: 10})
+	f.weight = 1
+	f.offset = mount.Position{Az: 0.4, El: -0.2}
+
+	// One frame after the loss both the weight and the offset are still almost fully there.
+	f.Update(Prediction{}, mount.Position{}, false, Rates{}, false, 0.1)
+	if want := math.Exp(-0.05); math.Abs(f.Weight()-want) > 1e-9 {
+		t.Errorf("weight %v after the first lost frame, want %v", f.Weight(), want)
+	}
+
+	for i := 1; i < 20; i++ {
+		prev := f.Weight()
+		f.Update(Prediction{}, mount.Position{}, false, Rates{}, false, 0.1)
+		if f.Weight() >= prev {
+			t.Fatalf("weight %v didn't decrease from %v", f.Weight(), prev)
+		}
+	}
+	// After blendTime seconds everything decayed by 1/e.
+	decay := math.Exp(-1)
+	if math.Abs(f.Weight()-decay) > 1e-9 {
+		t.Errorf("weight %v, want %v", f.Weight(), decay)
+	}
+	if o := f.Offset(); math.Abs(o.Az-0.4*decay) > 1e-9 || math.Abs(o.El+0.2*decay) > 1e-9 {
+		t.Errorf("offset %+v, want %v, %v", o, 0.4*decay, -0.2*decay)
+	}
+
+	// Relocking continues the blending from the decayed weight.
+	f.Update(Prediction{}, mount.Position{}, false, Rates{}, true, 0.1)
+	if want := decay + 0.05; math.Abs(f.Weight()-want) > 1e-9 {
+		t.Errorf("weight %v after relock, want %v", f.Weight(), want)
+	}
+
+	f.Reset()
+	if f.Weight() != 0 || f.Offset() != (mount.Position{}) {
+		t.Errorf("not reset: weight %v offset %+v", f.Weight(), f.Offset())
+	}
+}
+
+func TestFeedForwardPosition(t *testing.T) {
+	f := NewFeedForward(FeedForwardConfig{PositionGain: 2, BlendTime: 2, MaxRate: 1})
+	f.offset = mount.Position{Az: 0.1}
+
+	tests := []struct {
+		pred Prediction
+		pos  mount.Position
+		want Rates
+	}{
+		{Prediction{Az: 10, El: 20, AzRate: 0.2}, mount.Position{Az: 10, El: 20}, Rates{Az: 0.4, El: 0}},
+		// The azimuth error takes the short way around.
+		{Prediction{Az: 0.1, El: 20}, mount.Position{Az: 359.9, El: 20.1}, Rates{Az: 0.6, El: -0.2}},
+		// Clamped to MaxRate.
+		{Prediction{Az: 10, El: 40}, mount.Position{Az: 20, El: 20}, Rates{Az: -1, El: 1}},
+	}
+	for _, tt := range tests {
+		// No decay with zero dt.
+		f.weight = 0
+		got := f.Update(tt.pred, tt.pos, true, Rates{}, false, 0)
+		if math.Abs(got.Az-tt.want.Az) > 1e-6 || math.Abs(got.El-tt.want.El) > 1e-6 {
+			t.Errorf("Update(%+v, %+v) = %+v, want %+v", tt.pred, tt.pos, got, tt.want)
+		}
+	}
+}
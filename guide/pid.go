@@ -0,0 +1,80 @@
+// Package guide turns the tracked target's pixel offset into mount axis rates.
+package guide
+
+import "math"
+
+type PIDConfig struct {
+	Kp float64 `json:"kp"`
+	Ki float64 `json:"ki"`
+	Kd float64 `json:"kd"`
+	// The integral term is clamped to this absolute value. Zero means no clamping.
+	IntegralLimit float64 `json:"integralLimit"`
+	// The output is clamped to this absolute value. Zero means no clamping.
+	OutputLimit float64 `json:"outputLimit"`
+	// Errors with an absolute value below this are treated as zero.
+	Deadband float64 `json:"deadband"`
+}
+
+type PID struct {
+	config PIDConfig
+
+	integral float64
+	prevErr  float64
+	hasPrev  bool
+}
+
+func NewPID(config PIDConfig) *PID {
+	return &PID{config: config}
+}
+
+func (p *PID) Reset() {
+	p.integral = 0
+	p.prevErr = 0
+	p.hasPrev = false
+}
+
+// Update feeds the current error and the time elapsed since the last update (in seconds) to the controller
+// and returns the new output.
+func (p *PID) Update(err, dt float64) float64 {
+	if math.Abs(err) < p.config.Deadband {
+		err = 0
+	}
+
+	var derivative float64
+	if p.hasPrev && dt > 0 {
+		derivative = (err - p.prevErr) / dt
+	}
+	p.prevErr = err
+	p.hasPrev = true
+
+	integral := p.integral
+	if dt > 0 {
+		integral += err * dt
+	}
+	if p.config.IntegralLimit > 0 {
+		integral = clamp(integral, p.config.IntegralLimit)
+	}
+
+	out := p.config.Kp*err + p.config.Ki*integral + p.config.Kd*derivative
+	if p.config.OutputLimit > 0 && math.Abs(out) > p.config.OutputLimit {
+		out = clamp(out, p.config.OutputLimit)
+		// Anti-windup: don't let the integral grow further while the output is saturated in the same
+		// direction as the error.
+		if err*integral > 0 && math.Abs(integral) > math.Abs(p.integral) {
+			integral = p.integral
+		}
+	}
+	p.integral = integral
+
+	return out
+}
+
+func clamp(v, limit float64) float64 {
+	if v > limit {
+		return limit
+	}
+	if v < -limit {
+		return -limit
+	}
+	return v
+}
@@ -0,0 +1,84 @@
+package guide
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPIDStepResponse(t *testing.T) {
+	tests := []struct {
+		name   string
+		config PIDConfig
+		// Outputs for a unit error step, updated every 0.1 seconds.
+		want []float64
+	}{
+		{"p", PIDConfig{Kp: 2}, []float64{2, 2, 2, 2}},
+		{"pi", PIDConfig{Kp: 1, Ki: 0.5}, []float64{1.05, 1.1, 1.15, 1.2}},
+		// The derivative kicks in on the second update, and the error doesn't change afterwards.
+		{"pd", PIDConfig{Kp: 1, Kd: 1}, []float64{1, 1, 1, 1}},
+		{"integral limit", PIDConfig{Ki: 1, IntegralLimit: 0.25}, []float64{0.1, 0.2, 0.25, 0.25}},
+		{"output limit", PIDConfig{Kp: 3, OutputLimit: 2}, []float64{2, 2, 2, 2}},
+		{"deadband", PIDConfig{Kp: 1, Deadband: 1.5}, []float64{0, 0, 0, 0}},
+	}
+	for _, tt := range tests {
+		p := NewPID(tt.config)
+		for i, want := range tt.want {
+			if got := p.Update(1, 0.1); math.Abs(got-want) > 1e-9 {
+				t.Errorf("%s: output %d = %v, want %v", tt.name, i, got, want)
+			}
+		}
+	}
+}
+
+func TestPIDDerivative(t *testing.T) {
+	p := NewPID(PIDConfig{Kd: 1})
+	if got := p.Update(1, 0.1); got != 0 {
+		t.Errorf("first output %v, want 0", got)
+	}
+	if got := p.Update(0.5, 0.1); math.Abs(got+5) > 1e-9 {
+		t.Errorf("output %v, want -5", got)
+	}
+	p.Reset()
+	if got := p.Update(3, 0.1); got != 0 {
+		t.Errorf("output after reset %v, want 0", got)
+	}
+}
+
+// Drives an integrating plant (the rate output moves the position) to a setpoint.
+func TestPIDClosedLoop(t *testing.T) {
+	p := NewPID(PIDConfig{Kp: 2, Ki: 0.5, OutputLimit: 5})
+	const dt = 0.05
+	pos := 0.0
+	for i := 0; i < 400; i++ {
+		pos += p.Update(10-pos, dt) * dt
+	}
+	if math.Abs(pos-10) > 0.01 {
+		t.Errorf("position %v, want 10", pos)
+	}
+}
+
+func TestPIDAntiWindup(t *testing.T) {
+	p := NewPID(PIDConfig{Kp: 1, Ki: 1, OutputLimit: 1})
+	// A large error saturates the output for a long time.
+	for i := 0; i < 100; i++ {
+		if got := p.Update(10, 0.1); got != 1 {
+			t.Fatalf("saturated output %v, want 1", got)
+		}
+	}
+	if p.integral != 0 {
+		t.Errorf("integral grew to %v while saturated", p.integral)
+	}
+
+	// Without windup the output follows the error sign right away.
+	if got := p.Update(-0.5, 0.1); got >= 0 {
+		t.Errorf("output %v after the error changed sign, want negative", got)
+	}
+
+	// The integral can still shrink while saturated.
+	p = NewPID(PIDConfig{Kp: 1, Ki: 1, OutputLimit: 1})
+	p.integral = 2
+	p.Update(-10, 0.1)
+	if math.Abs(p.integral-1) > 1e-9 {
+		t.Errorf("integral %v, want 1", p.integral)
+	}
+}
@@ -0,0 +1,456 @@
+// Package indi implements a client for the INDI XML protocol, as spoken by indiserver.
+package indi
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const protocolVersion = "1.7"
+
+const DefaultServer = "localhost:7624"
+
+type Message struct {
+	Device    string
+	Timestamp time.Time
+	Message   string
+}
+
+type Client struct {
+	conn    net.Conn
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	devices map[string]map[string]*Property
+	// Closed and replaced on every property tree change to wake up waiters.
+	changed chan struct{}
+
+	messages chan Message
+
+	done chan struct{}
+	err  error
+}
+
+type xmlElement struct {
+	XMLName xml.Name
+	Name    string `xml:"name,attr"`
+	Label   string `xml:"label,attr"`
+	Format  string `xml:"format,attr"`
+	Min     string `xml:"min,attr"`
+	Max     string `xml:"max,attr"`
+	Step    string `xml:"step,attr"`
+	Value   string `xml:",chardata"`
+}
+
+type xmlVector struct {
+	XMLName   xml.Name
+	Device    string       `xml:"device,attr"`
+	Name      string       `xml:"name,attr"`
+	Label     string       `xml:"label,attr"`
+	Group     string       `xml:"group,attr"`
+	State     string       `xml:"state,attr"`
+	Perm      string       `xml:"perm,attr"`
+	Rule      string       `xml:"rule,attr"`
+	Timeout   string       `xml:"timeout,attr"`
+	Timestamp string       `xml:"timestamp,attr"`
+	Message   string       `xml:"message,attr"`
+	Elements  []xmlElement `xml:",any"`
+}
+
+// Dial connects to the indiserver at addr. The caller should call GetProperties afterwards to populate the
+// property tree.
+func Dial(addr string) (*Client, error) {
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn), nil
+}
+
+// NewClient starts a client on an already established connection.
+func NewClient(conn net.Conn) *Client {
+	c := &Client{
+		conn:     conn,
+		devices:  make(map[string]map[string]*Property),
+		changed:  make(chan struct{}),
+		messages: make(chan Message, 64),
+		done:     make(chan struct{}),
+	}
+	go c.readLoop()
+	return c
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// Done is closed when the connection to the server is lost.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns the error which ended the connection, if any.
+func (c *Client) Err() error {
+	select {
+	case <-c.done:
+		return c.err
+	default:
+		return nil
+	}
+}
+
+// Messages returns the channel of messages sent by the server. Messages are dropped if the channel is not
+// read.
+func (c *Client) Messages() <-chan Message {
+	return c.messages
+}
+
+// GetProperties asks the server to define properties. Empty device and name request everything.
+func (c *Client) GetProperties(device, name string) error {
+	var sb strings.Builder
+	sb.WriteString(`<getProperties version="` + protocolVersion + `"`)
+	writeAttr(&sb, "device", device)
+	writeAttr(&sb, "name", name)
+	sb.WriteString("/>\n")
+	return c.write(sb.String())
+}
+
+func (c *Client) SetNumber(device, name string, values map[string]float64) error {
+	elements := make(map[string]string, len(values))
+	for k, v := range values {
+		elements[k] = strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return c.sendNewVector("Number", device, name, elements)
+}
+
+func (c *Client) SetSwitch(device, name string, values map[string]bool) error {
+	elements := make(map[string]string, len(values))
+	for k, v := range values {
+		if v {
+			elements[k] = "On"
+		} else {
+			elements[k] = "Off"
+		}
+	}
+	return c.sendNewVector("Switch", device, name, elements)
+}
+
+func (c *Client) SetText(device, name string, values map[string]string) error {
+	return c.sendNewVector("Text", device, name, values)
+}
+
+// Devices returns the names of the known devices, sorted.
+func (c *Client) Devices() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var res []string
+	for d := range c.devices {
+		res = append(res, d)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// Properties returns a copy of all properties of the given device, sorted by name.
+func (c *Client) Properties(device string) []Property {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var res []Property
+	for _, p := range c.devices[device] {
+		res = append(res, p.clone())
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+// Property returns a copy of the given property.
+func (c *Client) Property(device, name string) (Property, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	p, ok := c.devices[device][name]
+	if !ok {
+		return Property{}, false
+	}
+	return p.clone(), true
+}
+
+func (c *Client) Number(device, name, element string) (float64, error) {
+	e, err := c.element(device, name, element)
+	if err != nil {
+		return 0, err
+	}
+	return e.Number()
+}
+
+func (c *Client) Switch(device, name, element string) (bool, error) {
+	e, err := c.element(device, name, element)
+	if err != nil {
+		return false, err
+	}
+	return e.Switch(), nil
+}
+
+func (c *Client) Text(device, name, element string) (string, error) {
+	e, err := c.element(device, name, element)
+	if err != nil {
+		return "", err
+	}
+	return e.Value, nil
+}
+
+// WaitFor blocks until the given property exists and cond returns true for it, or the timeout expires.
+// A nil cond only waits for the property to be defined.
+func (c *Client) WaitFor(device, name string, cond func(Property) bool, timeout time.Duration) (Property, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		c.mu.Lock()
+		p, ok := c.devices[device][name]
+		var pc Property
+		if ok {
+			pc = p.clone()
+		}
+		changed := c.changed
+		c.mu.Unlock()
+
+		if ok && (cond == nil || cond(pc)) {
+			return pc, nil
+		}
+
+		select {
+		case <-changed:
+		case <-c.done:
+			return Property{}, fmt.Errorf("indi: connection closed while waiting for %s.%s", device, name)
+		case <-timer.C:
+			return Property{}, fmt.Errorf("indi: timeout waiting for %s.%s", device, name)
+		}
+	}
+}
+
+func (c *Client) element(device, name, element string) (Element, error) {
+	p, ok := c.Property(device, name)
+	if !ok {
+		return Element{}, fmt.Errorf("indi: unknown property %s.%s", device, name)
+	}
+	e, ok := p.Element(element)
+	if !ok {
+		return Element{}, fmt.Errorf("indi: unknown element %s.%s.%s", device, name, element)
+	}
+	return e, nil
+}
+
+func (c *Client) sendNewVector(kind, device, name string, elements map[string]string) error {
+	// Sorting keeps the output deterministic.
+	names := make([]string, 0, len(elements))
+	for k := range elements {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	sb.WriteString("<new" + kind + "Vector")
+	writeAttr(&sb, "device", device)
+	writeAttr(&sb, "name", name)
+	sb.WriteString(">\n")
+	for _, n := range names {
+		sb.WriteString("  <one" + kind)
+		writeAttr(&sb, "name", n)
+		sb.WriteString(">")
+		_ = xml.EscapeText(&sb, []byte(elements[n]))
+		sb.WriteString("</one" + kind + ">\n")
+	}
+	sb.WriteString("</new" + kind + "Vector>\n")
+	return c.write(sb.String())
+}
+
+func (c *Client) write(s string) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	_, err := io.WriteString(c.conn, s)
+	return err
+}
+
+func writeAttr(sb *strings.Builder, name, value string) {
+	if value == "" {
+		return
+	}
+	sb.WriteString(" " + name + `="`)
+	_ = xml.EscapeText(sb, []byte(value))
+	sb.WriteString(`"`)
+}
+
+func (c *Client) readLoop() {
+	d := xml.NewDecoder(c.conn)
+	// Drivers don't always send well formed XML.
+	d.Strict = false
+
+	var err error
+	for {
+		var tok xml.Token
+		tok, err = d.Token()
+		if err != nil {
+			break
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		var v xmlVector
+		if err = d.DecodeElement(&v, &start); err != nil {
+			break
+		}
+		c.handle(&v)
+	}
+
+	if errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF
+	}
+	c.err = err
+	close(c.done)
+}
+
+func (c *Client) handle(v *xmlVector) {
+	tag := v.XMLName.Local
+	switch {
+	case tag == "message":
+		c.sendMessage(v.Device, v.Timestamp, v.Message)
+		return
+	case tag == "delProperty":
+		c.mu.Lock()
+		if v.Name == "" {
+			delete(c.devices, v.Device)
+		} else {
+			delete(c.devices[v.Device], v.Name)
+		}
+		c.notifyLocked()
+		c.mu.Unlock()
+	case strings.HasPrefix(tag, "def") && strings.HasSuffix(tag, "Vector"):
+		t, ok := parsePropertyType(strings.TrimSuffix(strings.TrimPrefix(tag, "def"), "Vector"))
+		if !ok {
+			return
+		}
+		p := &Property{
+			Device: v.Device,
+			Name:   v.Name,
+			Label:  v.Label,
+			Group:  v.Group,
+			Type:   t,
+			Perm:   v.Perm,
+			Rule:   v.Rule,
+		}
+		c.updateProperty(p, v)
+
+		c.mu.Lock()
+		if c.devices[v.Device] == nil {
+			c.devices[v.Device] = make(map[string]*Property)
+		}
+		c.devices[v.Device][v.Name] = p
+		c.notifyLocked()
+		c.mu.Unlock()
+	case strings.HasPrefix(tag, "set") && strings.HasSuffix(tag, "Vector"):
+		c.mu.Lock()
+		p, ok := c.devices[v.Device][v.Name]
+		if ok {
+			c.updateProperty(p, v)
+			c.notifyLocked()
+		}
+		c.mu.Unlock()
+	default:
+		return
+	}
+
+	if v.Message != "" {
+		c.sendMessage(v.Device, v.Timestamp, v.Message)
+	}
+}
+
+// updateProperty applies the attributes and elements of a def or set vector to p. Elements of a set vector
+// which were not defined before are ignored.
+func (c *Client) updateProperty(p *Property, v *xmlVector) {
+	isDef := strings.HasPrefix(v.XMLName.Local, "def")
+
+	if v.State != "" {
+		p.State = PropertyState(v.State)
+	}
+	if v.Timeout != "" {
+		p.Timeout, _ = strconv.ParseFloat(v.Timeout, 64)
+	}
+	if v.Timestamp != "" {
+		p.Timestamp = parseTimestamp(v.Timestamp)
+	}
+	p.Message = v.Message
+
+	for _, xe := range v.Elements {
+		if isDef {
+			e := Element{
+				Name:   xe.Name,
+				Label:  xe.Label,
+				Value:  strings.TrimSpace(xe.Value),
+				Format: xe.Format,
+			}
+			e.Min, _ = ParseNumber(xe.Min)
+			e.Max, _ = ParseNumber(xe.Max)
+			e.Step, _ = ParseNumber(xe.Step)
+			p.Elements = append(p.Elements, e)
+			continue
+		}
+
+		for i := range p.Elements {
+			if p.Elements[i].Name != xe.Name {
+				continue
+			}
+			p.Elements[i].Value = strings.TrimSpace(xe.Value)
+			// Number limits may be changed by set vectors.
+			if xe.Min != "" {
+				p.Elements[i].Min, _ = ParseNumber(xe.Min)
+			}
+			if xe.Max != "" {
+				p.Elements[i].Max, _ = ParseNumber(xe.Max)
+			}
+			break
+		}
+	}
+}
+
+func (c *Client) notifyLocked() {
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+func (c *Client) sendMessage(device, timestamp, msg string) {
+	if msg == "" {
+		return
+	}
+	select {
+	case c.messages <- Message{Device: device, Timestamp: parseTimestamp(timestamp), Message: msg}:
+	default:
+	}
+}
+
+func parsePropertyType(s string) (PropertyType, bool) {
+	switch s {
+	case "Number":
+		return NumberProperty, true
+	case "Switch":
+		return SwitchProperty, true
+	case "Text":
+		return TextProperty, true
+	case "Light":
+		return LightProperty, true
+	}
+	return 0, false
+}
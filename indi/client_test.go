@@ -0,0 +1,285 @@
+package indi
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A scripted indiserver on a local TCP port.
+type fakeServer struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func newFakeServer(t *testing.T) (*fakeServer, *Client) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	c, err := Dial(l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverConn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		c.Close()
+		serverConn.Close()
+	})
+	return &fakeServer{conn: serverConn, reader: bufio.NewReader(serverConn)}, c
+}
+
+// Called from goroutines, so it can't stop the test.
+func (s *fakeServer) send(t *testing.T, xml string) {
+	if _, err := io.WriteString(s.conn, xml); err != nil {
+		t.Error(err)
+	}
+}
+
+// Reads a message of the client up to the given closing tag.
+func (s *fakeServer) receive(t *testing.T, closing string) string {
+	t.Helper()
+	s.conn.SetReadDeadline(time.Now().Add(time.Second))
+	var sb strings.Builder
+	for !strings.Contains(sb.String(), closing) {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		sb.WriteString(line)
+	}
+	return sb.String()
+}
+
+const defStream = `<defNumberVector device="Telescope Simulator" name="HORIZONTAL_COORD" label="Alt/Az" group="Main" state="Idle" perm="rw" timeout="60" timestamp="2021-03-01T10:00:00">
+    <defNumber name="AZ" label="Az" format="%010.6m" min="0" max="360" step="0">
+        123:30:00
+    </defNumber>
+    <defNumber name="ALT" label="Alt" format="%010.6m" min="-90" max="90" step="0">
+        45.25
+    </defNumber>
+</defNumberVector>
+<defSwitchVector device="Telescope Simulator" name="TELESCOPE_ABORT_MOTION" label="Abort" group="Main" state="Idle" perm="rw" rule="AtMostOne" timeout="0">
+    <defSwitch name="ABORT" label="Abort">
+Off
+    </defSwitch>
+</defSwitchVector>
+<defTextVector device="Telescope Simulator" name="DRIVER_INFO" label="Driver" group="General" state="Idle" perm="ro">
+    <defText name="DRIVER_NAME" label="Name">Telescope Simulator</defText>
+</defTextVector>
+<defLightVector device="Telescope Simulator" name="STATUS" label="Status" group="Main" state="Idle">
+    <defLight name="PARKED" label="Parked">Ok</defLight>
+</defLightVector>
+`
+
+func TestDefProperties(t *testing.T) {
+	s, c := newFakeServer(t)
+	go s.send(t, defStream)
+
+	if _, err := c.WaitFor("Telescope Simulator", "STATUS", nil, time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	p, ok := c.Property("Telescope Simulator", "HORIZONTAL_COORD")
+	if !ok {
+		t.Fatal("HORIZONTAL_COORD is not defined")
+	}
+	if p.Type != NumberProperty || p.State != StateIdle || p.Perm != "rw" || p.Timeout != 60 ||
+		p.Label != "Alt/Az" || p.Group != "Main" {
+		t.Errorf("wrong attributes %+v", p)
+	}
+	if want := time.Date(2021, 3, 1, 10, 0, 0, 0, time.UTC); !p.Timestamp.Equal(want) {
+		t.Errorf("timestamp %v, want %v", p.Timestamp, want)
+	}
+	az, ok := p.Element("AZ")
+	if !ok {
+		t.Fatal("no AZ element")
+	}
+	if az.Min != 0 || az.Max != 360 || az.Format != "%010.6m" {
+		t.Errorf("wrong AZ element %+v", az)
+	}
+
+	tests := []struct {
+		name    string
+		element string
+		want    float64
+	}{
+		{"HORIZONTAL_COORD", "AZ", 123.5},
+		{"HORIZONTAL_COORD", "ALT", 45.25},
+	}
+	for _, tt := range tests {
+		v, err := c.Number("Telescope Simulator", tt.name, tt.element)
+		if err != nil || v != tt.want {
+			t.Errorf("%s.%s = %v, %v, want %v", tt.name, tt.element, v, err, tt.want)
+		}
+	}
+
+	if on, err := c.Switch("Telescope Simulator", "TELESCOPE_ABORT_MOTION", "ABORT"); err != nil || on {
+		t.Errorf("ABORT = %v, %v", on, err)
+	}
+	if v, err := c.Text("Telescope Simulator", "DRIVER_INFO", "DRIVER_NAME"); err != nil ||
+		v != "Telescope Simulator" {
+		t.Errorf("DRIVER_NAME = %q, %v", v, err)
+	}
+	light, _ := c.Property("Telescope Simulator", "STATUS")
+	if e, _ := light.Element("PARKED"); e.Light() != StateOk {
+		t.Errorf("PARKED = %v", e.Light())
+	}
+	if d := c.Devices(); len(d) != 1 || d[0] != "Telescope Simulator" {
+		t.Errorf("devices %v", d)
+	}
+	if _, err := c.Number("Telescope Simulator", "HORIZONTAL_COORD", "NONE"); err == nil {
+		t.Error("no error for unknown element")
+	}
+}
+
+func TestSetVector(t *testing.T) {
+	s, c := newFakeServer(t)
+	go func() {
+		s.send(t, defStream)
+		s.send(t, `<setNumberVector device="Telescope Simulator" name="HORIZONTAL_COORD" state="Busy" message="slewing">
+    <oneNumber name="AZ" min="-180">200</oneNumber>
+    <oneNumber name="UNKNOWN">1</oneNumber>
+</setNumberVector>
+<delProperty device="Telescope Simulator" name="STATUS"/>
+`)
+	}()
+
+	p, err := c.WaitFor("Telescope Simulator", "HORIZONTAL_COORD", func(p Property) bool {
+		return p.State == StateBusy
+	}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	az, _ := p.Element("AZ")
+	if v, _ := az.Number(); v != 200 || az.Min != -180 || az.Max != 360 {
+		t.Errorf("AZ after set: %+v", az)
+	}
+	if _, ok := p.Element("UNKNOWN"); ok {
+		t.Error("undefined element was added by a set vector")
+	}
+	if alt, _ := p.Element("ALT"); alt.Value != "45.25" {
+		t.Errorf("ALT changed to %q", alt.Value)
+	}
+
+	select {
+	case m := <-c.Messages():
+		if m.Device != "Telescope Simulator" || m.Message != "slewing" {
+			t.Errorf("message %+v", m)
+		}
+	case <-time.After(time.Second):
+		t.Error("no message")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, ok := c.Property("Telescope Simulator", "STATUS"); !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("STATUS was not deleted")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestNewVectors(t *testing.T) {
+	s, c := newFakeServer(t)
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- c.SetNumber("Telescope Simulator", "HORIZONTAL_COORD", map[string]float64{"AZ": 10.5, "ALT": 45})
+	}()
+	got := s.receive(t, "</newNumberVector>")
+	if err := <-errChan; err != nil {
+		t.Fatal(err)
+	}
+	want := `<newNumberVector device="Telescope Simulator" name="HORIZONTAL_COORD">
+  <oneNumber name="ALT">45</oneNumber>
+  <oneNumber name="AZ">10.5</oneNumber>
+</newNumberVector>
+`
+	if got != want {
+		t.Errorf("newNumberVector:\n%s\nwant:\n%s", got, want)
+	}
+
+	go func() {
+		errChan <- c.SetSwitch("Telescope Simulator", "ON_COORD_SET", map[string]bool{"TRACK": true, "SLEW": false})
+	}()
+	got = s.receive(t, "</newSwitchVector>")
+	if err := <-errChan; err != nil {
+		t.Fatal(err)
+	}
+	want = `<newSwitchVector device="Telescope Simulator" name="ON_COORD_SET">
+  <oneSwitch name="SLEW">Off</oneSwitch>
+  <oneSwitch name="TRACK">On</oneSwitch>
+</newSwitchVector>
+`
+	if got != want {
+		t.Errorf("newSwitchVector:\n%s\nwant:\n%s", got, want)
+	}
+
+	go func() {
+		errChan <- c.GetProperties("Telescope Simulator", "")
+	}()
+	got = s.receive(t, "/>")
+	if err := <-errChan; err != nil {
+		t.Fatal(err)
+	}
+	if want := `<getProperties version="1.7" device="Telescope Simulator"/>` + "\n"; got != want {
+		t.Errorf("getProperties %q, want %q", got, want)
+	}
+}
+
+func TestDisconnect(t *testing.T) {
+	s, c := newFakeServer(t)
+	s.conn.Close()
+
+	select {
+	case <-c.Done():
+	case <-time.After(time.Second):
+		t.Fatal("Done is not closed")
+	}
+	if c.Err() == nil {
+		t.Error("no error after disconnect")
+	}
+	if _, err := c.WaitFor("Telescope Simulator", "HORIZONTAL_COORD", nil, time.Second); err == nil {
+		t.Error("WaitFor succeeded on a closed connection")
+	}
+}
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+		err  bool
+	}{
+		{"12.5", 12.5, false},
+		{" -3 ", -3, false},
+		{"12:30", 12.5, false},
+		{"-12:30:36", -12.51, false},
+		{"12 30 36", 12.51, false},
+		{"", 0, true},
+		{"abc", 0, true},
+		{"1:2:3:4", 0, true},
+	}
+	for _, tt := range tests {
+		v, err := ParseNumber(tt.in)
+		if (err != nil) != tt.err || (err == nil && !almostEqual(v, tt.want)) {
+			t.Errorf("ParseNumber(%q) = %v, %v, want %v", tt.in, v, err, tt.want)
+		}
+	}
+}
+
+func almostEqual(a, b float64) bool {
+	d := a - b
+	return d < 1e-9 && d > -1e-9
+}
@@ -0,0 +1,135 @@
+package indi
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type PropertyType int
+
+const (
+	NumberProperty = PropertyType(iota)
+	SwitchProperty
+	TextProperty
+	LightProperty
+)
+
+func (t PropertyType) String() string {
+	switch t {
+	case NumberProperty:
+		return "Number"
+	case SwitchProperty:
+		return "Switch"
+	case TextProperty:
+		return "Text"
+	case LightProperty:
+		return "Light"
+	}
+	return "Unknown"
+}
+
+type PropertyState string
+
+const (
+	StateIdle  = PropertyState("Idle")
+	StateOk    = PropertyState("Ok")
+	StateBusy  = PropertyState("Busy")
+	StateAlert = PropertyState("Alert")
+)
+
+// Element is a single member of a property vector. Value holds the raw text sent by the server, use the
+// typed getters to interpret it.
+type Element struct {
+	Name  string
+	Label string
+	Value string
+
+	// Only used by number elements.
+	Format string
+	Min    float64
+	Max    float64
+	Step   float64
+}
+
+func (e Element) Number() (float64, error) {
+	return ParseNumber(e.Value)
+}
+
+func (e Element) Switch() bool {
+	return e.Value == "On"
+}
+
+func (e Element) Light() PropertyState {
+	return PropertyState(e.Value)
+}
+
+type Property struct {
+	Device    string
+	Name      string
+	Label     string
+	Group     string
+	Type      PropertyType
+	State     PropertyState
+	Perm      string
+	Rule      string
+	Timeout   float64
+	Timestamp time.Time
+	Message   string
+	Elements  []Element
+}
+
+func (p Property) Element(name string) (Element, bool) {
+	for i := range p.Elements {
+		if p.Elements[i].Name == name {
+			return p.Elements[i], true
+		}
+	}
+	return Element{}, false
+}
+
+func (p Property) clone() Property {
+	p.Elements = append([]Element(nil), p.Elements...)
+	return p
+}
+
+// ParseNumber parses an INDI number value. Besides plain floats INDI allows sexagesimal values like
+// "-12:30:15.5" or "12 30".
+func ParseNumber(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, errors.New("empty number")
+	}
+	if v, err := strconv.ParseFloat(s, 64); err == nil {
+		return v, nil
+	}
+
+	fields := strings.FieldsFunc(s, func(r rune) bool { return r == ':' || r == ' ' || r == ';' })
+	if len(fields) == 0 || len(fields) > 3 {
+		return 0, errors.New("invalid number " + s)
+	}
+	neg := strings.HasPrefix(fields[0], "-")
+	var v float64
+	for i, f := range fields {
+		n, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return 0, errors.New("invalid number " + s)
+		}
+		v += math.Abs(n) / math.Pow(60, float64(i))
+	}
+	if neg {
+		v = -v
+	}
+	return v, nil
+}
+
+func parseTimestamp(s string) time.Time {
+	for _, layout := range []string{"2006-01-02T15:04:05.999999999", "2006-01-02T15:04:05Z07:00"} {
+		if t, err := time.Parse(layout, strings.TrimSpace(s)); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
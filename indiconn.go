@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/nonoo/jampec/indi"
+)
+
+// Cameras using the same indiserver share one connection. Lost connections are removed, so the next lookup
+// connects again. The INDI mounts look up the client again when their connection is lost.
+var indiClients struct {
+	mu      sync.Mutex
+	clients map[string]*indi.Client
+}
+
+func getIndiClient(server string) (*indi.Client, error) {
+	indiClients.mu.Lock()
+	defer indiClients.mu.Unlock()
+
+	if c, ok := indiClients.clients[server]; ok {
+		select {
+		case <-c.Done():
+			delete(indiClients.clients, server)
+		default:
+			return c, nil
+		}
+	}
+
+	c, err := indi.Dial(server)
+	if err != nil {
+		return nil, fmt.Errorf("can't connect to indiserver %s: %w", server, err)
+	}
+	if indiClients.clients == nil {
+		indiClients.clients = make(map[string]*indi.Client)
+	}
+	indiClients.clients[server] = c
+
+	go func() {
+		for {
+			select {
+			case msg := <-c.Messages():
+				log.Print(msg.Device, ": ", msg.Message)
+			case <-c.Done():
+				log.Error("indiserver ", server, " disconnected: ", c.Err())
+				removeIndiClient(server, c)
+				return
+			}
+		}
+	}()
+
+	return c, nil
+}
+
+func closeIndiClients() {
+	indiClients.mu.Lock()
+	defer indiClients.mu.Unlock()
+
+	for server, c := range indiClients.clients {
+		c.Close()
+		delete(indiClients.clients, server)
+	}
+}
+
+// Removes the client from the cache, if it's still the client of the server.
+func removeIndiClient(server string, c *indi.Client) {
+	indiClients.mu.Lock()
+	defer indiClients.mu.Unlock()
+
+	if indiClients.clients[server] == c {
+		delete(indiClients.clients, server)
+	}
+}
@@ -0,0 +1,139 @@
+// Package jsonedit changes single values in JSON documents, keeping the rest of the document (formatting,
+// key order and unknown keys) untouched.
+package jsonedit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Set replaces the value at path in data with value marshaled to JSON. Path elements are object keys
+// (strings) or array indexes (ints). Missing object keys are added, missing array elements are an error.
+func Set(data []byte, value interface{}, path ...interface{}) ([]byte, error) {
+	v, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return set(data, v, path)
+}
+
+// Replaces the value at path in the raw JSON value data.
+func set(data, value []byte, path []interface{}) ([]byte, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	t, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch key := path[0].(type) {
+	case string:
+		if t != json.Delim('{') {
+			return nil, fmt.Errorf("jsonedit: %q: not an object", key)
+		}
+		lastStart, lastEnd := -1, -1
+		for dec.More() {
+			t, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			start, end, err := nextValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			if t == key {
+				return splice(data, start, end, value, path[1:])
+			}
+			lastStart, lastEnd = start, end
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return insert(data, int(dec.InputOffset())-1, lastStart, lastEnd, key, value, path[1:])
+	case int:
+		if t != json.Delim('[') {
+			return nil, fmt.Errorf("jsonedit: %d: not an array", key)
+		}
+		for i := 0; dec.More(); i++ {
+			start, end, err := nextValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			if i == key {
+				return splice(data, start, end, value, path[1:])
+			}
+		}
+		return nil, fmt.Errorf("jsonedit: index %d out of range", key)
+	default:
+		return nil, fmt.Errorf("jsonedit: invalid path element %v", path[0])
+	}
+}
+
+// Skips the next value of the decoder and returns its offsets.
+func nextValue(dec *json.Decoder) (start, end int, err error) {
+	var raw json.RawMessage
+	if err := dec.Decode(&raw); err != nil {
+		return 0, 0, err
+	}
+	end = int(dec.InputOffset())
+	return end - len(raw), end, nil
+}
+
+// Replaces the value between start and end with the edited value.
+func splice(data []byte, start, end int, value []byte, path []interface{}) ([]byte, error) {
+	v, err := set(data[start:end], value, path)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]byte, 0, len(data)-(end-start)+len(v))
+	res = append(res, data[:start]...)
+	res = append(res, v...)
+	return append(res, data[end:]...), nil
+}
+
+// Adds a new member to the object which closes at the given offset. lastStart and lastEnd are the offsets
+// of the value of the object's last member, or -1 if the object is empty. The new member is indented like
+// the last one.
+func insert(data []byte, closing, lastStart, lastEnd int, key string, value []byte,
+	path []interface{}) ([]byte, error) {
+
+	// Creating the missing objects of the path.
+	for i := len(path) - 1; i >= 0; i-- {
+		k, ok := path[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("jsonedit: index %v out of range", path[i])
+		}
+		v, err := json.Marshal(map[string]json.RawMessage{k: value})
+		if err != nil {
+			return nil, err
+		}
+		value = v
+	}
+	k, err := json.Marshal(key)
+	if err != nil {
+		return nil, err
+	}
+	member := append(append(k, ": "...), value...)
+
+	var res []byte
+	if lastEnd < 0 {
+		res = append(res, data[:closing]...)
+		res = append(res, member...)
+		return append(res, data[closing:]...), nil
+	}
+
+	sep := []byte(", ")
+	if lineStart := bytes.LastIndexByte(data[:lastStart], '\n'); lineStart >= 0 {
+		line := data[lineStart+1 : lastStart]
+		indent := line[:len(line)-len(bytes.TrimLeft(line, " \t"))]
+		sep = append([]byte(",\n"), indent...)
+	}
+	res = append(res, data[:lastEnd]...)
+	res = append(res, sep...)
+	res = append(res, member...)
+	return append(res, data[lastEnd:]...), nil
+}
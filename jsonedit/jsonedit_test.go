@@ -0,0 +1,116 @@
+package jsonedit
+
+import "testing"
+
+func TestSet(t *testing.T) {
+	const doc = `{
+	"site": {"latitude": 47.5},
+	"unknown": [1, 2],
+	"cams": [
+		{
+			"devNum": 0,
+			"guide": {
+				"pixelScale": 0.002
+			}
+		},
+		{
+			"devNum": 1
+		}
+	]
+}
+`
+	tests := []struct {
+		name  string
+		data  string
+		value interface{}
+		path  []interface{}
+		want  string
+	}{
+		{"replace", `{"a": 1, "b": {"c": true}}`, "x", []interface{}{"b", "c"}, `{"a": 1, "b": {"c": "x"}}`},
+		{"array element", `[1, [2, 3]]`, 4, []interface{}{1, 0}, `[1, [4, 3]]`},
+		{"add to compact object", `{"a": 1}`, 2, []interface{}{"b"}, `{"a": 1, "b": 2}`},
+		{"add to empty object", `{ }`, 2, []interface{}{"b"}, `{ "b": 2}`},
+		{"add missing objects", `{}`, 2, []interface{}{"a", "b"}, `{"a": {"b":2}}`},
+		{"existing key in indented document", doc, [2][2]float64{{1, 2}, {3, 4}},
+			[]interface{}{"cams", 0, "guide", "pixelScale"}, `{
+	"site": {"latitude": 47.5},
+	"unknown": [1, 2],
+	"cams": [
+		{
+			"devNum": 0,
+			"guide": {
+				"pixelScale": [[1,2],[3,4]]
+			}
+		},
+		{
+			"devNum": 1
+		}
+	]
+}
+`},
+		{"new key in indented document", doc, 0.5, []interface{}{"cams", 0, "guide", "step"}, `{
+	"site": {"latitude": 47.5},
+	"unknown": [1, 2],
+	"cams": [
+		{
+			"devNum": 0,
+			"guide": {
+				"pixelScale": 0.002,
+				"step": 0.5
+			}
+		},
+		{
+			"devNum": 1
+		}
+	]
+}
+`},
+		{"new object in indented document", doc, 0.5, []interface{}{"cams", 1, "guide", "pixelScale"}, `{
+	"site": {"latitude": 47.5},
+	"unknown": [1, 2],
+	"cams": [
+		{
+			"devNum": 0,
+			"guide": {
+				"pixelScale": 0.002
+			}
+		},
+		{
+			"devNum": 1,
+			"guide": {"pixelScale":0.5}
+		}
+	]
+}
+`},
+	}
+	for _, tt := range tests {
+		got, err := Set([]byte(tt.data), tt.value, tt.path...)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s:\n%s\nwant:\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSetErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		path []interface{}
+	}{
+		{"index out of range", `[1]`, []interface{}{1}},
+		{"key of array", `[1]`, []interface{}{"a"}},
+		{"index of object", `{"a": 1}`, []interface{}{0}},
+		{"missing array", `{}`, []interface{}{"a", 0}},
+		{"invalid json", `{"a": `, []interface{}{"a"}},
+		{"invalid path", `{}`, []interface{}{1.5}},
+	}
+	for _, tt := range tests {
+		if _, err := Set([]byte(tt.data), 1, tt.path...); err == nil {
+			t.Errorf("%s: no error", tt.name)
+		}
+	}
+}
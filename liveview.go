@@ -0,0 +1,642 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nonoo/jampec/astro"
+	"github.com/nonoo/jampec/event"
+	"github.com/nonoo/jampec/mount"
+	"github.com/nonoo/jampec/sgp4"
+	"github.com/nonoo/jampec/websocket"
+	"gocv.io/x/gocv"
+)
+
+const (
+	liveStatusInterval = 200 * time.Millisecond
+	liveWriteTimeout   = 5 * time.Second
+	mjpegBoundary      = "jampecframe"
+)
+
+// The state of a camera, pushed to the WebSocket clients.
+type camStatus struct {
+	Cam               int             `json:"cam"`
+	DevNum            int             `json:"devNum"`
+	Time              time.Time       `json:"time"`
+	Frame             int             `json:"frame"`
+	Width             int             `json:"width"`
+	Height            int             `json:"height"`
+	Active            bool            `json:"active"`
+	ShowOriginalImage bool            `json:"showOriginalImage"`
+	State             string          `json:"state"`
+	Tracker           string          `json:"tracker"`
+	Rect              *[4]int         `json:"rect"`
+	Filter            *recordFilter   `json:"filter"`
+	Mount             *mount.Position `json:"mount"`
+	Target            *targetStatus   `json:"target"`
+	Calibrating       bool            `json:"calibrating"`
+	Acquiring         bool            `json:"acquiring"`
+	Recording         bool            `json:"recording"`
+	DroppedFrames     int64           `json:"droppedFrames"`
+}
+
+type targetStatus struct {
+	// Zero if the target is the position commanded by a rotctld client.
+	SatNum int     `json:"satNum"`
+	Name   string  `json:"name"`
+	Up     bool    `json:"up"`
+	Az     float64 `json:"az"`
+	El     float64 `json:"el"`
+}
+
+func (s *camStruct) status(td *trackData) camStatus {
+	st := camStatus{
+		Cam:               s.nr,
+		DevNum:            s.config.DevNum,
+		Time:              td.frameTime,
+		Frame:             td.frameNr,
+		Width:             s.imgSize.X,
+		Height:            s.imgSize.Y,
+		Active:            s.controlActive,
+		ShowOriginalImage: s.showOrigImage,
+		State:             s.trackState.String(),
+		Tracker:           s.trackerAlgorithm,
+		Calibrating:       s.calibration != nil,
+		Acquiring:         s.acquisition != nil,
+		Recording:         s.recorder != nil,
+	}
+	m := s.recordMeta(td)
+	st.Rect = m.Rect
+	st.Filter = m.Filter
+	st.Mount = m.Mount
+	if s.recorder != nil {
+		st.DroppedFrames = s.recorder.droppedFrames()
+	}
+	if s.target != nil {
+		st.Target = &targetStatus{SatNum: s.target.TLE.SatNum, Name: s.target.TLE.Name, Up: s.targetUp}
+		if s.targetUp {
+			st.Target.Az = s.targetPrediction.Az
+			st.Target.El = s.targetPrediction.El
+		}
+	}
+	if p, ok := s.commandedPrediction(time.Now()); ok {
+		st.Target = &targetStatus{Name: "rotctld", Up: p.El >= 0, Az: p.Az, El: p.El}
+	}
+	return st
+}
+
+// Encodes the frames offered by a camera to JPEG in the background, and passes them to the clients. Frames
+// are dropped if there are no clients, if the encoder is busy, or to keep the maximum frame rate.
+type mjpegStream struct {
+	quality  int
+	interval time.Duration
+
+	frameChan chan gocv.Mat
+	lastFrame time.Time
+
+	mutex   sync.Mutex
+	clients map[chan []byte]bool
+	closed  bool
+}
+
+func newMJPEGStream(quality int, maxFPS float64) *mjpegStream {
+	m := &mjpegStream{
+		quality:   quality,
+		interval:  time.Duration(float64(time.Second) / maxFPS),
+		frameChan: make(chan gocv.Mat, 1),
+		clients:   make(map[chan []byte]bool),
+	}
+	go m.encodeLoop()
+	return m
+}
+
+func (m *mjpegStream) hasClients() bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return len(m.clients) > 0
+}
+
+// Called by the camera's loop, only copies img if it's going to be encoded.
+func (m *mjpegStream) offer(img gocv.Mat) {
+	if !m.hasClients() || len(m.frameChan) > 0 || time.Since(m.lastFrame) < m.interval {
+		return
+	}
+	m.lastFrame = time.Now()
+	m.frameChan <- img.Clone()
+}
+
+func (m *mjpegStream) encodeLoop() {
+	for img := range m.frameChan {
+		buf, err := gocv.IMEncodeWithParams(gocv.JPEGFileExt, img, []int{gocv.IMWriteJpegQuality, m.quality})
+		img.Close()
+		if err != nil {
+			log.Error("can't encode frame: ", err)
+			continue
+		}
+		m.mutex.Lock()
+		for c := range m.clients {
+			// Slow clients miss frames.
+			select {
+			case c <- buf:
+			default:
+			}
+		}
+		m.mutex.Unlock()
+	}
+
+	m.mutex.Lock()
+	for c := range m.clients {
+		close(c)
+	}
+	m.clients = nil
+	m.closed = true
+	m.mutex.Unlock()
+}
+
+func (m *mjpegStream) subscribe() (chan []byte, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.closed {
+		return nil, false
+	}
+	c := make(chan []byte, 1)
+	m.clients[c] = true
+	return c, true
+}
+
+func (m *mjpegStream) unsubscribe(c chan []byte) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if !m.closed {
+		delete(m.clients, c)
+	}
+}
+
+func (m *mjpegStream) close() {
+	close(m.frameChan)
+}
+
+func (m *mjpegStream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c, ok := m.subscribe()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	defer m.unsubscribe(c)
+
+	w.Header().Set("Content-Type", "multipart/x-mixed-replace; boundary="+mjpegBoundary)
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher, _ := w.(http.Flusher)
+	for {
+		select {
+		case buf, ok := <-c:
+			if !ok {
+				return
+			}
+			_, err := fmt.Fprintf(w, "--%s\r\nContent-Type: image/jpeg\r\nContent-Length: %d\r\n\r\n",
+				mjpegBoundary, len(buf))
+			if err == nil {
+				_, err = w.Write(buf)
+			}
+			if err == nil {
+				_, err = w.Write([]byte("\r\n"))
+			}
+			if err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// The front-end of a camera on the live view server.
+type webFrontend struct {
+	nr        int
+	devNum    int
+	annotated *mjpegStream
+	raw       *mjpegStream
+	cmdChan   chan controlCmd
+}
+
+func (f *webFrontend) Show(img gocv.Mat) {
+	f.annotated.offer(img)
+}
+
+func (f *webFrontend) WantsRaw() bool {
+	return f.raw.hasClients()
+}
+
+func (f *webFrontend) ShowRaw(img gocv.Mat) {
+	f.raw.offer(img)
+}
+
+func (f *webFrontend) Poll() []controlCmd {
+	var cmds []controlCmd
+	for {
+		select {
+		case cmd := <-f.cmdChan:
+			cmds = append(cmds, cmd)
+		default:
+			return cmds
+		}
+	}
+}
+
+func (f *webFrontend) Close() {
+	f.annotated.close()
+	f.raw.close()
+}
+
+// Serves the frames of the cameras as MJPEG streams, and their status and commands on a WebSocket.
+type liveServer struct {
+	httpServer *http.Server
+	mux        *http.ServeMux
+
+	events *event.Subscription
+	// Origins of other sites which may open WebSocket connections.
+	allowedOrigins []string
+
+	mutex     sync.Mutex
+	cams      map[int]*webFrontend
+	statuses  map[int]camStatus
+	wsClients map[*wsClient]bool
+}
+
+func newLiveServer() *liveServer {
+	s := &liveServer{
+		mux:       http.NewServeMux(),
+		cams:      make(map[int]*webFrontend),
+		statuses:  make(map[int]camStatus),
+		wsClients: make(map[*wsClient]bool),
+
+		allowedOrigins: config.Server.AllowedOrigins,
+	}
+	s.mux.HandleFunc("/cam/", s.handleCam)
+	s.mux.HandleFunc("/ws", s.handleWebSocket)
+	s.mux.HandleFunc("/api/passes", s.handlePasses)
+	s.mux.Handle("/", webUIHandler())
+	s.httpServer = &http.Server{Addr: config.Server.Listen, Handler: s.mux}
+	return s
+}
+
+// Returns the front-end of the camera on the server.
+func (s *liveServer) addCam(nr, devNum int) *webFrontend {
+	f := &webFrontend{
+		nr:        nr,
+		devNum:    devNum,
+		annotated: newMJPEGStream(config.Server.JPEGQuality, config.Server.MaxFPS),
+		raw:       newMJPEGStream(config.Server.JPEGQuality, config.Server.MaxFPS),
+		cmdChan:   make(chan controlCmd, 10),
+	}
+	s.mutex.Lock()
+	s.cams[nr] = f
+	s.mutex.Unlock()
+	return f
+}
+
+func (s *liveServer) start() error {
+	ln, err := net.Listen("tcp", s.httpServer.Addr)
+	if err != nil {
+		return err
+	}
+	log.Print("live view server listening on ", ln.Addr())
+	go func() {
+		if err := s.httpServer.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Error("live view server error: ", err)
+		}
+	}()
+	s.events = bus.SubscribeLossy(64, frameAnnotatedEvent{}, trackStateChangedEvent{}, mountTelemetryEvent{},
+		camActivatedEvent{})
+	go s.eventLoop()
+	go s.statusLoop()
+	return nil
+}
+
+func (s *liveServer) close() {
+	s.events.Close()
+	s.httpServer.Close()
+}
+
+func (s *liveServer) cam(nr int) (*webFrontend, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	f, ok := s.cams[nr]
+	return f, ok
+}
+
+// Handles /cam/<nr>/mjpeg (annotated frames) and /cam/<nr>/raw.mjpeg (original frames).
+func (s *liveServer) handleCam(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/cam/"), "/")
+	if len(parts) != 2 {
+		http.NotFound(w, r)
+		return
+	}
+	nr, err := strconv.Atoi(parts[0])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	f, ok := s.cam(nr)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	switch parts[1] {
+	case "mjpeg":
+		f.annotated.ServeHTTP(w, r)
+	case "raw.mjpeg":
+		f.raw.ServeHTTP(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Keeps the last status of the cameras, and pushes the target state changes, the mount positions and the
+// activation changes to the WebSocket clients as they happen.
+func (s *liveServer) eventLoop() {
+	for e := range s.events.C {
+		s.mutex.Lock()
+		switch e := e.(type) {
+		case frameAnnotatedEvent:
+			s.statuses[e.status.Cam] = e.status
+		case trackStateChangedEvent:
+			msg := liveMsg{Type: "trackState", TrackState: &liveTrackState{Cam: e.cam, From: e.from.String(),
+				To: e.to.String(), Time: e.time}}
+			for c := range s.wsClients {
+				s.send(c, msg)
+			}
+		case mountTelemetryEvent:
+			m := &liveMount{Cam: e.cam, Time: e.time}
+			if e.err != nil {
+				m.Error = e.err.Error()
+			} else {
+				m.Az, m.El = e.pos.Az, e.pos.El
+			}
+			for c := range s.wsClients {
+				s.send(c, liveMsg{Type: "mount", Mount: m})
+			}
+		case camActivatedEvent:
+			if st, ok := s.statuses[e.cam]; ok {
+				st.Active = e.active
+				s.statuses[e.cam] = st
+			}
+			msg := liveMsg{Type: "activated", Activated: &liveActivated{Cam: e.cam, Active: e.active}}
+			for c := range s.wsClients {
+				s.send(c, msg)
+			}
+		}
+		s.mutex.Unlock()
+	}
+}
+
+// A message sent on the WebSocket.
+type liveMsg struct {
+	Type       string          `json:"type"`
+	Cams       []camStatus     `json:"cams,omitempty"`
+	TrackState *liveTrackState `json:"trackState,omitempty"`
+	Mount      *liveMount      `json:"mount,omitempty"`
+	Activated  *liveActivated  `json:"activated,omitempty"`
+	Lines      []string        `json:"lines,omitempty"`
+	Error      string          `json:"error,omitempty"`
+}
+
+type liveTrackState struct {
+	Cam  int       `json:"cam"`
+	From string    `json:"from"`
+	To   string    `json:"to"`
+	Time time.Time `json:"time"`
+}
+
+// A mount position poll. Az and El are not valid if Error is set.
+type liveMount struct {
+	Cam   int       `json:"cam"`
+	Az    float64   `json:"az"`
+	El    float64   `json:"el"`
+	Time  time.Time `json:"time"`
+	Error string    `json:"error,omitempty"`
+}
+
+type liveActivated struct {
+	Cam    int  `json:"cam"`
+	Active bool `json:"active"`
+}
+
+// A command received on the WebSocket.
+type liveCmd struct {
+	Cmd  string  `json:"cmd"`
+	Cam  int     `json:"cam"`
+	Rect *[4]int `json:"rect"`
+	// Jog rates in degrees per second.
+	Az float64 `json:"az"`
+	El float64 `json:"el"`
+}
+
+var liveCmdTypes = map[string]controlCmdType{
+	"exit":          controlCmdTypeExit,
+	"activate":      controlCmdTypeActivate,
+	"showOriginal":  controlCmdTypeShowOriginalImage,
+	"calibrate":     controlCmdTypeCalibrate,
+	"switchTracker": controlCmdTypeSwitchTracker,
+	"acquire":       controlCmdTypeAcquire,
+	"record":        controlCmdTypeRecord,
+	"snapshot":      controlCmdTypeSnapshot,
+	"select":        controlCmdTypeSelectRect,
+	"cancel":        controlCmdTypeCancelSelection,
+	"jog":           controlCmdTypeJog,
+}
+
+// Passes the command to the camera's loop.
+func (s *liveServer) handleCmd(c liveCmd) error {
+	t, ok := liveCmdTypes[c.Cmd]
+	if !ok {
+		return fmt.Errorf("unknown command %q", c.Cmd)
+	}
+	f, ok := s.cam(c.Cam)
+	if !ok {
+		return fmt.Errorf("unknown camera %d", c.Cam)
+	}
+	cmd := controlCmd{cmdType: t, camNr: f.devNum, azRate: c.Az, elRate: c.El}
+	if t == controlCmdTypeSelectRect {
+		if c.Rect == nil {
+			return errors.New("missing rect")
+		}
+		r := c.Rect
+		cmd.rect = image.Rect(r[0], r[1], r[2], r[3])
+	}
+	select {
+	case f.cmdChan <- cmd:
+	default:
+		return errors.New("camera is busy")
+	}
+	return nil
+}
+
+// A WebSocket client.
+type wsClient struct {
+	sendChan chan []byte
+	// Number of log lines sent to the client.
+	logCount int
+}
+
+func (s *liveServer) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	if !websocket.CheckOrigin(r, s.allowedOrigins) {
+		log.Error("live view websocket from ", r.RemoteAddr, " rejected, origin ", r.Header.Get("Origin"),
+			" is not allowed")
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+	conn, err := websocket.Upgrade(w, r)
+	if err != nil {
+		return
+	}
+	c := &wsClient{sendChan: make(chan []byte, 16)}
+	s.mutex.Lock()
+	s.wsClients[c] = true
+	s.mutex.Unlock()
+
+	go func() {
+		for msg := range c.sendChan {
+			conn.SetWriteDeadline(time.Now().Add(liveWriteTimeout))
+			if err := conn.WriteMessage(websocket.OpText, msg); err != nil {
+				conn.Close()
+				// Draining until the reader removes the client.
+				for range c.sendChan {
+				}
+				return
+			}
+		}
+		conn.Close()
+	}()
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			break
+		}
+		var cmd liveCmd
+		if err = json.Unmarshal(data, &cmd); err == nil {
+			err = s.handleCmd(cmd)
+		}
+		if err != nil {
+			s.mutex.Lock()
+			s.send(c, liveMsg{Type: "error", Error: err.Error()})
+			s.mutex.Unlock()
+		}
+	}
+
+	s.mutex.Lock()
+	delete(s.wsClients, c)
+	close(c.sendChan)
+	s.mutex.Unlock()
+}
+
+// Sends without blocking, slow clients miss messages. Called with the mutex locked.
+func (s *liveServer) send(c *wsClient, msg liveMsg) {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		log.Error("can't marshal live view message: ", err)
+		return
+	}
+	select {
+	case c.sendChan <- b:
+	default:
+	}
+}
+
+// Pushes the status of the cameras and the new log lines to the WebSocket clients.
+func (s *liveServer) statusLoop() {
+	ticker := time.NewTicker(liveStatusInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.mutex.Lock()
+		msg := liveMsg{Type: "status"}
+		for _, st := range s.statuses {
+			msg.Cams = append(msg.Cams, st)
+		}
+		sort.Slice(msg.Cams, func(i, j int) bool { return msg.Cams[i].Cam < msg.Cams[j].Cam })
+		for c := range s.wsClients {
+			s.send(c, msg)
+			var lines []string
+			if lines, c.logCount = logTail.since(c.logCount); len(lines) > 0 {
+				s.send(c, liveMsg{Type: "log", Lines: lines})
+			}
+		}
+		s.mutex.Unlock()
+	}
+}
+
+// Returns the upcoming passes of the cameras' targets. The hours, minel and visible query parameters are
+// the same as the flags of the passes command.
+func (s *liveServer) handlePasses(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	hours := 24.0
+	if v := q.Get("hours"); v != "" {
+		var err error
+		if hours, err = strconv.ParseFloat(v, 64); err != nil || hours <= 0 || hours > 24*7 {
+			http.Error(w, "invalid hours", http.StatusBadRequest)
+			return
+		}
+	}
+	var minEl float64
+	if v := q.Get("minel"); v != "" {
+		var err error
+		if minEl, err = strconv.ParseFloat(v, 64); err != nil {
+			http.Error(w, "invalid minel", http.StatusBadRequest)
+			return
+		}
+	}
+
+	// The cameras may update their config while the request is served.
+	type target struct {
+		tle    string
+		satNum int
+	}
+	var targets []target
+	configMutex.Lock()
+	for _, c := range config.Cams {
+		if !c.Disabled && c.Target.TLE != "" {
+			targets = append(targets, target{tle: c.Target.TLE, satNum: c.Target.SatNum})
+		}
+	}
+	configMutex.Unlock()
+
+	var tles []*sgp4.TLE
+	seen := make(map[int]bool)
+	for _, t := range targets {
+		if seen[t.satNum] {
+			continue
+		}
+		catalog, err := sgp4.LoadCatalog(t.tle)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if tle, ok := sgp4.FindSatNum(catalog, t.satNum); ok {
+			tles = append(tles, tle)
+			seen[t.satNum] = true
+		}
+	}
+
+	start := time.Now().UTC().Truncate(time.Second)
+	end := start.Add(time.Duration(hours * float64(time.Hour)))
+	passes := predictSatPasses(tles, start, end, astro.PassOptions{MinElevation: minEl, SunElevationLimit: -6},
+		q.Get("visible") == "true")
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(passes); err != nil {
+		log.Error("can't send passes: ", err)
+	}
+}
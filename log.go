@@ -2,9 +2,12 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"sync"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -17,6 +20,45 @@ type logger struct {
 
 var log logger
 
+const logTailSize = 200
+
+// Keeps the last lines of the log for the web console.
+type logTailWriter struct {
+	mutex sync.Mutex
+	lines []string
+	// Number of lines written so far.
+	count int
+}
+
+var logTail logTailWriter
+
+func (w *logTailWriter) Write(p []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	for _, l := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		w.lines = append(w.lines, l)
+		w.count++
+	}
+	if len(w.lines) > logTailSize {
+		w.lines = append([]string(nil), w.lines[len(w.lines)-logTailSize:]...)
+	}
+	return len(p), nil
+}
+
+// Returns the lines written after the first since lines, and the number of written lines.
+func (w *logTailWriter) since(since int) ([]string, int) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	n := w.count - since
+	if n > len(w.lines) {
+		n = len(w.lines)
+	}
+	if n <= 0 {
+		return nil, w.count
+	}
+	return append([]string(nil), w.lines[len(w.lines)-n:]...), w.count
+}
+
 func (l *logger) GetCallerFileName(withLine bool) string {
 	_, filename, line, _ := runtime.Caller(2)
 	extension := filepath.Ext(filename)
@@ -55,7 +97,7 @@ func (l *logger) Init() {
 
 	level := zap.DebugLevel
 
-	core := zapcore.NewCore(consoleEncoder, zapcore.AddSync(os.Stdout), level)
+	core := zapcore.NewCore(consoleEncoder, zapcore.AddSync(io.MultiWriter(os.Stdout, &logTail)), level)
 	l.logger = zap.New(core).Sugar()
 
 	var callerFilename string
@@ -1,89 +1,87 @@
 package main
 
 import (
+	"fmt"
 	"os"
-	"reflect"
+	"os/signal"
+	"syscall"
 )
 
-type ctrlMsgType int
-
-const (
-	ctrlMsgTypeExit              = ctrlMsgType(iota) // value1: error
-	ctrlMsgTypeActive                                // value1: cam nr, value2: true/false
-	ctrlMsgTypeShowOriginalImage                     // value1: true/false
-)
-
-type ctrlMsg struct {
-	msgType ctrlMsgType
-	value1  interface{}
-	value2  interface{}
-}
-
 func main() {
 	log.Init()
 
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "passes":
+			if err := runPasses(os.Args[2:]); err != nil {
+				log.Error(err)
+				os.Exit(1)
+			}
+		default:
+			log.Error("unknown command ", os.Args[1])
+			os.Exit(1)
+		}
+		return
+	}
+
 	if err := loadConfig("config.json"); err != nil {
 		log.Error(err)
 		os.Exit(1)
 	}
 
-	var cams []camStruct
-	for i := range configs {
-		if configs[i].Disabled {
+	var server *liveServer
+	if config.Server.Listen != "" {
+		server = newLiveServer()
+		if err := server.start(); err != nil {
+			log.Error("can't start live view server: ", err)
+			os.Exit(1)
+		}
+	}
+
+	exitEvents := bus.SubscribeLossy(1, exitRequestedEvent{})
+
+	var cams []*camStruct
+	for i := range config.Cams {
+		if config.Cams[i].Disabled {
 			continue
 		}
-		newCam := camStruct{}
-		err := newCam.init(configs[i], i)
+		newCam := &camStruct{}
+		// OpenCV doesn't tell which window a key was pressed in, so keys are only read by the first camera.
+		err := newCam.init(config.Cams[i], i, config.Headless, len(cams) == 0)
 		if err != nil {
 			log.Error(err.Error())
 			os.Exit(1)
 		}
 
+		if server != nil {
+			newCam.frontends = append(newCam.frontends, server.addCam(newCam.nr, newCam.config.DevNum))
+		}
+
 		go newCam.loop()
 		cams = append(cams, newCam)
 	}
 
-	cases := make([]reflect.SelectCase, len(cams))
+	// Exiting cleanly on Ctrl-C, so recordings are finished.
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-sigChan
+		bus.Publish(exitRequestedEvent{reason: fmt.Sprint("got signal ", sig)})
+	}()
+
+	e := (<-exitEvents.C).(exitRequestedEvent)
+	log.Print("exiting: ", e.reason)
 	for i := range cams {
-		cases[i] = reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(cams[i].ctrlOutChan)}
+		cams[i].stopRequestedChan <- true
+		<-cams[i].stopFinishedChan
+	}
+	closeIndiClients()
+	if server != nil {
+		server.close()
 	}
 
-	for {
-		_, value, _ := reflect.Select(cases)
-		msg, ok := value.Interface().(ctrlMsg)
-		if !ok {
-			continue
-		}
-
-		switch msg.msgType {
-		case ctrlMsgTypeExit:
-			err, _ := msg.value1.(error)
-			for i := range cams {
-				cams[i].stopRequestedChan <- true
-				<-cams[i].stopFinishedChan
-			}
-
-			if err != nil {
-				log.Error(err.Error())
-				os.Exit(1)
-			}
-			return
-		case ctrlMsgTypeActive:
-			nr := -1
-			for i := range cams {
-				if cams[i].config.DevNum == msg.value1 {
-					nr = i
-				} else {
-					cams[i].ctrlInChan <- ctrlMsg{msgType: ctrlMsgTypeActive, value2: false}
-				}
-			}
-			if nr >= 0 {
-				cams[nr].ctrlInChan <- ctrlMsg{msgType: ctrlMsgTypeActive, value2: true}
-			}
-		case ctrlMsgTypeShowOriginalImage:
-			for i := range cams {
-				cams[i].ctrlInChan <- ctrlMsg{msgType: ctrlMsgTypeShowOriginalImage, value1: msg.value1}
-			}
-		}
+	if e.err != nil {
+		log.Error(e.err.Error())
+		os.Exit(1)
 	}
 }
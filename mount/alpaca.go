@@ -0,0 +1,134 @@
+package mount
+
+import (
+	"errors"
+	"math"
+
+	"github.com/nonoo/jampec/alpaca"
+)
+
+// Alpaca drives an alt-az ASCOM Alpaca telescope.
+type Alpaca struct {
+	telescope *alpaca.Telescope
+	// Highest rates MoveAxis accepts on the axes in degrees per second, zero if not known.
+	maxRates [2]float64
+	// The tracking was turned off before driving the telescope.
+	trackingOff bool
+	// The tracking was on, and is turned back on by Close.
+	restoreTracking bool
+
+	// The last sent axis rates. Commands are only sent on changes.
+	azRate float64
+	elRate float64
+}
+
+// NewAlpaca connects the telescope. Its tracking is left alone until the telescope is driven.
+func NewAlpaca(t *alpaca.Telescope) (*Alpaca, error) {
+	if err := t.SetConnected(true); err != nil {
+		return nil, err
+	}
+	m := &Alpaca{telescope: t}
+	for axis := range m.maxRates {
+		rates, err := t.AxisRates(axis)
+		if err != nil {
+			continue
+		}
+		for _, r := range rates {
+			m.maxRates[axis] = math.Max(m.maxRates[axis], r.Maximum)
+		}
+	}
+	return m, nil
+}
+
+// Turns off the tracking before the first slew or move, as alt-az slews need it off, and sidereal tracking
+// would add to the rates of Move.
+func (m *Alpaca) stopTracking() error {
+	if m.trackingOff {
+		return nil
+	}
+	tracking, err := m.telescope.Tracking()
+	if err != nil && !isAlpacaError(err, alpaca.ErrNotImplemented) {
+		return err
+	}
+	if tracking {
+		if err := m.telescope.SetTracking(false); err != nil {
+			return err
+		}
+		m.restoreTracking = true
+	}
+	m.trackingOff = true
+	return nil
+}
+
+func isAlpacaError(err error, number int) bool {
+	var e *alpaca.Error
+	return errors.As(err, &e) && e.Number == number
+}
+
+func (m *Alpaca) Position() (Position, error) {
+	az, err := m.telescope.Azimuth()
+	if err != nil {
+		return Position{}, err
+	}
+	el, err := m.telescope.Altitude()
+	if err != nil {
+		return Position{}, err
+	}
+	return Position{Az: az, El: el}, nil
+}
+
+func (m *Alpaca) Goto(p Position) error {
+	if err := m.stopTracking(); err != nil {
+		return err
+	}
+	m.azRate, m.elRate = 0, 0
+	return m.telescope.SlewToAltAzAsync(NormalizeAz(p.Az), p.El)
+}
+
+func (m *Alpaca) Move(azRate, elRate float64) error {
+	if azRate != 0 || elRate != 0 {
+		if err := m.stopTracking(); err != nil {
+			return err
+		}
+	}
+	if azRate != m.azRate {
+		if err := m.telescope.MoveAxis(alpaca.AxisPrimary, m.limitRate(alpaca.AxisPrimary, azRate)); err != nil {
+			return err
+		}
+		m.azRate = azRate
+	}
+	if elRate != m.elRate {
+		if err := m.telescope.MoveAxis(alpaca.AxisSecondary, m.limitRate(alpaca.AxisSecondary, elRate)); err != nil {
+			return err
+		}
+		m.elRate = elRate
+	}
+	return nil
+}
+
+func (m *Alpaca) limitRate(axis int, rate float64) float64 {
+	if l := m.maxRates[axis]; l > 0 {
+		return math.Max(-l, math.Min(l, rate))
+	}
+	return rate
+}
+
+func (m *Alpaca) Stop() error {
+	m.azRate, m.elRate = 0, 0
+	for _, axis := range []int{alpaca.AxisPrimary, alpaca.AxisSecondary} {
+		if err := m.telescope.MoveAxis(axis, 0); err != nil {
+			return err
+		}
+	}
+	return m.telescope.AbortSlew()
+}
+
+// Close turns the tracking back on if it was turned off, and leaves the telescope connected, as other
+// clients of the Alpaca server may use it.
+func (m *Alpaca) Close() error {
+	if !m.restoreTracking {
+		return nil
+	}
+	m.trackingOff, m.restoreTracking = false, false
+	return m.telescope.SetTracking(true)
+}
@@ -0,0 +1,113 @@
+package mount
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/nonoo/jampec/alpaca"
+)
+
+// A stand-in Alpaca telescope which records the PUT requests.
+type fakeAlpacaTelescope struct {
+	mu       sync.Mutex
+	tracking bool
+	puts     []string
+}
+
+func (f *fakeAlpacaTelescope) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	method := path.Base(r.URL.Path)
+	resp := map[string]interface{}{}
+	if r.Method == http.MethodPut {
+		put := method
+		for _, k := range []string{"Axis", "Rate", "Tracking"} {
+			if v := r.Form.Get(k); v != "" {
+				put += " " + v
+			}
+		}
+		f.puts = append(f.puts, put)
+		if method == "tracking" {
+			f.tracking = r.Form.Get("Tracking") == "true"
+		}
+	} else {
+		switch method {
+		case "tracking":
+			resp["Value"] = f.tracking
+		case "axisrates":
+			resp["Value"] = []alpaca.AxisRate{{Minimum: 0, Maximum: 2}}
+		default:
+			resp["ErrorNumber"] = alpaca.ErrNotImplemented
+		}
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (f *fakeAlpacaTelescope) expect(t *testing.T, puts ...string) {
+	t.Helper()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if got, want := strings.Join(f.puts, ", "), strings.Join(puts, ", "); got != want {
+		t.Errorf("requests %q, want %q", got, want)
+	}
+	f.puts = nil
+}
+
+func TestAlpacaTracking(t *testing.T) {
+	f := &fakeAlpacaTelescope{tracking: true}
+	s := httptest.NewServer(f)
+	defer s.Close()
+
+	c := alpaca.NewClient(strings.TrimPrefix(s.URL, "http://"))
+	m, err := NewAlpaca(alpaca.NewTelescope(c, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The tracking is only turned off when the telescope is driven.
+	f.expect(t, "connected")
+	if err := m.Move(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	f.expect(t)
+
+	// The rates are limited to the axis rates.
+	if err := m.Move(3, -0.5); err != nil {
+		t.Fatal(err)
+	}
+	f.expect(t, "tracking false", "moveaxis 0 2", "moveaxis 1 -0.5")
+	if err := m.Goto(Position{Az: 10, El: 20}); err != nil {
+		t.Fatal(err)
+	}
+	f.expect(t, "slewtoaltazasync")
+
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.expect(t, "tracking true")
+}
+
+func TestAlpacaNotTracking(t *testing.T) {
+	f := &fakeAlpacaTelescope{}
+	s := httptest.NewServer(f)
+	defer s.Close()
+
+	c := alpaca.NewClient(strings.TrimPrefix(s.URL, "http://"))
+	m, err := NewAlpaca(alpaca.NewTelescope(c, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Goto(Position{Az: 10, El: 20}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.expect(t, "connected", "slewtoaltazasync")
+}
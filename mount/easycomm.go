@@ -0,0 +1,114 @@
+package mount
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EasyCommRateLead is how far ahead the target of an emulated rate move is placed, like IndiRateLead.
+const EasyCommRateLead = time.Second
+
+// EasyComm drives a rotator controller speaking EasyComm II.
+type EasyComm struct {
+	port *linePort
+	// Use the move commands for rate moves, otherwise they're emulated with slews.
+	move bool
+
+	// The last sent rotation directions (-1, 0 or 1). Commands are only sent on changes.
+	azDir int
+	elDir int
+}
+
+// NewEasyComm returns a mount which sends the EasyComm II commands to port. EasyComm II has no speed
+// setting, so if move is set, Move turns the axes at the controller's speed in the direction of the rates,
+// otherwise it's emulated by slewing ahead of the rotator.
+func NewEasyComm(port Port, move bool) (*EasyComm, error) {
+	m := &EasyComm{port: newLinePort(port, '\n'), move: move}
+	// Checking if the controller answers.
+	if _, err := m.Position(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Version returns the version string of the controller.
+func (m *EasyComm) Version() (string, error) {
+	resp, err := m.port.query("VE\n")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(resp, "VE"), nil
+}
+
+// The controller answers "AZaaa.a ELeee.e".
+func (m *EasyComm) Position() (Position, error) {
+	resp, err := m.port.query("AZ EL\n")
+	if err != nil {
+		return Position{}, err
+	}
+	var p Position
+	var azOK, elOK bool
+	for _, f := range strings.Fields(resp) {
+		switch {
+		case strings.HasPrefix(f, "AZ"):
+			p.Az, err = strconv.ParseFloat(f[2:], 64)
+			azOK = err == nil
+		case strings.HasPrefix(f, "EL"):
+			p.El, err = strconv.ParseFloat(f[2:], 64)
+			elOK = err == nil
+		}
+	}
+	if !azOK || !elOK {
+		return Position{}, fmt.Errorf("easycomm: invalid position %q", resp)
+	}
+	return p, nil
+}
+
+func (m *EasyComm) Goto(p Position) error {
+	m.azDir, m.elDir = 0, 0
+	return m.port.send(fmt.Sprintf("AZ%.1f EL%.1f\n", NormalizeAz(p.Az), p.El))
+}
+
+func (m *EasyComm) Move(azRate, elRate float64) error {
+	if azRate == 0 && elRate == 0 {
+		return m.Stop()
+	}
+	if !m.move {
+		p, err := m.Position()
+		if err != nil {
+			return err
+		}
+		lead := EasyCommRateLead.Seconds()
+		p.Az += azRate * lead
+		p.El += elRate * lead
+		return m.Goto(p)
+	}
+
+	azDir, elDir := sign(azRate), sign(elRate)
+	var cmds []string
+	if azDir != m.azDir {
+		cmds = append(cmds, map[int]string{-1: "ML", 0: "SA", 1: "MR"}[azDir])
+	}
+	if elDir != m.elDir {
+		cmds = append(cmds, map[int]string{-1: "MD", 0: "SE", 1: "MU"}[elDir])
+	}
+	if len(cmds) == 0 {
+		return nil
+	}
+	if err := m.port.send(strings.Join(cmds, " ") + "\n"); err != nil {
+		return err
+	}
+	m.azDir, m.elDir = azDir, elDir
+	return nil
+}
+
+func (m *EasyComm) Stop() error {
+	m.azDir, m.elDir = 0, 0
+	return m.port.send("SA SE\n")
+}
+
+func (m *EasyComm) Close() error {
+	return m.port.close()
+}
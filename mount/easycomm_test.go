@@ -0,0 +1,98 @@
+package mount
+
+import (
+	"testing"
+)
+
+func easyCommReply(cmd string) string {
+	switch cmd {
+	case "AZ EL\n":
+		return "AZ123.4 EL45.6\n"
+	case "VE\n":
+		return "VE1.2\n"
+	}
+	return ""
+}
+
+func TestEasyCommPosition(t *testing.T) {
+	tests := []struct {
+		reply string
+		want  Position
+		err   bool
+	}{
+		{"AZ123.4 EL45.6\n", Position{Az: 123.4, El: 45.6}, false},
+		{"AZ0.0 EL-1.5\r\n", Position{Az: 0, El: -1.5}, false},
+		{"EL10.0 AZ20.0\n", Position{Az: 20, El: 10}, false},
+		{"AZ123.4\n", Position{}, true},
+		{"AZx EL1\n", Position{}, true},
+	}
+	for _, tt := range tests {
+		first := true
+		f, port := newFakeController(t, splitAfter('\n'), func(cmd string) string {
+			if first {
+				first = false
+				return easyCommReply(cmd)
+			}
+			return tt.reply
+		})
+		m, err := NewEasyComm(port, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err := m.Position()
+		if (err != nil) != tt.err || p != tt.want {
+			t.Errorf("reply %q: position %+v, %v, want %+v", tt.reply, p, err, tt.want)
+		}
+		f.expect(t, "AZ EL\n", "AZ EL\n")
+	}
+}
+
+func TestEasyCommCommands(t *testing.T) {
+	f, port := newFakeController(t, splitAfter('\n'), easyCommReply)
+	m, err := NewEasyComm(port, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.expect(t, "AZ EL\n")
+
+	steps := []struct {
+		name   string
+		do     func() error
+		expect []string
+	}{
+		{"version", func() error {
+			v, err := m.Version()
+			if v != "1.2" {
+				t.Errorf("version %q", v)
+			}
+			return err
+		}, []string{"VE\n"}},
+		{"goto", func() error { return m.Goto(Position{Az: -10, El: 10.55}) }, []string{"AZ350.0 EL10.6\n"}},
+		// There is no speed setting, the axes only get a direction.
+		{"move", func() error { return m.Move(0.1, -3) }, []string{"MR MD\n"}},
+		{"same directions", func() error { return m.Move(2, -0.1) }, nil},
+		{"elevation stops", func() error { return m.Move(2, 0) }, []string{"SE\n"}},
+		{"reverse", func() error { return m.Move(-2, 1) }, []string{"ML MU\n"}},
+		{"stop", func() error { return m.Move(0, 0) }, []string{"SA SE\n"}},
+	}
+	for _, s := range steps {
+		if err := s.do(); err != nil {
+			t.Errorf("%s: %v", s.name, err)
+		}
+		f.expect(t, s.expect...)
+	}
+}
+
+func TestEasyCommEmulatedMove(t *testing.T) {
+	f, port := newFakeController(t, splitAfter('\n'), easyCommReply)
+	m, err := NewEasyComm(port, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.expect(t, "AZ EL\n")
+
+	if err := m.Move(-1, 0.5); err != nil {
+		t.Fatal(err)
+	}
+	f.expect(t, "AZ EL\n", "AZ122.4 EL46.1\n")
+}
@@ -0,0 +1,175 @@
+package mount
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// GS232RateLead is how far ahead the target of an emulated rate move is placed, like IndiRateLead.
+const GS232RateLead = time.Second
+
+// The reference of emulated moves restarts from the reported position if it's further from it than this
+// many degrees plus the lead, like after the rotator couldn't keep up.
+const gs232MaxRefError = 2
+
+// Speeds of the X command.
+const (
+	gs232MinSpeed = 1
+	gs232MaxSpeed = 4
+)
+
+var gs232Number = regexp.MustCompile(`[-+]?\d+(\.\d+)?`)
+
+// GS232 drives a Yaesu GS-232A or GS-232B rotator controller.
+type GS232 struct {
+	port *linePort
+	// Azimuth rate at the maximum speed in degrees per second. If zero, moves are emulated with slews.
+	maxRate float64
+
+	// The last sent rotation directions (-1, 0 or 1) and speed. Commands are only sent on changes.
+	azDir int
+	elDir int
+	speed int
+
+	// Emulated moves slew ahead of a reference position which moves with the rates. The controller only
+	// takes whole degrees, and slow moves ahead of the reported position would be rounded back to it.
+	emulating bool
+	ref       Position
+	refTime   time.Time
+	refAzRate float64
+	refElRate float64
+
+	// Returns the current time, replaced by the tests.
+	now func() time.Time
+}
+
+// NewGS232 returns a mount which sends the GS-232 commands to port. If maxRate is set, Move uses the
+// rotation commands with the azimuth speed set proportional to the azimuth rate, otherwise it's emulated
+// by slewing ahead of the rotator.
+func NewGS232(port Port, maxRate float64) (*GS232, error) {
+	m := &GS232{port: newLinePort(port, '\r'), maxRate: maxRate, now: time.Now}
+	// Checking if the controller answers.
+	if _, err := m.Position(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// The GS-232A answers "+0aaa+0eee", the GS-232B "AZ=aaa  EL=eee".
+func (m *GS232) Position() (Position, error) {
+	resp, err := m.port.query("C2\r")
+	if err != nil {
+		return Position{}, err
+	}
+	n := gs232Number.FindAllString(resp, -1)
+	if len(n) != 2 {
+		return Position{}, fmt.Errorf("gs232: invalid position %q", resp)
+	}
+	az, err := strconv.ParseFloat(n[0], 64)
+	if err != nil {
+		return Position{}, err
+	}
+	el, err := strconv.ParseFloat(n[1], 64)
+	if err != nil {
+		return Position{}, err
+	}
+	return Position{Az: az, El: el}, nil
+}
+
+func (m *GS232) Goto(p Position) error {
+	m.azDir, m.elDir = 0, 0
+	m.emulating = false
+	return m.slew(p)
+}
+
+func (m *GS232) slew(p Position) error {
+	el := math.Max(0, math.Min(180, p.El))
+	return m.port.send(fmt.Sprintf("W%03d %03d\r", int(math.Round(NormalizeAz(p.Az)))%360, int(math.Round(el))))
+}
+
+func (m *GS232) Move(azRate, elRate float64) error {
+	if azRate == 0 && elRate == 0 {
+		return m.Stop()
+	}
+	if m.maxRate <= 0 {
+		return m.emulateMove(azRate, elRate)
+	}
+
+	if azRate != 0 {
+		speed := int(math.Ceil(math.Abs(azRate) / m.maxRate * gs232MaxSpeed))
+		if speed < gs232MinSpeed {
+			speed = gs232MinSpeed
+		} else if speed > gs232MaxSpeed {
+			speed = gs232MaxSpeed
+		}
+		if speed != m.speed {
+			if err := m.port.send(fmt.Sprintf("X%d\r", speed)); err != nil {
+				return err
+			}
+			m.speed = speed
+		}
+	}
+	if dir := sign(azRate); dir != m.azDir {
+		if err := m.port.send(map[int]string{-1: "L\r", 0: "A\r", 1: "R\r"}[dir]); err != nil {
+			return err
+		}
+		m.azDir = dir
+	}
+	if dir := sign(elRate); dir != m.elDir {
+		if err := m.port.send(map[int]string{-1: "D\r", 0: "E\r", 1: "U\r"}[dir]); err != nil {
+			return err
+		}
+		m.elDir = dir
+	}
+	return nil
+}
+
+// Slews ahead of the reference position, which is moved with the previous rates since the last call.
+func (m *GS232) emulateMove(azRate, elRate float64) error {
+	p, err := m.Position()
+	if err != nil {
+		return err
+	}
+	now := m.now()
+	lead := GS232RateLead.Seconds()
+	if m.emulating {
+		dt := now.Sub(m.refTime).Seconds()
+		m.ref.Az += m.refAzRate * dt
+		m.ref.El += m.refElRate * dt
+		// The rotator is ahead of the reference by the lead.
+		maxErr := gs232MaxRefError + math.Hypot(m.refAzRate, m.refElRate)*lead
+		if math.Hypot(math.Remainder(p.Az-m.ref.Az, 360), p.El-m.ref.El) > maxErr {
+			m.emulating = false
+		}
+	}
+	if !m.emulating {
+		m.ref = p
+		m.emulating = true
+	}
+	m.refTime = now
+	m.refAzRate, m.refElRate = azRate, elRate
+	return m.slew(Position{Az: m.ref.Az + azRate*lead, El: m.ref.El + elRate*lead})
+}
+
+func (m *GS232) Stop() error {
+	m.azDir, m.elDir = 0, 0
+	m.emulating = false
+	return m.port.send("S\r")
+}
+
+func (m *GS232) Close() error {
+	return m.port.close()
+}
+
+func sign(v float64) int {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+	return 0
+}
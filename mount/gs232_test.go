@@ -0,0 +1,165 @@
+package mount
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGS232Position(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply string
+		want  Position
+		err   bool
+	}{
+		{"gs-232a", "+0123+0045\r", Position{Az: 123, El: 45}, false},
+		{"gs-232a at zero", "+0000+0000\r", Position{}, false},
+		{"gs-232b", "AZ=123  EL=045\r", Position{Az: 123, El: 45}, false},
+		{"gs-232b with newline", "\nAZ=359  EL=180\r\n", Position{Az: 359, El: 180}, false},
+		{"azimuth only", "AZ=123\r", Position{}, true},
+		{"error", "?>\r", Position{}, true},
+	}
+	for _, tt := range tests {
+		first := true
+		f, port := newFakeController(t, splitAfter('\r'), func(cmd string) string {
+			// NewGS232 reads the position to check the controller.
+			if first {
+				first = false
+				return "+0000+0000\r"
+			}
+			return tt.reply
+		})
+		m, err := NewGS232(port, 0)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		p, err := m.Position()
+		if (err != nil) != tt.err || p != tt.want {
+			t.Errorf("%s: position %+v, %v, want %+v", tt.name, p, err, tt.want)
+		}
+		f.expect(t, "C2\r", "C2\r")
+	}
+}
+
+func TestGS232Commands(t *testing.T) {
+	f, port := newFakeController(t, splitAfter('\r'), func(cmd string) string {
+		if cmd == "C2\r" {
+			return "AZ=123  EL=045\r"
+		}
+		return ""
+	})
+	m, err := NewGS232(port, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.expect(t, "C2\r")
+
+	steps := []struct {
+		name   string
+		do     func() error
+		expect []string
+	}{
+		{"goto", func() error { return m.Goto(Position{Az: -10.4, El: 45.6}) }, []string{"W350 046\r"}},
+		{"goto north", func() error { return m.Goto(Position{Az: 359.7, El: 200}) }, []string{"W000 180\r"}},
+		// The azimuth speed follows the rate, the elevation only has a direction.
+		{"move", func() error { return m.Move(2, 0.5) }, []string{"X2\r", "R\r", "U\r"}},
+		{"same move", func() error { return m.Move(2, 0.7) }, nil},
+		{"elevation stops", func() error { return m.Move(1.5, 0) }, []string{"E\r"}},
+		{"faster left", func() error { return m.Move(-5, 0) }, []string{"X4\r", "L\r"}},
+		{"slowest", func() error { return m.Move(-0.1, -1) }, []string{"X1\r", "D\r"}},
+		{"azimuth stops", func() error { return m.Move(0, -1) }, []string{"A\r"}},
+		{"stop", func() error { return m.Move(0, 0) }, []string{"S\r"}},
+	}
+	for _, s := range steps {
+		if err := s.do(); err != nil {
+			t.Errorf("%s: %v", s.name, err)
+		}
+		f.expect(t, s.expect...)
+	}
+}
+
+func TestGS232EmulatedMove(t *testing.T) {
+	f, port := newFakeController(t, splitAfter('\r'), func(cmd string) string {
+		if cmd == "C2\r" {
+			return "+0123+0045\r"
+		}
+		return ""
+	})
+	m, err := NewGS232(port, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.expect(t, "C2\r")
+
+	if err := m.Move(2, -1); err != nil {
+		t.Fatal(err)
+	}
+	f.expect(t, "C2\r", "W125 044\r")
+}
+
+// A rotator which arrives at the slew target at once, and reports its position in whole degrees.
+type gs232Sim struct {
+	mu     sync.Mutex
+	az, el int
+}
+
+func (s *gs232Sim) reply(cmd string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cmd == "C2\r" {
+		return fmt.Sprintf("+0%03d+0%03d\r", s.az, s.el)
+	}
+	fmt.Sscanf(cmd, "W%d %d\r", &s.az, &s.el)
+	return ""
+}
+
+func (s *gs232Sim) position() Position {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return Position{Az: float64(s.az), El: float64(s.el)}
+}
+
+// Rates below half a degree per second move the rotator too, although the commanded position would round back
+// to the reported one.
+func TestGS232SlowEmulatedMove(t *testing.T) {
+	sim := &gs232Sim{az: 123, el: 45}
+	f, port := newFakeController(t, splitAfter('\r'), sim.reply)
+	m, err := NewGS232(port, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2021, 3, 1, 20, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+
+	start := now
+	move := func(azRate, elRate float64, d time.Duration) {
+		t.Helper()
+		for end := now.Add(d); !now.After(end); now = now.Add(200 * time.Millisecond) {
+			if err := m.Move(azRate, elRate); err != nil {
+				t.Fatal(err)
+			}
+			// Only the position of the rotator is checked, not the commands.
+			for len(f.commands) > 0 {
+				<-f.commands
+			}
+		}
+	}
+	move(0.3, -0.2, 10*time.Second)
+	// The target is one lead ahead.
+	want := Position{Az: math.Round(123 + 0.3*11), El: math.Round(45 - 0.2*11)}
+	if p := sim.position(); p != want {
+		t.Errorf("position %+v after %v, want %+v", p, now.Sub(start), want)
+	}
+
+	// The reference restarts from the position of a goto.
+	if err := m.Goto(Position{Az: 200, El: 10}); err != nil {
+		t.Fatal(err)
+	}
+	move(-0.4, 0, 5*time.Second)
+	if p, want := sim.position(), (Position{Az: math.Round(200 - 0.4*6), El: 10}); p != want {
+		t.Errorf("position %+v after goto and move, want %+v", p, want)
+	}
+}
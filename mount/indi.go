@@ -0,0 +1,133 @@
+package mount
+
+import (
+	"errors"
+	"time"
+
+	"github.com/nonoo/jampec/indi"
+)
+
+// The INDI standard properties used for alt-az mounts.
+const (
+	indiHorizontalCoord = "HORIZONTAL_COORD"
+	indiCoordSet        = "ON_COORD_SET"
+	indiAbortMotion     = "TELESCOPE_ABORT_MOTION"
+)
+
+// IndiRateLead is how far ahead the target of an emulated rate move is placed. INDI has no standard alt-az
+// rate command, so Move is emulated by continuously moving a goto target ahead of the mount.
+const IndiRateLead = time.Second
+
+// After the connection to the indiserver is lost, connecting is only tried again after this.
+const indiRedialInterval = time.Second
+
+type Indi struct {
+	connect func() (*indi.Client, error)
+	device  string
+
+	// Nil after the connection was lost.
+	client   *indi.Client
+	lastDial time.Time
+}
+
+// NewIndi returns a mount backed by the given INDI telescope device. The client is returned by connect,
+// which is called again when the connection is lost. It waits until the device has defined its horizontal
+// coordinates.
+func NewIndi(connect func() (*indi.Client, error), device string) (*Indi, error) {
+	m := &Indi{connect: connect, device: device}
+	if _, err := m.conn(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Returns the client, connecting again if the connection was lost.
+func (m *Indi) conn() (*indi.Client, error) {
+	if m.client != nil {
+		select {
+		case <-m.client.Done():
+			m.client = nil
+		default:
+			return m.client, nil
+		}
+	}
+	if time.Since(m.lastDial) < indiRedialInterval {
+		return nil, errors.New("indi: not connected")
+	}
+	m.lastDial = time.Now()
+
+	c, err := m.connect()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.GetProperties(m.device, ""); err != nil {
+		return nil, err
+	}
+	if _, err := c.WaitFor(m.device, indiHorizontalCoord, nil, 5*time.Second); err != nil {
+		return nil, err
+	}
+	m.client = c
+	return c, nil
+}
+
+func (m *Indi) Position() (Position, error) {
+	c, err := m.conn()
+	if err != nil {
+		return Position{}, err
+	}
+	az, err := c.Number(m.device, indiHorizontalCoord, "AZ")
+	if err != nil {
+		return Position{}, err
+	}
+	el, err := c.Number(m.device, indiHorizontalCoord, "ALT")
+	if err != nil {
+		return Position{}, err
+	}
+	return Position{Az: az, El: el}, nil
+}
+
+func (m *Indi) Goto(p Position) error {
+	c, err := m.conn()
+	if err != nil {
+		return err
+	}
+	if _, ok := c.Property(m.device, indiCoordSet); ok {
+		if err := c.SetSwitch(m.device, indiCoordSet, map[string]bool{"TRACK": true}); err != nil {
+			return err
+		}
+	}
+	return c.SetNumber(m.device, indiHorizontalCoord, map[string]float64{
+		"AZ":  NormalizeAz(p.Az),
+		"ALT": p.El,
+	})
+}
+
+func (m *Indi) Move(azRate, elRate float64) error {
+	if azRate == 0 && elRate == 0 {
+		return m.Stop()
+	}
+	p, err := m.Position()
+	if err != nil {
+		return err
+	}
+	lead := IndiRateLead.Seconds()
+	p.Az += azRate * lead
+	p.El += elRate * lead
+	return m.Goto(p)
+}
+
+func (m *Indi) Stop() error {
+	c, err := m.conn()
+	if err != nil {
+		return err
+	}
+	if _, ok := c.Property(m.device, indiAbortMotion); !ok {
+		return errors.New("indi: device has no abort motion property")
+	}
+	return c.SetSwitch(m.device, indiAbortMotion, map[string]bool{"ABORT": true})
+}
+
+// Close does nothing, the INDI connection is owned by the caller.
+func (m *Indi) Close() error {
+	return nil
+}
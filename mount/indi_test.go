@@ -0,0 +1,182 @@
+package mount
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nonoo/jampec/indi"
+)
+
+const fakeIndiDevice = "Telescope Simulator"
+
+// A fake indiserver on a local TCP port with an alt-az telescope which arrives at the goto target at once.
+type fakeIndiServer struct {
+	addr string
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]bool
+	az, alt  float64
+}
+
+func newFakeIndiServer(t *testing.T) *fakeIndiServer {
+	s := &fakeIndiServer{addr: "127.0.0.1:0", az: 100, alt: 20}
+	s.start(t)
+	t.Cleanup(s.kill)
+	return s
+}
+
+// Starts listening, on the same port after a restart.
+func (s *fakeIndiServer) start(t *testing.T) {
+	l, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.mu.Lock()
+	s.addr = l.Addr().String()
+	s.listener = l
+	s.conns = make(map[net.Conn]bool)
+	s.mu.Unlock()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns[conn] = true
+			s.mu.Unlock()
+			go s.serve(conn)
+		}
+	}()
+}
+
+// Closes the listener and all connections, like a stopped indiserver.
+func (s *fakeIndiServer) kill() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.listener.Close()
+	for c := range s.conns {
+		c.Close()
+	}
+}
+
+func (s *fakeIndiServer) serve(conn net.Conn) {
+	d := xml.NewDecoder(conn)
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch start.Name.Local {
+		case "getProperties":
+			s.mu.Lock()
+			msg := fmt.Sprintf(`<defNumberVector device="%s" name="HORIZONTAL_COORD" state="Idle" perm="rw">
+<defNumber name="AZ" min="0" max="360">%g</defNumber>
+<defNumber name="ALT" min="-90" max="90">%g</defNumber>
+</defNumberVector>
+`, fakeIndiDevice, s.az, s.alt)
+			s.mu.Unlock()
+			conn.Write([]byte(msg))
+		case "newNumberVector":
+			var v struct {
+				Numbers []struct {
+					Name  string `xml:"name,attr"`
+					Value string `xml:",chardata"`
+				} `xml:"oneNumber"`
+			}
+			if err := d.DecodeElement(&v, &start); err != nil {
+				return
+			}
+			s.mu.Lock()
+			for _, n := range v.Numbers {
+				f, _ := strconv.ParseFloat(n.Value, 64)
+				if n.Name == "AZ" {
+					s.az = f
+				} else if n.Name == "ALT" {
+					s.alt = f
+				}
+			}
+			msg := fmt.Sprintf(`<setNumberVector device="%s" name="HORIZONTAL_COORD" state="Ok">
+<oneNumber name="AZ">%g</oneNumber>
+<oneNumber name="ALT">%g</oneNumber>
+</setNumberVector>
+`, fakeIndiDevice, s.az, s.alt)
+			s.mu.Unlock()
+			conn.Write([]byte(msg))
+		}
+	}
+}
+
+// Polls the position like the mount loop until it's the wanted one.
+func waitForIndiPosition(t *testing.T, m *Indi, want Position, timeout time.Duration) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for {
+		p, err := m.Position()
+		if err == nil && p == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("position %+v, %v, want %+v", p, err, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestIndiReconnect(t *testing.T) {
+	s := newFakeIndiServer(t)
+	dials := 0
+	m, err := NewIndi(func() (*indi.Client, error) {
+		dials++
+		return indi.Dial(s.addr)
+	}, fakeIndiDevice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if m.client != nil {
+			m.client.Close()
+		}
+	}()
+	waitForIndiPosition(t, m, Position{Az: 100, El: 20}, time.Second)
+	if err := m.Goto(Position{Az: -10, El: 30}); err != nil {
+		t.Fatal(err)
+	}
+	waitForIndiPosition(t, m, Position{Az: 350, El: 30}, time.Second)
+
+	s.kill()
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, err := m.Position(); err != nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("no error after the server was stopped")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := m.Goto(Position{Az: 10, El: 10}); err == nil {
+		t.Error("goto succeeded while the server is stopped")
+	}
+
+	// The mount connects again after the server is restarted.
+	s.start(t)
+	waitForIndiPosition(t, m, Position{Az: 350, El: 30}, 3*indiRedialInterval)
+	if err := m.Goto(Position{Az: 20, El: 40}); err != nil {
+		t.Fatal(err)
+	}
+	waitForIndiPosition(t, m, Position{Az: 20, El: 40}, time.Second)
+	if dials < 2 {
+		t.Errorf("dialed %d times", dials)
+	}
+}
@@ -0,0 +1,99 @@
+package mount
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Port is a serial port, or a TCP connection to a serial bridge.
+type Port interface {
+	io.ReadWriteCloser
+	SetDeadline(t time.Time) error
+}
+
+// Timeout of a response from a serial controller.
+const lineTimeout = 2 * time.Second
+
+// Sends commands to a controller which answers with lines ending with delim.
+type linePort struct {
+	port   Port
+	reader *bufio.Reader
+	delim  byte
+	// Only one command can be in flight.
+	mu sync.Mutex
+}
+
+func newLinePort(port Port, delim byte) *linePort {
+	return &linePort{port: port, reader: bufio.NewReader(port), delim: delim}
+}
+
+// Sends the command without waiting for a response.
+func (l *linePort) send(cmd string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.write(cmd)
+}
+
+// Sends the command and returns the response line without the delimiter and surrounding whitespace.
+// Empty lines are skipped.
+func (l *linePort) query(cmd string) (string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.write(cmd); err != nil {
+		return "", err
+	}
+	for {
+		resp, err := l.reader.ReadString(l.delim)
+		if err != nil {
+			return "", err
+		}
+		if resp = strings.TrimSpace(resp); resp != "" {
+			return resp, nil
+		}
+	}
+}
+
+// Sends the command and returns the response up to the delimiter as it is, which may be empty.
+func (l *linePort) queryRaw(cmd string) (string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.write(cmd); err != nil {
+		return "", err
+	}
+	resp, err := l.reader.ReadString(l.delim)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(resp, string(l.delim)), nil
+}
+
+// Sends the command and returns the single byte response.
+func (l *linePort) queryByte(cmd string) (byte, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := l.write(cmd); err != nil {
+		return 0, err
+	}
+	return l.reader.ReadByte()
+}
+
+// Sets the deadline of the command and writes it. Leftovers of earlier commands are dropped.
+func (l *linePort) write(cmd string) error {
+	if err := l.port.SetDeadline(time.Now().Add(lineTimeout)); err != nil {
+		return err
+	}
+	l.reader.Discard(l.reader.Buffered())
+	_, err := io.WriteString(l.port, cmd)
+	return err
+}
+
+func (l *linePort) close() error {
+	return l.port.Close()
+}
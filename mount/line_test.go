@@ -0,0 +1,87 @@
+package mount
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nonoo/jampec/serial"
+)
+
+// A fake serial controller on the master side of a pseudo terminal. The mount opens the slave side as its
+// serial port. The received bytes are split to commands by the split function of the protocol, the
+// commands are recorded, and answered with the reply of the test.
+type fakeController struct {
+	pty      *os.File
+	commands chan string
+}
+
+func newFakeController(t *testing.T, split bufio.SplitFunc, reply func(cmd string) string) (*fakeController,
+	Port) {
+
+	t.Helper()
+	pty, slave, err := openPty()
+	if err != nil {
+		t.Skip("can't open a pseudo terminal: ", err)
+	}
+	port, err := serial.Open(slave, 9600)
+	if err != nil {
+		pty.Close()
+		t.Fatal(err)
+	}
+	f := &fakeController{pty: pty, commands: make(chan string, 100)}
+	t.Cleanup(func() {
+		port.Close()
+		pty.Close()
+	})
+	go f.serve(split, reply)
+	return f, port
+}
+
+func (f *fakeController) serve(split bufio.SplitFunc, reply func(cmd string) string) {
+	s := bufio.NewScanner(f.pty)
+	s.Split(split)
+	for s.Scan() {
+		cmd := s.Text()
+		f.commands <- cmd
+		if r := reply(cmd); r != "" {
+			if _, err := io.WriteString(f.pty, r); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Returns a split function for commands ending with delim, which is kept.
+func splitAfter(delim byte) bufio.SplitFunc {
+	return func(data []byte, atEOF bool) (int, []byte, error) {
+		if i := bytes.IndexByte(data, delim); i >= 0 {
+			return i + 1, data[:i+1], nil
+		}
+		return 0, nil, nil
+	}
+}
+
+// Checks that the given commands were received since the last call, in order.
+func (f *fakeController) expect(t *testing.T, cmds ...string) {
+	t.Helper()
+	for _, want := range cmds {
+		select {
+		case got := <-f.commands:
+			if got != want {
+				t.Errorf("received %q, want %q", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("%q not received", want)
+			return
+		}
+	}
+	select {
+	case got := <-f.commands:
+		t.Errorf("unexpected command %q", got)
+	default:
+	}
+}
@@ -0,0 +1,194 @@
+package mount
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+)
+
+var lx200Number = regexp.MustCompile(`\d+`)
+
+// LX200 drives an alt-az goto mount with the Meade LX200 command set. Rate moves use the :RA and :RE
+// commands of the LX200GPS and Autostar II, which set the axis rates in degrees per second. Other mounts
+// don't have them, so their axes are only started and stopped with :Mn/:Ms/:Me/:Mw at the slew rate
+// selected on the hand controller.
+type LX200 struct {
+	port *linePort
+	// The axis rates can be set with :RA and :RE.
+	rates bool
+	// Angles are sent and received with arcsecond precision.
+	highPrecision bool
+
+	// The last sent directions (-1, 0 or 1) and rates. Commands are only sent on changes.
+	azDir  int
+	elDir  int
+	azRate float64
+	elRate float64
+}
+
+// NewLX200 returns a mount which sends the LX200 commands to port. If rates is false, the mount doesn't
+// have the :RA and :RE commands.
+func NewLX200(port Port, rates bool) (*LX200, error) {
+	m := &LX200{port: newLinePort(port, '#'), rates: rates}
+	// The mount answers with minutes in low precision mode. :U# toggles the precision, so it's only sent
+	// then.
+	resp, err := m.port.queryRaw(":GZ#")
+	if err != nil {
+		return nil, err
+	}
+	if len(lx200Number.FindAllString(resp, -1)) == 2 {
+		if err := m.port.send(":U#"); err != nil {
+			return nil, err
+		}
+		if resp, err = m.port.queryRaw(":GZ#"); err != nil {
+			return nil, err
+		}
+	}
+	m.highPrecision = len(lx200Number.FindAllString(resp, -1)) == 3
+	// Checking if the mount answers.
+	if _, err := m.Position(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *LX200) Position() (Position, error) {
+	resp, err := m.port.queryRaw(":GZ#")
+	if err != nil {
+		return Position{}, err
+	}
+	az, err := parseLX200Angle(resp)
+	if err != nil {
+		return Position{}, err
+	}
+	resp, err = m.port.queryRaw(":GA#")
+	if err != nil {
+		return Position{}, err
+	}
+	el, err := parseLX200Angle(resp)
+	if err != nil {
+		return Position{}, err
+	}
+	return Position{Az: az, El: el}, nil
+}
+
+// Parses sDD*MM, sDD*MM'SS or DDD*MM'SS, where the separators can be any non-digit characters.
+func parseLX200Angle(s string) (float64, error) {
+	n := lx200Number.FindAllString(s, -1)
+	if len(n) < 2 || len(n) > 3 {
+		return 0, fmt.Errorf("lx200: invalid angle %q", s)
+	}
+	var v float64
+	for i, f := range n {
+		d, err := strconv.Atoi(f)
+		if err != nil {
+			return 0, err
+		}
+		v += float64(d) / math.Pow(60, float64(i))
+	}
+	if len(s) > 0 && s[0] == '-' {
+		v = -v
+	}
+	return v, nil
+}
+
+// Formats the angle in degrees and minutes, rounded to a minute, or with seconds, rounded to a second.
+func formatLX200Angle(v float64, degDigits int, signed, seconds bool) string {
+	sign := ""
+	if signed {
+		sign = "+"
+		if v < 0 {
+			sign = "-"
+		}
+	}
+	if seconds {
+		s := int(math.Round(math.Abs(v) * 3600))
+		return fmt.Sprintf("%s%0*d*%02d:%02d", sign, degDigits, s/3600, s/60%60, s%60)
+	}
+	minutes := int(math.Round(math.Abs(v) * 60))
+	return fmt.Sprintf("%s%0*d*%02d", sign, degDigits, minutes/60, minutes%60)
+}
+
+func (m *LX200) Goto(p Position) error {
+	// The azimuth is rounded first, so it doesn't become 360.
+	unit := 60.0
+	if m.highPrecision {
+		unit = 3600
+	}
+	az := math.Mod(math.Round(NormalizeAz(p.Az)*unit), 360*unit) / unit
+	cmds := []string{
+		":Sz" + formatLX200Angle(az, 3, false, m.highPrecision) + "#",
+		":Sa" + formatLX200Angle(p.El, 2, true, m.highPrecision) + "#",
+	}
+	for _, c := range cmds {
+		r, err := m.port.queryByte(c)
+		if err != nil {
+			return err
+		}
+		if r != '1' {
+			return fmt.Errorf("lx200: %s rejected", c)
+		}
+	}
+	r, err := m.port.queryByte(":MA#")
+	if err != nil {
+		return err
+	}
+	if r != '0' {
+		return fmt.Errorf("lx200: slew rejected")
+	}
+	m.azDir, m.elDir = 0, 0
+	return nil
+}
+
+func (m *LX200) Move(azRate, elRate float64) error {
+	if azRate == 0 && elRate == 0 {
+		return m.Stop()
+	}
+	var err error
+	// West turns the azimuth clockwise, the way objects move over the southern sky.
+	m.azDir, m.azRate, err = m.moveAxis("A", "w", "e", m.azDir, m.azRate, azRate)
+	if err != nil {
+		return err
+	}
+	m.elDir, m.elRate, err = m.moveAxis("E", "n", "s", m.elDir, m.elRate, elRate)
+	return err
+}
+
+// Sets the rate of an axis and starts or stops it. Returns the new direction and rate.
+func (m *LX200) moveAxis(axis, pos, neg string, dir int, rate, newRate float64) (int, float64, error) {
+	newDir := sign(newRate)
+	newRate = math.Abs(newRate)
+	if m.rates && newDir != 0 && newRate != rate {
+		if err := m.port.send(fmt.Sprintf(":R%s%.4f#", axis, newRate)); err != nil {
+			return dir, rate, err
+		}
+		rate = newRate
+	}
+	if newDir == dir {
+		return dir, rate, nil
+	}
+	var cmd string
+	if dir != 0 {
+		cmd = ":Q" + pos + "#:Q" + neg + "#"
+	}
+	switch newDir {
+	case 1:
+		cmd += ":M" + pos + "#"
+	case -1:
+		cmd += ":M" + neg + "#"
+	}
+	if err := m.port.send(cmd); err != nil {
+		return dir, rate, err
+	}
+	return newDir, rate, nil
+}
+
+func (m *LX200) Stop() error {
+	m.azDir, m.elDir = 0, 0
+	return m.port.send(":Q#")
+}
+
+func (m *LX200) Close() error {
+	return m.port.close()
+}
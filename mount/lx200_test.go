@@ -0,0 +1,208 @@
+package mount
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// A simulated alt-az LX200 mount. Every azimuth read advances the moving axes by a second.
+type lx200Sim struct {
+	az, el        float64
+	highPrecision bool
+	// Doesn't have the :U# command.
+	lowPrecisionOnly bool
+	// Axis rates in degrees per second, set by :RA and :RE, or selected on the hand controller.
+	azRate, elRate     float64
+	azDir, elDir       float64
+	targetAz, targetEl float64
+}
+
+func newLX200Sim() *lx200Sim {
+	return &lx200Sim{az: 10, el: 20, azRate: 1, elRate: 1}
+}
+
+func (s *lx200Sim) reply(cmd string) string {
+	arg := ""
+	if len(cmd) > 3 {
+		arg = strings.TrimSuffix(cmd[3:], "#")
+	}
+	switch {
+	case cmd == ":GZ#":
+		s.az = NormalizeAz(s.az + s.azDir*s.azRate)
+		s.el += s.elDir * s.elRate
+		return s.format(s.az, "%03d") + "#"
+	case cmd == ":GA#":
+		sign := "+"
+		if s.el < 0 {
+			sign = "-"
+		}
+		return sign + s.format(math.Abs(s.el), "%02d") + "#"
+	case cmd == ":U#":
+		s.highPrecision = !s.highPrecision && !s.lowPrecisionOnly
+	case strings.HasPrefix(cmd, ":Sz"), strings.HasPrefix(cmd, ":Sa"):
+		v, err := parseLX200Angle(strings.Replace(arg, ":", "'", 1))
+		if err != nil || strings.Contains(arg, ":") != s.highPrecision {
+			return "0"
+		}
+		if cmd[2] == 'z' {
+			s.targetAz = v
+		} else {
+			s.targetEl = v
+		}
+		return "1"
+	case cmd == ":MA#":
+		s.az, s.el = s.targetAz, s.targetEl
+		return "0"
+	case strings.HasPrefix(cmd, ":RA"), strings.HasPrefix(cmd, ":RE"):
+		v, err := strconv.ParseFloat(arg, 64)
+		if err == nil && cmd[2] == 'A' {
+			s.azRate = v
+		} else if err == nil {
+			s.elRate = v
+		}
+	case cmd == ":Mw#":
+		s.azDir = 1
+	case cmd == ":Me#":
+		s.azDir = -1
+	case cmd == ":Mn#":
+		s.elDir = 1
+	case cmd == ":Ms#":
+		s.elDir = -1
+	case cmd == ":Qw#", cmd == ":Qe#":
+		s.azDir = 0
+	case cmd == ":Qn#", cmd == ":Qs#":
+		s.elDir = 0
+	case cmd == ":Q#":
+		s.azDir, s.elDir = 0, 0
+	}
+	return ""
+}
+
+func (s *lx200Sim) format(v float64, degFormat string) string {
+	if s.highPrecision {
+		sec := int(math.Round(v * 3600))
+		return fmt.Sprintf(degFormat+"*%02d'%02d", sec/3600, sec/60%60, sec%60)
+	}
+	minutes := int(math.Round(v * 60))
+	return fmt.Sprintf(degFormat+"*%02d", minutes/60, minutes%60)
+}
+
+func checkPosition(t *testing.T, m Mount, want Position, tolerance float64) {
+	t.Helper()
+	p, err := m.Position()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(azDiff(p.Az, want.Az)) > tolerance || math.Abs(p.El-want.El) > tolerance {
+		t.Errorf("position %+v, want %+v", p, want)
+	}
+}
+
+func azDiff(a, b float64) float64 {
+	return math.Remainder(a-b, 360)
+}
+
+func TestLX200Precision(t *testing.T) {
+	sim := newLX200Sim()
+	f, port := newFakeController(t, splitAfter('#'), sim.reply)
+	m, err := NewLX200(port, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.expect(t, ":GZ#", ":U#", ":GZ#", ":GZ#", ":GA#")
+
+	if err := m.Goto(Position{Az: 123.4567, El: -12.3456}); err != nil {
+		t.Fatal(err)
+	}
+	f.expect(t, ":Sz123*27:24#", ":Sa-12*20:44#", ":MA#")
+	checkPosition(t, m, Position{Az: 123.4567, El: -12.3456}, 1.0/3600)
+	f.expect(t, ":GZ#", ":GA#")
+
+	if err := m.Goto(Position{Az: -0.0001, El: 45}); err != nil {
+		t.Fatal(err)
+	}
+	f.expect(t, ":Sz000*00:00#", ":Sa+45*00:00#", ":MA#")
+}
+
+func TestLX200LowPrecision(t *testing.T) {
+	sim := newLX200Sim()
+	sim.lowPrecisionOnly = true
+	f, port := newFakeController(t, splitAfter('#'), sim.reply)
+	m, err := NewLX200(port, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.expect(t, ":GZ#", ":U#", ":GZ#", ":GZ#", ":GA#")
+
+	if err := m.Goto(Position{Az: 359.995, El: 12.3456}); err != nil {
+		t.Fatal(err)
+	}
+	f.expect(t, ":Sz000*00#", ":Sa+12*21#", ":MA#")
+	checkPosition(t, m, Position{Az: 0, El: 12.35}, 1.0/60)
+}
+
+func TestLX200Move(t *testing.T) {
+	sim := newLX200Sim()
+	sim.highPrecision = true
+	f, port := newFakeController(t, splitAfter('#'), sim.reply)
+	m, err := NewLX200(port, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.expect(t, ":GZ#", ":GZ#", ":GA#")
+
+	// Positive azimuth rates turn the mount west, clockwise.
+	if err := m.Move(0.5, -0.25); err != nil {
+		t.Fatal(err)
+	}
+	f.expect(t, ":RA0.5000#", ":Mw#", ":RE0.2500#", ":Ms#")
+	checkPosition(t, m, Position{Az: 10.5, El: 19.75}, 1.0/3600)
+	f.expect(t, ":GZ#", ":GA#")
+
+	if err := m.Move(-0.5, -0.125); err != nil {
+		t.Fatal(err)
+	}
+	f.expect(t, ":Qw#", ":Qe#", ":Me#", ":RE0.1250#")
+	checkPosition(t, m, Position{Az: 10, El: 19.625}, 1.0/3600)
+	f.expect(t, ":GZ#", ":GA#")
+
+	if err := m.Move(-1, 0); err != nil {
+		t.Fatal(err)
+	}
+	f.expect(t, ":RA1.0000#", ":Qn#", ":Qs#")
+	checkPosition(t, m, Position{Az: 9, El: 19.625}, 1.0/3600)
+	f.expect(t, ":GZ#", ":GA#")
+
+	if err := m.Move(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	f.expect(t, ":Q#")
+	checkPosition(t, m, Position{Az: 9, El: 19.625}, 1.0/3600)
+}
+
+func TestLX200MoveWithoutRates(t *testing.T) {
+	sim := newLX200Sim()
+	sim.highPrecision = true
+	f, port := newFakeController(t, splitAfter('#'), sim.reply)
+	m, err := NewLX200(port, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.expect(t, ":GZ#", ":GZ#", ":GA#")
+
+	// The axes turn at the hand controller's rate.
+	if err := m.Move(-0.1, 0.2); err != nil {
+		t.Fatal(err)
+	}
+	f.expect(t, ":Me#", ":Mn#")
+	checkPosition(t, m, Position{Az: 9, El: 21}, 1.0/3600)
+	f.expect(t, ":GZ#", ":GA#")
+
+	if err := m.Move(-0.3, 0.4); err != nil {
+		t.Fatal(err)
+	}
+	f.expect(t)
+}
@@ -0,0 +1,30 @@
+// Package mount contains the backends jampec can use to point the optics.
+package mount
+
+import "math"
+
+// Position is an azimuth/elevation pair in degrees.
+type Position struct {
+	Az float64 `json:"az"`
+	El float64 `json:"el"`
+}
+
+type Mount interface {
+	Position() (Position, error)
+	// Goto starts a slew to the given position and returns without waiting for it to finish.
+	Goto(p Position) error
+	// Move sets the axis rates in degrees per second. Zero rates stop the axes.
+	Move(azRate, elRate float64) error
+	// Stop aborts all motion.
+	Stop() error
+	Close() error
+}
+
+// NormalizeAz returns az in the [0, 360) range.
+func NormalizeAz(az float64) float64 {
+	az = math.Mod(az, 360)
+	if az < 0 {
+		az += 360
+	}
+	return az
+}
@@ -0,0 +1,129 @@
+package mount
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Motor controller device ids and commands of the NexStar pass-through command.
+const (
+	nexStarAzmDevice    = 16
+	nexStarAltDevice    = 17
+	nexStarRatePositive = 6
+	nexStarRateNegative = 7
+	// The variable rate is sent in quarter arcseconds per second, in 16 bits.
+	nexStarMaxRate = 0xffff / 4.0 / 3600
+)
+
+// NexStar drives a Celestron mount with the NexStar hand controller protocol. The mount has to be aligned
+// in alt-az mode.
+type NexStar struct {
+	port *linePort
+
+	// The last sent axis rates. Commands are only sent on changes.
+	azRate float64
+	elRate float64
+}
+
+// NewNexStar returns a mount which sends the NexStar commands to port.
+func NewNexStar(port Port) (*NexStar, error) {
+	m := &NexStar{port: newLinePort(port, '#')}
+	// Checking if the hand controller answers the echo command.
+	resp, err := m.port.queryRaw("Kx")
+	if err != nil {
+		return nil, err
+	}
+	if resp != "x" {
+		return nil, fmt.Errorf("nexstar: invalid echo %q", resp)
+	}
+	return m, nil
+}
+
+// Positions are sent as 32 bit fractions of a revolution.
+func (m *NexStar) Position() (Position, error) {
+	resp, err := m.port.queryRaw("z")
+	if err != nil {
+		return Position{}, err
+	}
+	f := strings.Split(resp, ",")
+	if len(f) != 2 {
+		return Position{}, fmt.Errorf("nexstar: invalid position %q", resp)
+	}
+	azm, err := strconv.ParseUint(f[0], 16, 32)
+	if err != nil {
+		return Position{}, fmt.Errorf("nexstar: invalid position %q", resp)
+	}
+	alt, err := strconv.ParseUint(f[1], 16, 32)
+	if err != nil {
+		return Position{}, fmt.Errorf("nexstar: invalid position %q", resp)
+	}
+	return Position{Az: nexStarAngle(azm), El: math.Remainder(nexStarAngle(alt), 360)}, nil
+}
+
+func nexStarAngle(v uint64) float64 {
+	return float64(v) / (1 << 32) * 360
+}
+
+func nexStarPosition(deg float64) uint32 {
+	return uint32(math.Round(NormalizeAz(deg)/360*(1<<32))) & 0xffffff00
+}
+
+func (m *NexStar) Goto(p Position) error {
+	m.azRate, m.elRate = 0, 0
+	return m.command(fmt.Sprintf("b%08X,%08X", nexStarPosition(p.Az), nexStarPosition(p.El)))
+}
+
+func (m *NexStar) Move(azRate, elRate float64) error {
+	if azRate != m.azRate {
+		if err := m.setRate(nexStarAzmDevice, azRate); err != nil {
+			return err
+		}
+		m.azRate = azRate
+	}
+	if elRate != m.elRate {
+		if err := m.setRate(nexStarAltDevice, elRate); err != nil {
+			return err
+		}
+		m.elRate = elRate
+	}
+	return nil
+}
+
+// Sets the variable rate of an axis in degrees per second.
+func (m *NexStar) setRate(device byte, rate float64) error {
+	dir := byte(nexStarRatePositive)
+	if rate < 0 {
+		dir = nexStarRateNegative
+	}
+	r := int(math.Round(math.Min(math.Abs(rate), nexStarMaxRate) * 3600 * 4))
+	return m.command(string([]byte{'P', 3, device, dir, byte(r >> 8), byte(r), 0, 0}))
+}
+
+func (m *NexStar) Stop() error {
+	m.azRate, m.elRate = 0, 0
+	for _, d := range []byte{nexStarAzmDevice, nexStarAltDevice} {
+		if err := m.setRate(d, 0); err != nil {
+			return err
+		}
+	}
+	// Cancels a running goto.
+	return m.command("M")
+}
+
+// Sends a command which is answered with a single #.
+func (m *NexStar) command(cmd string) error {
+	resp, err := m.port.queryRaw(cmd)
+	if err != nil {
+		return err
+	}
+	if resp != "" {
+		return fmt.Errorf("nexstar: unexpected response %q", resp)
+	}
+	return nil
+}
+
+func (m *NexStar) Close() error {
+	return m.port.close()
+}
@@ -0,0 +1,109 @@
+package mount
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+)
+
+// A simulated NexStar hand controller of an alt-az mount. Every position read advances the axes by a
+// second.
+type nexStarSim struct {
+	az, el         float64
+	azRate, elRate float64
+}
+
+func (s *nexStarSim) reply(cmd string) string {
+	switch {
+	case cmd == "Kx":
+		return "x#"
+	case cmd == "z":
+		s.az += s.azRate
+		s.el += s.elRate
+		return fmt.Sprintf("%08X,%08X#", nexStarPosition(s.az), nexStarPosition(s.el))
+	case len(cmd) == 18 && cmd[0] == 'b' && cmd[9] == ',':
+		az, err1 := strconv.ParseUint(cmd[1:9], 16, 32)
+		el, err2 := strconv.ParseUint(cmd[10:], 16, 32)
+		if err1 != nil || err2 != nil {
+			return ""
+		}
+		s.az, s.el = nexStarAngle(az), nexStarAngle(el)
+		return "#"
+	case len(cmd) == 8 && cmd[0] == 'P' && cmd[1] == 3:
+		rate := float64(int(cmd[4])<<8|int(cmd[5])) / 4 / 3600
+		if cmd[3] == nexStarRateNegative {
+			rate = -rate
+		}
+		if cmd[2] == nexStarAzmDevice {
+			s.azRate = rate
+		} else {
+			s.elRate = rate
+		}
+		return "#"
+	case cmd == "M":
+		return "#"
+	}
+	return ""
+}
+
+// The commands have a fixed length by their first byte.
+func splitNexStar(data []byte, atEOF bool) (int, []byte, error) {
+	if len(data) == 0 {
+		return 0, nil, nil
+	}
+	n, ok := map[byte]int{'K': 2, 'z': 1, 'b': 18, 'P': 8, 'M': 1}[data[0]]
+	if !ok {
+		n = 1
+	}
+	if len(data) < n {
+		return 0, nil, nil
+	}
+	return n, data[:n], nil
+}
+
+func TestNexStar(t *testing.T) {
+	sim := &nexStarSim{}
+	f, port := newFakeController(t, splitNexStar, sim.reply)
+	m, err := NewNexStar(port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.expect(t, "Kx")
+
+	// Negative angles are sent as fractions of a revolution above 180 degrees.
+	if err := m.Goto(Position{Az: 90, El: -5}); err != nil {
+		t.Fatal(err)
+	}
+	f.expect(t, "b40000000,FC71C700")
+	checkPosition(t, m, Position{Az: 90, El: -5}, 1e-4)
+	f.expect(t, "z")
+
+	steps := []struct {
+		name   string
+		azRate float64
+		elRate float64
+		expect []string
+		pos    Position
+	}{
+		{"move", 1, -0.5, []string{"P\x03\x10\x06\x38\x40\x00\x00", "P\x03\x11\x07\x1c\x20\x00\x00"},
+			Position{Az: 91, El: -5.5}},
+		{"same elevation rate", -1, -0.5, []string{"P\x03\x10\x07\x38\x40\x00\x00"}, Position{Az: 90, El: -6}},
+		{"clamped", 10, 0, []string{"P\x03\x10\x06\xff\xff\x00\x00", "P\x03\x11\x06\x00\x00\x00\x00"},
+			Position{Az: 90 + nexStarMaxRate, El: -6}},
+	}
+	for _, s := range steps {
+		if err := m.Move(s.azRate, s.elRate); err != nil {
+			t.Fatal(err)
+		}
+		f.expect(t, s.expect...)
+		checkPosition(t, m, s.pos, 1e-4)
+		f.expect(t, "z")
+	}
+
+	if err := m.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	f.expect(t, "P\x03\x10\x06\x00\x00\x00\x00", "P\x03\x11\x06\x00\x00\x00\x00", "M")
+	// Stopped, so the position doesn't change anymore.
+	checkPosition(t, m, Position{Az: 90 + nexStarMaxRate, El: -6}, 1e-4)
+}
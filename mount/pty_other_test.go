@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package mount
+
+import (
+	"errors"
+	"os"
+)
+
+// Serial ports are only implemented on Linux.
+func openPty() (*os.File, string, error) {
+	return nil, "", errors.New("not supported on this platform")
+}
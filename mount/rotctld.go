@@ -0,0 +1,127 @@
+package mount
+
+import (
+	"math"
+	"time"
+
+	"github.com/nonoo/jampec/rotctld"
+)
+
+// RotctldRateLead is how far ahead the target of an emulated rate move is placed, like IndiRateLead.
+const RotctldRateLead = time.Second
+
+// The move command turns both axes with the same speed on diagonals. If the slower axis' rate is below
+// rotctldMinorAxisRatio of the faster one's, only the faster axis is moved, and the next correction takes
+// care of the slower one. Diagonals are used if the ratio is at least rotctldDiagonalRatio. In between, the
+// move is emulated with a slew, so neither axis overshoots.
+const (
+	rotctldMinorAxisRatio = 0.2
+	rotctldDiagonalRatio  = 0.7
+)
+
+// Rotctld drives an az/el rotator through hamlib's rotctld.
+type Rotctld struct {
+	client *rotctld.Client
+	// Axis rate at the maximum move speed in degrees per second. If zero, moves are emulated with slews.
+	maxRate float64
+}
+
+// NewRotctld connects to the rotctld at addr. If maxRate is set, Move uses the move command with a speed
+// proportional to the faster axis' rate where it can, otherwise it's emulated by slewing ahead of the
+// rotator.
+func NewRotctld(addr string, maxRate float64) (*Rotctld, error) {
+	c, err := rotctld.Dial(addr)
+	if err != nil {
+		return nil, err
+	}
+	m := &Rotctld{client: c, maxRate: maxRate}
+	// Checking if the daemon answers.
+	if _, err := m.Position(); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *Rotctld) Position() (Position, error) {
+	az, el, err := m.client.Position()
+	if err != nil {
+		return Position{}, err
+	}
+	return Position{Az: az, El: el}, nil
+}
+
+func (m *Rotctld) Goto(p Position) error {
+	return m.client.SetPosition(NormalizeAz(p.Az), p.El)
+}
+
+func (m *Rotctld) Move(azRate, elRate float64) error {
+	if azRate == 0 && elRate == 0 {
+		return m.Stop()
+	}
+	if m.maxRate > 0 {
+		fast := math.Max(math.Abs(azRate), math.Abs(elRate))
+		ratio := math.Min(math.Abs(azRate), math.Abs(elRate)) / fast
+		switch {
+		case ratio < rotctldMinorAxisRatio:
+			if math.Abs(azRate) < math.Abs(elRate) {
+				azRate = 0
+			} else {
+				elRate = 0
+			}
+			return m.move(azRate, elRate, fast)
+		case ratio >= rotctldDiagonalRatio:
+			return m.move(azRate, elRate, fast)
+		}
+	}
+
+	p, err := m.Position()
+	if err != nil {
+		return err
+	}
+	lead := RotctldRateLead.Seconds()
+	p.Az += azRate * lead
+	p.El += elRate * lead
+	return m.Goto(p)
+}
+
+// Sends the move command with the direction of the rates, and the speed of the given rate.
+func (m *Rotctld) move(azRate, elRate, rate float64) error {
+	speed := int(math.Round(rate / m.maxRate * rotctld.MaxSpeed))
+	if speed < 1 {
+		speed = 1
+	} else if speed > rotctld.MaxSpeed {
+		speed = rotctld.MaxSpeed
+	}
+	return m.client.Move(moveDirection(azRate, elRate), speed)
+}
+
+// Returns the rotctld direction of the given rates, which are not both zero. The move command has no
+// separate axis speeds, so both axes move with the same speed on diagonals.
+func moveDirection(azRate, elRate float64) int {
+	switch {
+	case elRate > 0 && azRate < 0:
+		return rotctld.DirUpLeft
+	case elRate > 0 && azRate > 0:
+		return rotctld.DirUpRight
+	case elRate < 0 && azRate < 0:
+		return rotctld.DirDownLeft
+	case elRate < 0 && azRate > 0:
+		return rotctld.DirDownRight
+	case elRate > 0:
+		return rotctld.DirUp
+	case elRate < 0:
+		return rotctld.DirDown
+	case azRate < 0:
+		return rotctld.DirLeft
+	}
+	return rotctld.DirRight
+}
+
+func (m *Rotctld) Stop() error {
+	return m.client.Stop()
+}
+
+func (m *Rotctld) Close() error {
+	return m.client.Close()
+}
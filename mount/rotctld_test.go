@@ -0,0 +1,327 @@
+package mount
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nonoo/jampec/rotctld"
+)
+
+// Speed of the simulated rotator in degrees per second, which is also its speed at the maximum move speed.
+const fakeRotctldRate = 10
+
+// Limits of the simulated rotator in degrees.
+const (
+	fakeRotctldMaxAz = 360
+	fakeRotctldMaxEl = 90
+)
+
+// A simulated rotctld which records the received commands. The rotator turns toward the set_pos target with
+// fakeRotctldRate on both axes, or in the direction of the move command with its speed, and stops at the
+// limits. Time only passes when the test calls advance.
+type fakeRotctld struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	commands []string
+	az, el   float64
+	// The set_pos target, if slewing.
+	slewing            bool
+	targetAz, targetEl float64
+	// Rates of the move command in degrees per second.
+	azRate, elRate float64
+	// If not zero, every command fails with this hamlib error code.
+	errCode int
+}
+
+func newFakeRotctld(t *testing.T) *fakeRotctld {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &fakeRotctld{listener: l, az: 100, el: 20}
+	t.Cleanup(func() { l.Close() })
+	go f.serve()
+	return f
+}
+
+func (f *fakeRotctld) serve() {
+	conn, err := f.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.TrimPrefix(strings.TrimSpace(line), "+\\")
+		args := strings.Fields(cmd + " ")
+		if args[0] == "q" {
+			return
+		}
+		f.mu.Lock()
+		f.commands = append(f.commands, cmd)
+		resp, code := f.handle(args[0], args[1:])
+		f.mu.Unlock()
+
+		if _, err := fmt.Fprintf(conn, "%s:\n%sRPRT %d\n", args[0], resp, -code); err != nil {
+			return
+		}
+	}
+}
+
+// Executes a command, and returns the response values and the hamlib error code.
+func (f *fakeRotctld) handle(name string, args []string) (string, int) {
+	if f.errCode != 0 {
+		return "", f.errCode
+	}
+	switch name {
+	case "get_pos":
+		return fmt.Sprintf("Azimuth: %f\nElevation: %f\n", f.az, f.el), 0
+	case "set_pos":
+		if len(args) != 2 {
+			return "", rotctld.ErrInvalid
+		}
+		az, err1 := strconv.ParseFloat(args[0], 64)
+		el, err2 := strconv.ParseFloat(args[1], 64)
+		if err1 != nil || err2 != nil || az < 0 || az > fakeRotctldMaxAz || el < 0 || el > fakeRotctldMaxEl {
+			return "", rotctld.ErrInvalid
+		}
+		f.slewing = true
+		f.targetAz, f.targetEl = az, el
+		f.azRate, f.elRate = 0, 0
+	case "move":
+		if len(args) != 2 {
+			return "", rotctld.ErrInvalid
+		}
+		dir, err1 := strconv.Atoi(args[0])
+		speed, err2 := strconv.Atoi(args[1])
+		if err1 != nil || err2 != nil || speed < 1 || speed > rotctld.MaxSpeed {
+			return "", rotctld.ErrInvalid
+		}
+		d, ok := map[int][2]float64{
+			rotctld.DirUp: {0, 1}, rotctld.DirDown: {0, -1}, rotctld.DirLeft: {-1, 0},
+			rotctld.DirRight: {1, 0}, rotctld.DirUpLeft: {-1, 1}, rotctld.DirUpRight: {1, 1},
+			rotctld.DirDownLeft: {-1, -1}, rotctld.DirDownRight: {1, -1},
+		}[dir]
+		if !ok {
+			return "", rotctld.ErrInvalid
+		}
+		rate := float64(speed) / rotctld.MaxSpeed * fakeRotctldRate
+		f.slewing = false
+		f.azRate, f.elRate = d[0]*rate, d[1]*rate
+	case "stop":
+		f.slewing = false
+		f.azRate, f.elRate = 0, 0
+	default:
+		return "", rotctld.ErrNotImplemented
+	}
+	return "", 0
+}
+
+// Moves the rotator for the given time.
+func (f *fakeRotctld) advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	step := fakeRotctldRate * d.Seconds()
+	if f.slewing {
+		toward := func(v, target float64) float64 {
+			return v + math.Max(-step, math.Min(step, target-v))
+		}
+		f.az, f.el = toward(f.az, f.targetAz), toward(f.el, f.targetEl)
+		f.slewing = f.az != f.targetAz || f.el != f.targetEl
+		return
+	}
+	f.az = math.Max(0, math.Min(fakeRotctldMaxAz, f.az+f.azRate*d.Seconds()))
+	f.el = math.Max(0, math.Min(fakeRotctldMaxEl, f.el+f.elRate*d.Seconds()))
+}
+
+func (f *fakeRotctld) position() Position {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return Position{Az: f.az, El: f.el}
+}
+
+// Returns and clears the received commands.
+func (f *fakeRotctld) take() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c := f.commands
+	f.commands = nil
+	return c
+}
+
+func TestRotctldMove(t *testing.T) {
+	tests := []struct {
+		name    string
+		maxRate float64
+		azRate  float64
+		elRate  float64
+		want    []string
+	}{
+		{"stop", 10, 0, 0, []string{"stop"}},
+		{"az only", 10, -2, 0, []string{"move 8 20"}},
+		{"el only", 10, 0, 15, []string{"move 2 100"}},
+		{"similar rates move diagonally", 10, 5, 4, []string{"move 64 50"}},
+		{"similar rates down left", 10, -3, -2.5, []string{"move 128 30"}},
+		{"slower axis is left alone", 10, 5, -0.5, []string{"move 16 50"}},
+		{"slower az is left alone", 10, 0.1, 1, []string{"move 2 10"}},
+		// A diagonal move would turn the azimuth more than twice as fast as needed.
+		{"mixed rates are emulated", 10, -5, 2, []string{"get_pos", "set_pos 95.00 22.00"}},
+		{"no move command", 0, 1, 1, []string{"get_pos", "set_pos 101.00 21.00"}},
+		{"emulated el move", 0, 0, -0.5, []string{"get_pos", "set_pos 100.00 19.50"}},
+	}
+	for _, tt := range tests {
+		f := newFakeRotctld(t)
+		m, err := NewRotctld(f.listener.Addr().String(), tt.maxRate)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c := f.take(); len(c) != 1 || c[0] != "get_pos" {
+			t.Errorf("%s: commands of NewRotctld %q", tt.name, c)
+		}
+
+		if err := m.Move(tt.azRate, tt.elRate); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+		if got := f.take(); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("%s: sent %q, want %q", tt.name, got, tt.want)
+		}
+		m.Close()
+	}
+}
+
+func TestRotctldMoveSequence(t *testing.T) {
+	f := newFakeRotctld(t)
+	m, err := NewRotctld(f.listener.Addr().String(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	f.take()
+
+	// A correction where the elevation rate changes relative to the azimuth rate.
+	for _, r := range [][2]float64{{4, 4}, {4, 2}, {4, 0.5}, {0, 0}} {
+		if err := m.Move(r[0], r[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{"move 64 40", "get_pos", "set_pos 104.00 22.00", "move 16 40", "stop"}
+	if got := f.take(); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("sent %q, want %q", got, want)
+	}
+}
+
+// Follows a target moving with constant rates, like the mount loop: the rates are the target's rates with a
+// proportional correction, sent once per second. With move commands the slower axis is only corrected when
+// its error grows, so its error stays within a degree.
+func TestRotctldTracking(t *testing.T) {
+	tests := []struct {
+		name    string
+		maxRate float64
+		azRate  float64
+		elRate  float64
+		maxErr  float64
+		// The rotator is driven with move commands too, not only with slews.
+		moves bool
+	}{
+		{"emulated", 0, 2, -0.5, 0.05, false},
+		{"diagonal moves", 10, 2, 1.8, 1, true},
+		{"single axis moves", 10, -3, 0.2, 1, true},
+	}
+	for _, tt := range tests {
+		f := newFakeRotctld(t)
+		m, err := NewRotctld(f.listener.Addr().String(), tt.maxRate)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		target := Position{Az: 105, El: 23}
+		moves := false
+		for i := 0; i < 20; i++ {
+			p, err := m.Position()
+			if err != nil {
+				t.Fatal(err)
+			}
+			errAz, errEl := target.Az-p.Az, target.El-p.El
+			if i >= 10 && (math.Abs(errAz) > tt.maxErr || math.Abs(errEl) > tt.maxErr) {
+				t.Errorf("%s: tracking error %.2f, %.2f after %d polls", tt.name, errAz, errEl, i)
+			}
+			if err := m.Move(tt.azRate+0.5*errAz, tt.elRate+0.5*errEl); err != nil {
+				t.Fatal(err)
+			}
+			for _, c := range f.take() {
+				moves = moves || strings.HasPrefix(c, "move ")
+			}
+			f.advance(time.Second)
+			target.Az += tt.azRate
+			target.El += tt.elRate
+		}
+		if moves != tt.moves {
+			t.Errorf("%s: move commands used %v", tt.name, moves)
+		}
+		m.Close()
+	}
+}
+
+func TestRotctldErrors(t *testing.T) {
+	f := newFakeRotctld(t)
+	m, err := NewRotctld(f.listener.Addr().String(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	var rerr *rotctld.Error
+	if err := m.Goto(Position{Az: 10, El: 95}); !errors.As(err, &rerr) || rerr.Code != rotctld.ErrInvalid {
+		t.Errorf("goto beyond the limit: %v", err)
+	}
+	// Normalized to the range of the rotator.
+	if err := m.Goto(Position{Az: -10, El: 5}); err != nil {
+		t.Fatal(err)
+	}
+	f.advance(30 * time.Second)
+	if p := f.position(); p != (Position{Az: 350, El: 5}) {
+		t.Errorf("position %+v after goto", p)
+	}
+
+	// Moves stop at the limits.
+	if err := m.Move(0, -5); err != nil {
+		t.Fatal(err)
+	}
+	f.advance(2 * time.Second)
+	if p, err := m.Position(); err != nil || p.El != 0 {
+		t.Errorf("position %+v, %v after moving down", p, err)
+	}
+
+	// An emulated move below the limit is rejected.
+	m.maxRate = 0
+	if err := m.Move(0, -1); !errors.As(err, &rerr) || rerr.Code != rotctld.ErrInvalid {
+		t.Errorf("move beyond the limit: %v", err)
+	}
+
+	f.mu.Lock()
+	f.errCode = rotctld.ErrIO
+	f.mu.Unlock()
+	if _, err := m.Position(); !errors.As(err, &rerr) || rerr.Code != rotctld.ErrIO {
+		t.Errorf("position: %v", err)
+	}
+	if err := m.Move(1, 1); !errors.As(err, &rerr) || rerr.Code != rotctld.ErrIO {
+		t.Errorf("move: %v", err)
+	}
+	if err := m.Stop(); !errors.As(err, &rerr) || rerr.Code != rotctld.ErrIO {
+		t.Errorf("stop: %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/nonoo/jampec/astro"
+	"github.com/nonoo/jampec/sgp4"
+)
+
+type satPass struct {
+	SatNum int    `json:"satNum"`
+	Name   string `json:"name"`
+	astro.Pass
+}
+
+// Handles the "passes" command which lists the upcoming passes over the configured site, or the site given on
+// the command line.
+func runPasses(args []string) error {
+	fs := flag.NewFlagSet("passes", flag.ExitOnError)
+	tleFile := fs.String("tle", "", "TLE file to read, single element set or catalog (required)")
+	satNums := fs.String("sat", "", "comma separated catalog numbers to use from the TLE file, all if empty")
+	hours := fs.Float64("hours", 24, "length of the prediction window in hours")
+	minEl := fs.Float64("minel", 0, "minimum maximum elevation of the listed passes in degrees")
+	visibleOnly := fs.Bool("visible", false, "only list passes when the satellite is sunlit and the site is dark")
+	sunEl := fs.Float64("sunel", -6, "the Sun must be below this elevation for the site to be dark")
+	jsonOutput := fs.Bool("json", false, "print the passes as JSON")
+	lat := fs.Float64("lat", 0, "site latitude in degrees, overrides the config")
+	lon := fs.Float64("lon", 0, "site longitude in degrees, positive east, overrides the config")
+	alt := fs.Float64("alt", 0, "site altitude in meters, overrides the config")
+	_ = fs.Parse(args)
+
+	if *tleFile == "" {
+		fs.Usage()
+		return errors.New("no TLE file given")
+	}
+
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	// The config file is not needed if the site is given on the command line.
+	if err := loadConfig("config.json"); err != nil && (!errors.Is(err, os.ErrNotExist) || !set["lat"] || !set["lon"]) {
+		return err
+	}
+	if set["lat"] {
+		config.Site.Latitude = *lat
+	}
+	if set["lon"] {
+		config.Site.Longitude = *lon
+	}
+	if set["alt"] {
+		config.Site.Altitude = *alt
+	}
+	if config.Site.Latitude < -90 || config.Site.Latitude > 90 {
+		return errors.New("invalid site latitude")
+	}
+
+	tles, err := sgp4.LoadCatalog(*tleFile)
+	if err != nil {
+		return err
+	}
+	if *satNums != "" {
+		var selected []*sgp4.TLE
+		for _, s := range strings.Split(*satNums, ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil {
+				return fmt.Errorf("invalid catalog number %s", s)
+			}
+			tle, ok := sgp4.FindSatNum(tles, n)
+			if !ok {
+				return fmt.Errorf("satellite %d not found in %s", n, *tleFile)
+			}
+			selected = append(selected, tle)
+		}
+		tles = selected
+	}
+
+	start := time.Now().UTC().Truncate(time.Second)
+	end := start.Add(time.Duration(*hours * float64(time.Hour)))
+	opts := astro.PassOptions{MinElevation: *minEl, SunElevationLimit: *sunEl}
+
+	passes := predictSatPasses(tles, start, end, opts, *visibleOnly)
+
+	if *jsonOutput {
+		e := json.NewEncoder(os.Stdout)
+		e.SetIndent("", "\t")
+		return e.Encode(passes)
+	}
+
+	const timeFormat = "2006-01-02 15:04:05"
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SAT\tNAME\tAOS (UTC)\tAZ\tTCA (UTC)\tMAX EL\tAZ\tLOS (UTC)\tAZ\tSUNLIT\tVISIBLE")
+	for _, p := range passes {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%.1f\t%s\t%.1f\t%.1f\t%s\t%.1f\t%s\t%s\n", p.SatNum, p.Name,
+			p.AOS.Time.Format(timeFormat), p.AOS.Az,
+			p.TCA.Time.Format(timeFormat), p.TCA.El, p.TCA.Az,
+			p.LOS.Time.Format(timeFormat), p.LOS.Az,
+			yesNo(p.Sunlit), yesNo(p.Visible))
+	}
+	return w.Flush()
+}
+
+// Returns the passes of the satellites ordered by their AOS.
+func predictSatPasses(tles []*sgp4.TLE, start, end time.Time, opts astro.PassOptions, visibleOnly bool) []satPass {
+	passes := []satPass{}
+	for _, tle := range tles {
+		sat, err := sgp4.NewSatellite(tle)
+		if err != nil {
+			log.Error("can't initialize ", tle.SatNum, " ", tle.Name, ": ", err)
+			continue
+		}
+		satPasses, err := config.Site.PredictPasses(sat, start, end, opts)
+		if err != nil {
+			log.Error("can't predict passes of ", tle.SatNum, " ", tle.Name, ": ", err)
+			continue
+		}
+		for _, p := range satPasses {
+			if visibleOnly && !p.Visible {
+				continue
+			}
+			passes = append(passes, satPass{SatNum: tle.SatNum, Name: tle.Name, Pass: p})
+		}
+	}
+	sort.Slice(passes, func(i, j int) bool { return passes[i].AOS.Time.Before(passes[j].AOS.Time) })
+	return passes
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/nonoo/jampec/mount"
+	"github.com/nonoo/jampec/ser"
+	"gocv.io/x/gocv"
+)
+
+const recorderSidecarFilename = "frames.jsonl"
+
+func validRecorderFormat(format string) bool {
+	return format == "video" || format == "sequence" || format == "ser"
+}
+
+// A line of the sidecar file.
+type recordMeta struct {
+	Frame int       `json:"frame"`
+	Time  time.Time `json:"time"`
+	// Index of the frame in the video, or the file name of the frame in the sequence.
+	Index int    `json:"index"`
+	File  string `json:"file,omitempty"`
+
+	State  string          `json:"state"`
+	Rect   *[4]int         `json:"rect"`
+	Filter *recordFilter   `json:"filter"`
+	Mount  *mount.Position `json:"mount"`
+}
+
+type recordFilter struct {
+	X          float64     `json:"x"`
+	Y          float64     `json:"y"`
+	VX         float64     `json:"vx"`
+	VY         float64     `json:"vy"`
+	AX         float64     `json:"ax"`
+	AY         float64     `json:"ay"`
+	Covariance [][]float64 `json:"covariance"`
+	CoastTime  float64     `json:"coastTime"`
+}
+
+type recordedFrame struct {
+	img  *gocv.Mat
+	meta recordMeta
+}
+
+// Recorder writes the original frames of a camera and their metadata to a new directory. Frames are written
+// by a separate goroutine, and dropped if it can't keep up.
+type recorder struct {
+	nr     int
+	dir    string
+	format string
+	codec  string
+	fps    float64
+
+	frameChan chan recordedFrame
+	// Set by the writer goroutine on the first error, after which it drops all frames.
+	failed  int32
+	dropped int64
+	written int64
+
+	finishedChan chan bool
+}
+
+func startRecorder(config DevConfig, nr int) (*recorder, error) {
+	c := config.Recorder
+	dir := filepath.Join(c.Dir, fmt.Sprintf("cam%d-%s", nr, time.Now().UTC().Format("20060102-150405")))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	sidecar, err := os.Create(filepath.Join(dir, recorderSidecarFilename))
+	if err != nil {
+		return nil, err
+	}
+
+	r := &recorder{
+		nr:           nr,
+		dir:          dir,
+		format:       c.Format,
+		codec:        c.Codec,
+		fps:          c.FPS,
+		frameChan:    make(chan recordedFrame, c.QueueSize),
+		finishedChan: make(chan bool),
+	}
+	go r.writeLoop(sidecar)
+	log.Print("cam ", nr, " recording to ", dir)
+	return r, nil
+}
+
+// Returns a copy of img to be recorded, or nil if the frame has to be dropped.
+func (r *recorder) clone(img gocv.Mat) *gocv.Mat {
+	if len(r.frameChan) == cap(r.frameChan) || atomic.LoadInt32(&r.failed) != 0 {
+		atomic.AddInt64(&r.dropped, 1)
+		return nil
+	}
+	c := img.Clone()
+	return &c
+}
+
+// Queues a frame returned by clone for writing.
+func (r *recorder) add(img *gocv.Mat, meta recordMeta) {
+	select {
+	case r.frameChan <- recordedFrame{img: img, meta: meta}:
+	default:
+		img.Close()
+		atomic.AddInt64(&r.dropped, 1)
+	}
+}
+
+// Returns the number of dropped frames.
+func (r *recorder) droppedFrames() int64 {
+	return atomic.LoadInt64(&r.dropped)
+}
+
+// Waits until the queued frames are written.
+func (r *recorder) stop() {
+	close(r.frameChan)
+	<-r.finishedChan
+	log.Print("cam ", r.nr, " recording stopped, ", atomic.LoadInt64(&r.written), " frames written, ",
+		r.droppedFrames(), " dropped")
+}
+
+func (r *recorder) writeLoop(sidecar *os.File) {
+	w := bufio.NewWriter(sidecar)
+	enc := json.NewEncoder(w)
+	var vw *gocv.VideoWriter
+	var sw *ser.Writer
+	var index int
+
+	for f := range r.frameChan {
+		if atomic.LoadInt32(&r.failed) != 0 {
+			f.img.Close()
+			atomic.AddInt64(&r.dropped, 1)
+			continue
+		}
+
+		var err error
+		switch r.format {
+		case "video":
+			if vw == nil {
+				vw, err = gocv.VideoWriterFile(filepath.Join(r.dir, "video.avi"), r.codec, r.fps, f.img.Cols(),
+					f.img.Rows(), f.img.Channels() > 1)
+				if err == nil && !vw.IsOpened() {
+					err = errors.New("can't open video writer with codec " + r.codec)
+				}
+			}
+			if err == nil {
+				err = vw.Write(*f.img)
+			}
+		case "ser":
+			if sw == nil {
+				sw, err = r.createSER(*f.img)
+			}
+			if err == nil {
+				err = sw.WriteFrame(f.img.ToBytes(), f.meta.Time)
+			}
+		case "sequence":
+			f.meta.File = fmt.Sprintf("frame_%06d.png", f.meta.Frame)
+			if !gocv.IMWrite(filepath.Join(r.dir, f.meta.File), *f.img) {
+				err = errors.New("can't write " + f.meta.File)
+			}
+		}
+		f.img.Close()
+		if err == nil {
+			f.meta.Index = index
+			err = enc.Encode(f.meta)
+		}
+		if err != nil {
+			log.Error("cam ", r.nr, " recording failed: ", err)
+			atomic.StoreInt32(&r.failed, 1)
+			atomic.AddInt64(&r.dropped, 1)
+			continue
+		}
+		index++
+		atomic.AddInt64(&r.written, 1)
+	}
+
+	if vw != nil {
+		vw.Close()
+	}
+	if sw != nil {
+		if err := sw.Close(); err != nil {
+			log.Error("cam ", r.nr, " can't finish recording: ", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		log.Error("cam ", r.nr, " can't write recording sidecar: ", err)
+	}
+	sidecar.Close()
+	r.finishedChan <- true
+}
+
+// Creates the SER file of the recording, with the size and color format of img.
+func (r *recorder) createSER(img gocv.Mat) (*ser.Writer, error) {
+	h := ser.Header{
+		ColorID:    ser.BGR,
+		Width:      img.Cols(),
+		Height:     img.Rows(),
+		PixelDepth: 8,
+		Instrument: fmt.Sprint("jampec cam ", r.nr),
+	}
+	if img.Channels() == 1 {
+		h.ColorID = ser.Mono
+	}
+	return ser.Create(filepath.Join(r.dir, "video.ser"), h)
+}
+
+func (s *camStruct) toggleRecording() {
+	if s.recorder != nil {
+		s.recorder.stop()
+		s.recorder = nil
+		return
+	}
+	var err error
+	s.recorder, err = startRecorder(s.config, s.nr)
+	if err != nil {
+		log.Error("cam ", s.nr, " can't start recording: ", err)
+	}
+}
+
+// Returns the sidecar record of the frame.
+func (s *camStruct) recordMeta(td *trackData) recordMeta {
+	m := recordMeta{
+		Frame: td.frameNr,
+		Time:  td.frameTime,
+		State: s.trackState.String(),
+	}
+	if !td.rect.Empty() {
+		m.Rect = &[4]int{td.rect.Min.X, td.rect.Min.Y, td.rect.Max.X, td.rect.Max.Y}
+	}
+	if td.filterValid {
+		f := td.filter
+		m.Filter = &recordFilter{X: f.X, Y: f.Y, VX: f.VX, VY: f.VY, AX: f.AX, AY: f.AY,
+			Covariance: f.Covariance, CoastTime: f.CoastTime}
+	}
+	if pos, ok := s.mountPosition(); ok {
+		m.Mount = &pos
+	}
+	return m
+}
@@ -0,0 +1,136 @@
+package rotctld
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Timeout of a command's response.
+const commandTimeout = 5 * time.Second
+
+// Client sends commands to a rotctld. Commands are sent in extended response mode, so every response ends
+// with an RPRT line, and values are labelled.
+type Client struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	// Only one command can be in flight.
+	mu sync.Mutex
+}
+
+func Dial(addr string) (*Client, error) {
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn), nil
+}
+
+// NewClient starts a client on an already established connection.
+func NewClient(conn net.Conn) *Client {
+	return &Client{conn: conn, reader: bufio.NewReader(conn)}
+}
+
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conn.Write([]byte("q\n"))
+	return c.conn.Close()
+}
+
+// Position returns the azimuth and elevation of the rotator in degrees.
+func (c *Client) Position() (az, el float64, err error) {
+	values, err := c.command("get_pos")
+	if err != nil {
+		return 0, 0, err
+	}
+	if az, err = parseValue(values, "Azimuth"); err != nil {
+		return 0, 0, err
+	}
+	if el, err = parseValue(values, "Elevation"); err != nil {
+		return 0, 0, err
+	}
+	return az, el, nil
+}
+
+// SetPosition starts a slew to the given azimuth and elevation.
+func (c *Client) SetPosition(az, el float64) error {
+	_, err := c.command("set_pos", formatFloat(az), formatFloat(el))
+	return err
+}
+
+// Move starts moving in the given direction with a speed between 1 and MaxSpeed, until Stop is called.
+func (c *Client) Move(dir, speed int) error {
+	_, err := c.command("move", strconv.Itoa(dir), strconv.Itoa(speed))
+	return err
+}
+
+func (c *Client) Stop() error {
+	_, err := c.command("stop")
+	return err
+}
+
+func (c *Client) Park() error {
+	_, err := c.command("park")
+	return err
+}
+
+// Sends the command and returns the labelled values of the response.
+func (c *Client) command(cmd string, args ...string) (map[string]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.conn.SetDeadline(time.Now().Add(commandTimeout)); err != nil {
+		return nil, err
+	}
+	line := "+\\" + strings.Join(append([]string{cmd}, args...), " ") + "\n"
+	if _, err := c.conn.Write([]byte(line)); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string)
+	for {
+		l, err := c.reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		l = strings.TrimSpace(l)
+		if strings.HasPrefix(l, "RPRT ") {
+			code, err := strconv.Atoi(strings.TrimSpace(l[5:]))
+			if err != nil {
+				return nil, fmt.Errorf("rotctld: invalid response %q", l)
+			}
+			if code != 0 {
+				return nil, &Error{Code: -code}
+			}
+			return values, nil
+		}
+		// The first line repeats the command.
+		if strings.HasPrefix(l, cmd+":") {
+			continue
+		}
+		if i := strings.Index(l, ":"); i >= 0 {
+			values[strings.TrimSpace(l[:i])] = strings.TrimSpace(l[i+1:])
+		}
+	}
+}
+
+func parseValue(values map[string]string, key string) (float64, error) {
+	v, ok := values[key]
+	if !ok {
+		return 0, fmt.Errorf("rotctld: no %s in response", key)
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fmt.Errorf("rotctld: invalid %s %q", key, v)
+	}
+	return f, nil
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', 2, 64)
+}
@@ -0,0 +1,74 @@
+// Package rotctld implements the network protocol of hamlib's rotator daemon.
+package rotctld
+
+import "fmt"
+
+const DefaultServer = "localhost:4533"
+
+// Directions of the move command.
+const (
+	DirUp        = 2
+	DirDown      = 4
+	DirLeft      = 8
+	DirRight     = 16
+	DirUpLeft    = 32
+	DirUpRight   = 64
+	DirDownLeft  = 128
+	DirDownRight = 256
+)
+
+// Move speeds are between 1 and 100.
+const MaxSpeed = 100
+
+// Hamlib error codes, sent as negative numbers in the RPRT lines.
+const (
+	ErrInvalid        = 1
+	ErrConfig         = 2
+	ErrNoMem          = 3
+	ErrNotImplemented = 4
+	ErrTimeout        = 5
+	ErrIO             = 6
+	ErrInternal       = 7
+	ErrProtocol       = 8
+	ErrRejected       = 9
+	ErrTruncated      = 10
+	ErrNotAvailable   = 11
+	ErrNoTarget       = 12
+	ErrBus            = 13
+	ErrBusBusy        = 14
+	ErrArg            = 15
+	ErrVFO            = 16
+	ErrDomain         = 17
+)
+
+var errorTexts = map[int]string{
+	ErrInvalid:        "invalid parameter",
+	ErrConfig:         "invalid configuration",
+	ErrNoMem:          "memory shortage",
+	ErrNotImplemented: "function not implemented",
+	ErrTimeout:        "communication timed out",
+	ErrIO:             "IO error",
+	ErrInternal:       "internal hamlib error",
+	ErrProtocol:       "protocol error",
+	ErrRejected:       "command rejected by the rotator",
+	ErrTruncated:      "argument truncated",
+	ErrNotAvailable:   "function not available",
+	ErrNoTarget:       "VFO not targetable",
+	ErrBus:            "bus error",
+	ErrBusBusy:        "bus collision",
+	ErrArg:            "invalid argument",
+	ErrVFO:            "invalid VFO",
+	ErrDomain:         "argument out of domain",
+}
+
+// Error is a non-zero RPRT code.
+type Error struct {
+	Code int
+}
+
+func (e *Error) Error() string {
+	if t, ok := errorTexts[e.Code]; ok {
+		return "rotctld: " + t
+	}
+	return fmt.Sprint("rotctld: error ", e.Code)
+}
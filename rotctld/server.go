@@ -0,0 +1,229 @@
+package rotctld
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ClientID identifies a connection of a Server.
+type ClientID uint64
+
+// Handler executes the commands received by a Server. The methods are called from the goroutines of the
+// connections.
+type Handler interface {
+	Position() (az, el float64, err error)
+	SetPosition(client ClientID, az, el float64) error
+	Stop() error
+	// Called when a client which set the position disconnects.
+	Disconnected(client ClientID)
+}
+
+// Server answers rotctld clients like gpredict. Commands other than getting and setting the position,
+// stopping and the state dump are rejected as not implemented.
+type Server struct {
+	listener net.Listener
+	handler  Handler
+
+	mu     sync.Mutex
+	conns  map[net.Conn]bool
+	lastID ClientID
+}
+
+// Listen starts serving rotctld clients on addr.
+func Listen(addr string, handler Handler) (*Server, error) {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{listener: l, handler: handler, conns: make(map[net.Conn]bool)}
+	go s.acceptLoop()
+	return s, nil
+}
+
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Close stops the server and closes the connections.
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for c := range s.conns {
+		c.Close()
+	}
+	return err
+}
+
+func (s *Server) acceptLoop() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[conn] = true
+		s.lastID++
+		id := s.lastID
+		s.mu.Unlock()
+		go s.serve(conn, id)
+	}
+}
+
+// Long names of the commands, with the number of their arguments.
+var serverCommands = map[string]struct {
+	long string
+	args int
+}{
+	"p": {"get_pos", 0},
+	"P": {"set_pos", 2},
+	"S": {"stop", 0},
+	"q": {"quit", 0},
+	"Q": {"quit", 0},
+}
+
+func (s *Server) serve(conn net.Conn, id ClientID) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	// Set if the client commanded a position.
+	var commanded bool
+	defer func() {
+		if commanded {
+			s.handler.Disconnected(id)
+		}
+	}()
+
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		extended := strings.HasPrefix(fields[0], "+")
+		fields[0] = strings.TrimPrefix(fields[0], "+")
+		if fields[0] == "" {
+			fields = fields[1:]
+			if len(fields) == 0 {
+				continue
+			}
+		}
+		cmd := fields[0]
+		if strings.HasPrefix(cmd, "\\") {
+			cmd = cmd[1:]
+		} else if c, ok := serverCommands[cmd]; ok {
+			cmd = c.long
+		}
+		if cmd == "quit" {
+			return
+		}
+		if cmd == "set_pos" {
+			commanded = true
+		}
+
+		var resp string
+		if extended {
+			resp = s.extendedResponse(id, cmd, fields[1:])
+		} else {
+			resp = s.response(id, cmd, fields[1:])
+		}
+		if _, err := io.WriteString(conn, resp); err != nil {
+			return
+		}
+	}
+}
+
+// Returns the response in the default mode, which only contains the values, or an RPRT line for errors and
+// commands without values.
+func (s *Server) response(id ClientID, cmd string, args []string) string {
+	values, err := s.execute(id, cmd, args)
+	if err != nil || len(values) == 0 {
+		return rprt(err)
+	}
+	var sb strings.Builder
+	for _, v := range values {
+		sb.WriteString(v[1] + "\n")
+	}
+	return sb.String()
+}
+
+// Returns the response in extended mode, which repeats the command and labels the values.
+func (s *Server) extendedResponse(id ClientID, cmd string, args []string) string {
+	values, err := s.execute(id, cmd, args)
+	var sb strings.Builder
+	sb.WriteString(cmd + ":")
+	for _, a := range args {
+		sb.WriteString(" " + a)
+	}
+	sb.WriteString("\n")
+	for _, v := range values {
+		sb.WriteString(v[0] + ": " + v[1] + "\n")
+	}
+	sb.WriteString(rprt(err))
+	return sb.String()
+}
+
+// Executes the command of the client and returns its labelled values.
+func (s *Server) execute(id ClientID, cmd string, args []string) ([][2]string, error) {
+	switch cmd {
+	case "get_pos":
+		az, el, err := s.handler.Position()
+		if err != nil {
+			return nil, err
+		}
+		return [][2]string{{"Azimuth", formatValue(az)}, {"Elevation", formatValue(el)}}, nil
+	case "set_pos":
+		if len(args) != 2 {
+			return nil, &Error{Code: ErrInvalid}
+		}
+		az, err := strconv.ParseFloat(args[0], 64)
+		if err != nil {
+			return nil, &Error{Code: ErrInvalid}
+		}
+		el, err := strconv.ParseFloat(args[1], 64)
+		if err != nil {
+			return nil, &Error{Code: ErrInvalid}
+		}
+		return nil, s.handler.SetPosition(id, az, el)
+	case "stop":
+		return nil, s.handler.Stop()
+	case "dump_state":
+		// Protocol version, rotator model (net rotctl), azimuth and elevation limits.
+		return [][2]string{{"Protocol version", "0"}, {"Model", "2"}, {"Minimum Azimuth", formatValue(0)},
+			{"Maximum Azimuth", formatValue(360)}, {"Minimum Elevation", formatValue(0)},
+			{"Maximum Elevation", formatValue(90)}}, nil
+	}
+	return nil, &Error{Code: ErrNotImplemented}
+}
+
+// Returns the RPRT line of the error. Errors of the handler which are not rotctld errors are sent as IO
+// errors.
+func rprt(err error) string {
+	if err == nil {
+		return "RPRT 0\n"
+	}
+	var e *Error
+	if !errors.As(err, &e) {
+		e = &Error{Code: ErrIO}
+	}
+	return fmt.Sprint("RPRT ", -e.Code, "\n")
+}
+
+func formatValue(f float64) string {
+	return strconv.FormatFloat(f, 'f', 6, 64)
+}
@@ -0,0 +1,130 @@
+package rotctld
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+type testHandler struct {
+	mu           sync.Mutex
+	az, el       float64
+	client       ClientID
+	disconnected chan ClientID
+}
+
+func (h *testHandler) Position() (az, el float64, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.az, h.el, nil
+}
+
+func (h *testHandler) SetPosition(client ClientID, az, el float64) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.az, h.el = az, el
+	h.client = client
+	return nil
+}
+
+func (h *testHandler) Stop() error {
+	return nil
+}
+
+func (h *testHandler) Disconnected(client ClientID) {
+	h.disconnected <- client
+}
+
+// Returns the client which set the position last.
+func (h *testHandler) lastClient() ClientID {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.client
+}
+
+func TestServer(t *testing.T) {
+	h := &testHandler{disconnected: make(chan ClientID, 1)}
+	s, err := Listen("127.0.0.1:0", h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	// A client which only reads the position.
+	c, err := Dial(s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := c.Position(); err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+	select {
+	case <-h.disconnected:
+		t.Error("disconnect reported for a client which didn't set the position")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	c, err = Dial(s.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SetPosition(123.5, 45.25); err != nil {
+		t.Fatal(err)
+	}
+	az, el, err := c.Position()
+	if err != nil || az != 123.5 || el != 45.25 {
+		t.Errorf("position %v, %v, %v", az, el, err)
+	}
+	if err := c.Move(DirUp, 50); err == nil {
+		t.Error("move is not implemented by the server")
+	}
+	c.Close()
+	select {
+	case <-h.disconnected:
+	case <-time.After(time.Second):
+		t.Error("disconnect not reported")
+	}
+}
+
+// The handler gets which connection set the position, and which one disconnected.
+func TestServerClients(t *testing.T) {
+	h := &testHandler{disconnected: make(chan ClientID, 2)}
+	s, err := Listen("127.0.0.1:0", h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	var clients []*Client
+	var ids []ClientID
+	for i := 0; i < 2; i++ {
+		c, err := Dial(s.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.SetPosition(float64(10*i), 20); err != nil {
+			t.Fatal(err)
+		}
+		clients = append(clients, c)
+		ids = append(ids, h.lastClient())
+	}
+	if ids[0] == ids[1] {
+		t.Fatalf("same id %v for both clients", ids[0])
+	}
+	if err := clients[0].SetPosition(30, 20); err != nil || h.lastClient() != ids[0] {
+		t.Errorf("set by client %v, %v, want %v", h.lastClient(), err, ids[0])
+	}
+
+	for i := 1; i >= 0; i-- {
+		clients[i].Close()
+		select {
+		case id := <-h.disconnected:
+			if id != ids[i] {
+				t.Errorf("client %v disconnected, want %v", id, ids[i])
+			}
+		case <-time.After(time.Second):
+			t.Errorf("disconnect of client %v not reported", ids[i])
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/nonoo/jampec/guide"
+	"github.com/nonoo/jampec/mount"
+	"github.com/nonoo/jampec/rotctld"
+)
+
+// The commanded position is extrapolated with the rate estimated from the last two commands for at most
+// this long. Commands farther apart than this don't give a rate.
+const commandedTargetMaxExtrapolation = 10 * time.Second
+
+// A position commanded by a rotctld client, used as the coarse target instead of the TLE prediction.
+type commandedTarget struct {
+	// The connection which commanded the position.
+	client rotctld.ClientID
+	pos    mount.Position
+	rate   guide.Rates
+	time   time.Time
+	// Not yet forwarded to the mount.
+	forward bool
+}
+
+// Answers the rotctld clients of a camera. It runs on the server's goroutines, so it only touches the
+// camera's state under mutexes.
+type camRotctldHandler struct {
+	s *camStruct
+}
+
+// Reports the mount's position, which includes the optical correction while control is active.
+func (h camRotctldHandler) Position() (az, el float64, err error) {
+	pos, ok := h.s.mountPosition()
+	if !ok {
+		return 0, 0, errors.New("mount position is not known")
+	}
+	return mount.NormalizeAz(pos.Az), pos.El, nil
+}
+
+func (h camRotctldHandler) SetPosition(client rotctld.ClientID, az, el float64) error {
+	s := h.s
+	s.commandedMutex.Lock()
+	defer s.commandedMutex.Unlock()
+
+	now := time.Now()
+	t := &commandedTarget{client: client, pos: mount.Position{Az: az, El: el}, time: now, forward: true}
+	// The rate is only estimated from the commands of the same client.
+	if prev := s.commanded; prev != nil && prev.client == client {
+		if dt := now.Sub(prev.time).Seconds(); dt > 0 && dt <= commandedTargetMaxExtrapolation.Seconds() {
+			t.rate.Az = azDiff(az, prev.pos.Az) / dt
+			t.rate.El = (el - prev.pos.El) / dt
+		}
+	}
+	s.commanded = t
+	return nil
+}
+
+// The client is not driving the mount anymore, so the TLE prediction is used again as the target. The target
+// of another client which commanded a position since then is kept.
+func (h camRotctldHandler) Disconnected(client rotctld.ClientID) {
+	s := h.s
+	s.commandedMutex.Lock()
+	defer s.commandedMutex.Unlock()
+
+	if s.commanded != nil && s.commanded.client == client {
+		log.Print("cam ", s.nr, " rotctld client disconnected, dropping its target")
+		s.commanded = nil
+	}
+}
+
+func (h camRotctldHandler) Stop() error {
+	s := h.s
+	s.commandedMutex.Lock()
+	defer s.commandedMutex.Unlock()
+
+	s.commanded = nil
+	s.commandedStop = true
+	return nil
+}
+
+func (s *camStruct) startRotctldServer() error {
+	if s.config.RotctldServer.Listen == "" {
+		return nil
+	}
+	if s.mount == nil {
+		return errors.New("rotctld server needs a mount")
+	}
+	var err error
+	s.rotctldServer, err = rotctld.Listen(s.config.RotctldServer.Listen, camRotctldHandler{s: s})
+	if err != nil {
+		return err
+	}
+	log.Print("cam ", s.nr, " rotctld server listening on ", s.rotctldServer.Addr())
+	return nil
+}
+
+// Drops the commanded position if the client didn't send a new one for the configured timeout. Called with
+// commandedMutex locked.
+func (s *camStruct) expireCommanded(now time.Time) {
+	timeout := time.Duration(s.config.RotctldServer.Timeout * float64(time.Second))
+	if s.commanded != nil && now.Sub(s.commanded.time) > timeout {
+		log.Print("cam ", s.nr, " rotctld target expired")
+		s.commanded = nil
+	}
+}
+
+// Returns the commanded position extrapolated to t, if there's one.
+func (s *camStruct) commandedPrediction(t time.Time) (guide.Prediction, bool) {
+	s.commandedMutex.Lock()
+	defer s.commandedMutex.Unlock()
+
+	s.expireCommanded(t)
+	c := s.commanded
+	if c == nil {
+		return guide.Prediction{}, false
+	}
+	p := guide.Prediction{Az: c.pos.Az, El: c.pos.El}
+	if dt := t.Sub(c.time); dt <= commandedTargetMaxExtrapolation {
+		p.Az = mount.NormalizeAz(p.Az + c.rate.Az*dt.Seconds())
+		p.El += c.rate.El * dt.Seconds()
+		p.AzRate, p.ElRate = c.rate.Az, c.rate.El
+	}
+	return p, true
+}
+
+// While control is not active, the commands of the rotctld clients are forwarded to the mount.
+func (s *camStruct) updateCommandedTarget(cmdChan chan mountCmd) {
+	s.commandedMutex.Lock()
+	defer s.commandedMutex.Unlock()
+
+	s.expireCommanded(time.Now())
+	idle := !s.controlActive && !s.jogging
+	if s.commandedStop {
+		s.commandedStop = false
+		if idle {
+			sendMountCmd(cmdChan, mountCmd{cmdType: mountCmdTypeMove})
+		}
+	}
+	if s.commanded != nil && s.commanded.forward {
+		s.commanded.forward = false
+		if idle {
+			sendMountCmd(cmdChan, mountCmd{cmdType: mountCmdTypeGoto, pos: s.commanded.pos})
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package ser
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Reader reads frames of a SER file in any order.
+type Reader struct {
+	r      io.ReaderAt
+	closer io.Closer
+	header Header
+	order  binary.ByteOrder
+
+	// Empty if the file has no timestamps.
+	timestamps []time.Time
+}
+
+func Open(filename string) (*Reader, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r, err := NewReader(f, st.Size())
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r.closer = f
+	return r, nil
+}
+
+// NewReader reads the header and the timestamps from r, which has the given size.
+func NewReader(r io.ReaderAt, size int64) (*Reader, error) {
+	b := make([]byte, headerSize)
+	if _, err := r.ReadAt(b, 0); err != nil {
+		return nil, ErrInvalidHeader
+	}
+	h, order, err := unmarshalHeader(b)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Reader{r: r, header: h, order: order}
+
+	framesEnd := int64(headerSize) + int64(h.FrameCount)*int64(h.FrameSize())
+	if size < framesEnd || h.FrameCount == 0 {
+		// Probably an unfinished capture, the writer updates the frame count only when it's closed. Using
+		// the frames which are there.
+		res.header.FrameCount = int((size - headerSize) / int64(h.FrameSize()))
+		return res, nil
+	}
+	if size >= framesEnd+8*int64(h.FrameCount) && h.FrameCount > 0 {
+		tb := make([]byte, 8*h.FrameCount)
+		if _, err := r.ReadAt(tb, framesEnd); err != nil {
+			return nil, err
+		}
+		res.timestamps = make([]time.Time, h.FrameCount)
+		for i := range res.timestamps {
+			res.timestamps[i] = ticksToTime(int64(binary.LittleEndian.Uint64(tb[i*8:])))
+		}
+	}
+	return res, nil
+}
+
+func (r *Reader) Header() Header {
+	return r.header
+}
+
+// FrameCount returns the number of frames in the file.
+func (r *Reader) FrameCount() int {
+	return r.header.FrameCount
+}
+
+// Frame reads frame i into buf, which is reallocated if it's too small, and returns it. 16 bit values are
+// converted to little endian byte order.
+func (r *Reader) Frame(i int, buf []byte) ([]byte, error) {
+	if i < 0 || i >= r.header.FrameCount {
+		return nil, fmt.Errorf("ser: frame %d out of range", i)
+	}
+	size := r.header.FrameSize()
+	if cap(buf) < size {
+		buf = make([]byte, size)
+	}
+	buf = buf[:size]
+	if _, err := r.r.ReadAt(buf, int64(headerSize)+int64(i)*int64(size)); err != nil {
+		return nil, err
+	}
+	if r.header.BytesPerValue() == 2 && r.order == binary.BigEndian {
+		for j := 0; j+1 < len(buf); j += 2 {
+			buf[j], buf[j+1] = buf[j+1], buf[j]
+		}
+	}
+	return buf, nil
+}
+
+// Timestamp returns the UTC capture time of frame i. Returns false if the file has no timestamps.
+func (r *Reader) Timestamp(i int) (time.Time, bool) {
+	if i < 0 || i >= len(r.timestamps) || r.timestamps[i].IsZero() {
+		return time.Time{}, false
+	}
+	return r.timestamps[i], true
+}
+
+func (r *Reader) Close() error {
+	if r.closer != nil {
+		return r.closer.Close()
+	}
+	return nil
+}
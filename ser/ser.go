@@ -0,0 +1,211 @@
+// Package ser reads and writes SER files, the uncompressed video format used by astronomy capture and
+// stacking software. See http://www.grischa-hahn.homepage.t-online.de/astro/ser/ for the specification.
+package ser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	fileID     = "LUCAM-RECORDER"
+	headerSize = 178
+	textSize   = 40
+)
+
+type ColorID int32
+
+const (
+	Mono       = ColorID(0)
+	BayerRGGB  = ColorID(8)
+	BayerGRBG  = ColorID(9)
+	BayerGBRG  = ColorID(10)
+	BayerBGGR  = ColorID(11)
+	BayerCYYM  = ColorID(16)
+	BayerYCMY  = ColorID(17)
+	BayerYMCY  = ColorID(18)
+	BayerMYYC  = ColorID(19)
+	RGB        = ColorID(100)
+	BGR        = ColorID(101)
+	colorIDMax = BGR
+)
+
+func (c ColorID) String() string {
+	switch c {
+	case Mono:
+		return "MONO"
+	case BayerRGGB:
+		return "BAYER_RGGB"
+	case BayerGRBG:
+		return "BAYER_GRBG"
+	case BayerGBRG:
+		return "BAYER_GBRG"
+	case BayerBGGR:
+		return "BAYER_BGGR"
+	case BayerCYYM:
+		return "BAYER_CYYM"
+	case BayerYCMY:
+		return "BAYER_YCMY"
+	case BayerYMCY:
+		return "BAYER_YMCY"
+	case BayerMYYC:
+		return "BAYER_MYYC"
+	case RGB:
+		return "RGB"
+	case BGR:
+		return "BGR"
+	}
+	return fmt.Sprintf("ColorID(%d)", int32(c))
+}
+
+// Planes returns the number of values per pixel.
+func (c ColorID) Planes() int {
+	if c == RGB || c == BGR {
+		return 3
+	}
+	return 1
+}
+
+var ErrInvalidHeader = errors.New("ser: invalid header")
+
+// Header is the file header. Times are stored in the file with 100 ns resolution.
+type Header struct {
+	LuID    int32
+	ColorID ColorID
+	Width   int
+	Height  int
+	// Bits per pixel and plane, 1 to 16. Values above 8 are stored on two bytes.
+	PixelDepth int
+	FrameCount int
+
+	Observer   string
+	Instrument string
+	Telescope  string
+
+	// Start of the capture in local time and in UTC.
+	DateTime    time.Time
+	DateTimeUTC time.Time
+}
+
+// BytesPerValue returns the number of bytes used to store a value of a pixel plane.
+func (h Header) BytesPerValue() int {
+	if h.PixelDepth > 8 {
+		return 2
+	}
+	return 1
+}
+
+// FrameSize returns the size of a frame in bytes.
+func (h Header) FrameSize() int {
+	return h.Width * h.Height * h.ColorID.Planes() * h.BytesPerValue()
+}
+
+func (h Header) validate() error {
+	if h.Width <= 0 || h.Height <= 0 {
+		return fmt.Errorf("ser: invalid frame size %dx%d", h.Width, h.Height)
+	}
+	if h.PixelDepth < 1 || h.PixelDepth > 16 {
+		return fmt.Errorf("ser: invalid pixel depth %d", h.PixelDepth)
+	}
+	if h.ColorID < 0 || h.ColorID > colorIDMax {
+		return fmt.Errorf("ser: invalid color id %d", h.ColorID)
+	}
+	return nil
+}
+
+// Times are stored as the number of 100 ns ticks since 0001-01-01. This is the number of seconds between
+// 0001-01-01 and the Unix epoch.
+const epochToUnix = 62135596800
+
+func timeToTicks(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return (t.Unix()+epochToUnix)*1e7 + int64(t.Nanosecond())/100
+}
+
+func ticksToTime(ticks int64) time.Time {
+	if ticks <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(ticks/1e7-epochToUnix, (ticks%1e7)*100).UTC()
+}
+
+// The local time field stores the wall clock reading as if it was UTC.
+func localTimeToTicks(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	_, offset := t.Zone()
+	return timeToTicks(t.Add(time.Duration(offset) * time.Second))
+}
+
+func ticksToLocalTime(ticks int64) time.Time {
+	t := ticksToTime(ticks)
+	if t.IsZero() {
+		return t
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.Local)
+}
+
+// The endianness flag has the opposite meaning in most software than what the specification says, since the
+// original Lucam Recorder got it wrong. Like them, 0 is written for little endian data.
+const littleEndianFlag = 0
+
+func (h Header) marshal() []byte {
+	b := make([]byte, headerSize)
+	copy(b, fileID)
+	le := binary.LittleEndian
+	le.PutUint32(b[14:], uint32(h.LuID))
+	le.PutUint32(b[18:], uint32(h.ColorID))
+	le.PutUint32(b[22:], littleEndianFlag)
+	le.PutUint32(b[26:], uint32(h.Width))
+	le.PutUint32(b[30:], uint32(h.Height))
+	le.PutUint32(b[34:], uint32(h.PixelDepth))
+	le.PutUint32(b[38:], uint32(h.FrameCount))
+	copy(b[42:42+textSize], h.Observer)
+	copy(b[82:82+textSize], h.Instrument)
+	copy(b[122:122+textSize], h.Telescope)
+	le.PutUint64(b[162:], uint64(localTimeToTicks(h.DateTime)))
+	le.PutUint64(b[170:], uint64(timeToTicks(h.DateTimeUTC)))
+	return b
+}
+
+// Returns the header and the byte order of the 16 bit values.
+func unmarshalHeader(b []byte) (Header, binary.ByteOrder, error) {
+	if len(b) < headerSize || string(b[:len(fileID)]) != fileID {
+		return Header{}, nil, ErrInvalidHeader
+	}
+	le := binary.LittleEndian
+	h := Header{
+		LuID:        int32(le.Uint32(b[14:])),
+		ColorID:     ColorID(le.Uint32(b[18:])),
+		Width:       int(int32(le.Uint32(b[26:]))),
+		Height:      int(int32(le.Uint32(b[30:]))),
+		PixelDepth:  int(int32(le.Uint32(b[34:]))),
+		FrameCount:  int(int32(le.Uint32(b[38:]))),
+		Observer:    text(b[42 : 42+textSize]),
+		Instrument:  text(b[82 : 82+textSize]),
+		Telescope:   text(b[122 : 122+textSize]),
+		DateTime:    ticksToLocalTime(int64(le.Uint64(b[162:]))),
+		DateTimeUTC: ticksToTime(int64(le.Uint64(b[170:]))),
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if le.Uint32(b[22:]) != littleEndianFlag {
+		order = binary.BigEndian
+	}
+	if err := h.validate(); err != nil {
+		return Header{}, nil, err
+	}
+	return h, order, nil
+}
+
+func text(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(bytes.TrimRight(b, " "))
+}
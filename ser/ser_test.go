@@ -0,0 +1,210 @@
+package ser
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Returns a frame with a different value for every byte.
+func testFrame(h Header, nr int) []byte {
+	b := make([]byte, h.FrameSize())
+	for i := range b {
+		b[i] = byte(i*7 + nr*13)
+	}
+	return b
+}
+
+// Writes the frames with the writer, and returns the file name and the capture times.
+func writeTestFile(t *testing.T, h Header, frames int) (string, []time.Time) {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), "test.ser")
+	w, err := Create(filename, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2021, 3, 1, 20, 30, 15, 123456700, time.UTC)
+	var times []time.Time
+	for i := 0; i < frames; i++ {
+		times = append(times, start.Add(time.Duration(i)*33333300*time.Nanosecond))
+		if err := w.WriteFrame(testFrame(h, i), times[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if w.FrameCount() != frames {
+		t.Errorf("writer frame count %d", w.FrameCount())
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return filename, times
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		header Header
+	}{
+		{"8 bit mono", Header{ColorID: Mono, Width: 7, Height: 5, PixelDepth: 8}},
+		{"12 bit mono", Header{ColorID: Mono, Width: 7, Height: 5, PixelDepth: 12}},
+		{"16 bit mono", Header{ColorID: Mono, Width: 4, Height: 3, PixelDepth: 16}},
+		{"8 bit bgr", Header{ColorID: BGR, Width: 6, Height: 4, PixelDepth: 8}},
+		{"16 bit bgr", Header{ColorID: BGR, Width: 6, Height: 4, PixelDepth: 16, Observer: "observer",
+			Instrument: "camera", Telescope: "telescope"}},
+	}
+	for _, tt := range tests {
+		filename, times := writeTestFile(t, tt.header, 3)
+
+		b, err := os.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := headerSize + 3*tt.header.FrameSize() + 3*8; len(b) != want {
+			t.Errorf("%s: file size %d, want %d", tt.name, len(b), want)
+		}
+		// Little endian data is marked with 0, like in most software.
+		if flag := binary.LittleEndian.Uint32(b[22:]); flag != 0 {
+			t.Errorf("%s: endianness flag %d", tt.name, flag)
+		}
+
+		r, err := Open(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h := r.Header()
+		want := tt.header
+		want.FrameCount = 3
+		want.DateTimeUTC = times[0]
+		if h.DateTimeUTC != times[0] || !h.DateTime.Equal(times[0]) {
+			t.Errorf("%s: start time %v, %v, want %v", tt.name, h.DateTimeUTC, h.DateTime, times[0])
+		}
+		h.DateTime = time.Time{}
+		if h != want {
+			t.Errorf("%s: header %+v, want %+v", tt.name, h, want)
+		}
+		var buf []byte
+		for i := 0; i < 3; i++ {
+			if buf, err = r.Frame(i, buf); err != nil || !bytes.Equal(buf, testFrame(tt.header, i)) {
+				t.Errorf("%s: frame %d differs, %v", tt.name, i, err)
+			}
+			if ts, ok := r.Timestamp(i); !ok || ts != times[i] {
+				t.Errorf("%s: timestamp %d is %v, want %v", tt.name, i, ts, times[i])
+			}
+		}
+		if _, err := r.Frame(3, buf); err == nil {
+			t.Errorf("%s: no error for frame out of range", tt.name)
+		}
+		r.Close()
+	}
+}
+
+func TestBigEndian(t *testing.T) {
+	h := Header{ColorID: Mono, Width: 2, Height: 1, PixelDepth: 16, FrameCount: 1}
+	b := h.marshal()
+	binary.LittleEndian.PutUint32(b[22:], 1)
+	b = append(b, 0x12, 0x34, 0xab, 0xcd)
+
+	r, err := NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := r.Frame(0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Converted to little endian.
+	if want := []byte{0x34, 0x12, 0xcd, 0xab}; !bytes.Equal(f, want) {
+		t.Errorf("frame % x, want % x", f, want)
+	}
+	if _, ok := r.Timestamp(0); ok {
+		t.Error("timestamp without a trailer")
+	}
+}
+
+func TestTruncated(t *testing.T) {
+	h := Header{ColorID: Mono, Width: 8, Height: 8, PixelDepth: 8}
+	filename, _ := writeTestFile(t, h, 5)
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		b      []byte
+		frames int
+	}{
+		{"frames cut", b[:headerSize+2*h.FrameSize()+10], 2},
+		{"timestamps cut", b[:headerSize+5*h.FrameSize()+20], 5},
+		{"only the header", b[:headerSize], 0},
+	}
+	for _, tt := range tests {
+		r, err := NewReader(bytes.NewReader(tt.b), int64(len(tt.b)))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if r.FrameCount() != tt.frames {
+			t.Errorf("%s: %d frames, want %d", tt.name, r.FrameCount(), tt.frames)
+		}
+		if _, ok := r.Timestamp(0); ok {
+			t.Errorf("%s: timestamps of a truncated file", tt.name)
+		}
+		for i := 0; i < r.FrameCount(); i++ {
+			if f, err := r.Frame(i, nil); err != nil || !bytes.Equal(f, testFrame(h, i)) {
+				t.Errorf("%s: frame %d differs, %v", tt.name, i, err)
+			}
+		}
+	}
+
+	if _, err := NewReader(bytes.NewReader(b[:100]), 100); err != ErrInvalidHeader {
+		t.Errorf("cut header: %v", err)
+	}
+}
+
+// The writer updates the frame count only when it's closed, so the file of an interrupted capture has zero
+// frames in the header.
+func TestUnfinished(t *testing.T) {
+	h := Header{ColorID: Mono, Width: 8, Height: 8, PixelDepth: 16}
+	b := h.marshal()
+	for i := 0; i < 3; i++ {
+		b = append(b, testFrame(h, i)...)
+	}
+	b = append(b, 1, 2, 3)
+
+	r, err := NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.FrameCount() != 3 {
+		t.Fatalf("%d frames", r.FrameCount())
+	}
+	if f, err := r.Frame(2, nil); err != nil || !bytes.Equal(f, testFrame(h, 2)) {
+		t.Errorf("last frame differs, %v", err)
+	}
+}
+
+func TestInvalidHeader(t *testing.T) {
+	tests := []Header{
+		{ColorID: Mono, Width: 0, Height: 5, PixelDepth: 8},
+		{ColorID: Mono, Width: 5, Height: 5, PixelDepth: 17},
+		{ColorID: ColorID(102), Width: 5, Height: 5, PixelDepth: 8},
+	}
+	for _, h := range tests {
+		if _, err := NewWriter(nil, h); err == nil {
+			t.Errorf("header %+v accepted by the writer", h)
+		}
+		b := h.marshal()
+		if _, err := NewReader(bytes.NewReader(b), int64(len(b))); err == nil {
+			t.Errorf("header %+v accepted by the reader", h)
+		}
+	}
+	b := Header{ColorID: Mono, Width: 5, Height: 5, PixelDepth: 8}.marshal()
+	copy(b, "LUCAM-RECORDEX")
+	if _, err := NewReader(bytes.NewReader(b), int64(len(b))); err != ErrInvalidHeader {
+		t.Errorf("wrong file id: %v", err)
+	}
+}
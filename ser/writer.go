@@ -0,0 +1,110 @@
+package ser
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Writer writes frames to a SER file. The header is written with a zero frame count first, and updated on
+// Close, together with the frame timestamps which are appended after the frames.
+type Writer struct {
+	ws     io.WriteSeeker
+	closer io.Closer
+	w      *bufio.Writer
+	header Header
+
+	timestamps []int64
+}
+
+// Create creates the file and writes the header. The frame count and the start times of the header are
+// filled in by the writer.
+func Create(filename string, h Header) (*Writer, error) {
+	f, err := os.Create(filename)
+	if err != nil {
+		return nil, err
+	}
+	w, err := NewWriter(f, h)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	w.closer = f
+	return w, nil
+}
+
+// NewWriter writes the header to ws. The writer doesn't close ws.
+func NewWriter(ws io.WriteSeeker, h Header) (*Writer, error) {
+	h.FrameCount = 0
+	if err := h.validate(); err != nil {
+		return nil, err
+	}
+	w := &Writer{
+		ws:     ws,
+		w:      bufio.NewWriterSize(ws, 1<<20),
+		header: h,
+	}
+	if _, err := w.w.Write(h.marshal()); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// WriteFrame writes a frame with its UTC capture time. Frame data is stored row by row, with the planes of
+// a pixel interleaved. 16 bit values are expected in little endian byte order.
+func (w *Writer) WriteFrame(data []byte, t time.Time) error {
+	if len(data) != w.header.FrameSize() {
+		return fmt.Errorf("ser: frame size is %d bytes instead of %d", len(data), w.header.FrameSize())
+	}
+	if _, err := w.w.Write(data); err != nil {
+		return err
+	}
+	if len(w.timestamps) == 0 && w.header.DateTimeUTC.IsZero() {
+		w.header.DateTimeUTC = t.UTC()
+		w.header.DateTime = t.Local()
+	}
+	w.timestamps = append(w.timestamps, timeToTicks(t))
+	return nil
+}
+
+// FrameCount returns the number of written frames.
+func (w *Writer) FrameCount() int {
+	return len(w.timestamps)
+}
+
+// Close writes the timestamps and updates the header.
+func (w *Writer) Close() error {
+	err := w.finish()
+	if w.closer != nil {
+		if cerr := w.closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+func (w *Writer) finish() error {
+	b := make([]byte, 8)
+	for _, t := range w.timestamps {
+		binary.LittleEndian.PutUint64(b, uint64(t))
+		if _, err := w.w.Write(b); err != nil {
+			return err
+		}
+	}
+	if err := w.w.Flush(); err != nil {
+		return err
+	}
+
+	w.header.FrameCount = len(w.timestamps)
+	if _, err := w.ws.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := w.ws.Write(w.header.marshal()); err != nil {
+		return err
+	}
+	_, err := w.ws.Seek(0, io.SeekEnd)
+	return err
+}
@@ -0,0 +1,71 @@
+//go:build linux
+// +build linux
+
+// Package serial opens serial ports in raw mode.
+package serial
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var baudRates = map[int]uint32{
+	1200:   syscall.B1200,
+	2400:   syscall.B2400,
+	4800:   syscall.B4800,
+	9600:   syscall.B9600,
+	19200:  syscall.B19200,
+	38400:  syscall.B38400,
+	57600:  syscall.B57600,
+	115200: syscall.B115200,
+	230400: syscall.B230400,
+}
+
+// Open opens the serial port with 8N1 framing at the given baud rate, without flow control. The file is
+// non-blocking, so its deadlines can be used for timeouts.
+func Open(name string, baud int) (*os.File, error) {
+	speed, ok := baudRates[baud]
+	if !ok {
+		return nil, fmt.Errorf("serial: unsupported baud rate %d", baud)
+	}
+
+	f, err := os.OpenFile(name, os.O_RDWR|syscall.O_NOCTTY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	t := syscall.Termios{
+		Cflag:  syscall.CS8 | syscall.CREAD | syscall.CLOCAL | speed,
+		Ispeed: speed,
+		Ospeed: speed,
+	}
+	// Reads return what's available, the deadlines handle the timeouts.
+	t.Cc[syscall.VMIN] = 1
+	t.Cc[syscall.VTIME] = 0
+
+	if err := ioctl(f, syscall.TCSETS, &t); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("serial: can't set up %s: %w", name, err)
+	}
+	return f, nil
+}
+
+func ioctl(f *os.File, req uint, t *syscall.Termios) error {
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var errno syscall.Errno
+	err = rc.Control(func(fd uintptr) {
+		_, _, errno = syscall.Syscall(syscall.SYS_IOCTL, fd, uintptr(req), uintptr(unsafe.Pointer(t)))
+	})
+	if err != nil {
+		return err
+	}
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
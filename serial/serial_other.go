@@ -0,0 +1,14 @@
+//go:build !linux
+// +build !linux
+
+package serial
+
+import (
+	"errors"
+	"os"
+)
+
+// Open is only implemented on Linux.
+func Open(name string, baud int) (*os.File, error) {
+	return nil, errors.New("serial: not supported on this platform")
+}
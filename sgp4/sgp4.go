@@ -0,0 +1,424 @@
+// Package sgp4 parses two-line element sets and propagates them with the SGP4/SDP4 models to TEME position
+// and velocity. The implementation follows Vallado et al., "Revisiting Spacetrack Report #3" (AIAA 2006-6753)
+// using the WGS-72 constants and the improved operation mode, like the reference verification output.
+package sgp4
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+// WGS-72 constants.
+const (
+	mu            = 398600.8 // km^3/s^2
+	EarthRadiusKm = 6378.135
+	j2            = 0.001082616
+	j3            = -0.00000253881
+	j4            = -0.00000165597
+	j3oj2         = j3 / j2
+)
+
+var (
+	xke       = 60.0 / math.Sqrt(EarthRadiusKm*EarthRadiusKm*EarthRadiusKm/mu)
+	vkmpersec = EarthRadiusKm * xke / 60.0
+)
+
+const (
+	twoPi   = 2 * math.Pi
+	deg2rad = math.Pi / 180
+	x2o3    = 2.0 / 3.0
+	temp4   = 1.5e-12
+	// Minutes per day divided by 2pi, converts rev/day to rad/min.
+	xpdotp = 1440.0 / twoPi
+)
+
+var (
+	ErrEccentricity          = errors.New("sgp4: mean eccentricity out of range")
+	ErrMeanMotion            = errors.New("sgp4: mean motion is negative")
+	ErrPerturbedEccentricity = errors.New("sgp4: perturbed eccentricity out of range")
+	ErrSemiLatusRectum       = errors.New("sgp4: semi-latus rectum is negative")
+	ErrDecayed               = errors.New("sgp4: satellite has decayed")
+)
+
+// Vector is a TEME position (km) or velocity (km/s).
+type Vector struct {
+	X, Y, Z float64
+}
+
+func (v Vector) Norm() float64 {
+	return math.Sqrt(v.X*v.X + v.Y*v.Y + v.Z*v.Z)
+}
+
+// Satellite holds the initialized propagator state for a single element set. It's safe for concurrent use.
+type Satellite struct {
+	TLE *TLE
+
+	// The deep space resonance integrator keeps state between calls.
+	mu sync.Mutex
+
+	isimp   bool
+	deep    bool
+	epoch   float64 // Days since 1950 Jan 0.0.
+	bstar   float64
+	ecco    float64
+	argpo   float64
+	inclo   float64
+	mo      float64
+	noKozai float64
+	nodeo   float64
+	no      float64 // Un-Kozai'd mean motion.
+	a       float64
+	gsto    float64
+
+	aycof, con41, cc1, cc4, cc5, d2, d3, d4, delmo, eta, argpdot, omgcof, sinmao, t2cof, t3cof, t4cof,
+	t5cof, x1mth2, x7thm1, mdot, nodedot, xlcof, xmcof, nodecf float64
+
+	ds deepSpace
+}
+
+// NewSatellite initializes the propagator for the given element set.
+func NewSatellite(tle *TLE) (*Satellite, error) {
+	s := &Satellite{
+		TLE:     tle,
+		epoch:   tle.epochJD - 2433281.5,
+		bstar:   tle.BStar,
+		ecco:    tle.Eccentricity,
+		argpo:   tle.ArgPerigee * deg2rad,
+		inclo:   tle.Inclination * deg2rad,
+		mo:      tle.MeanAnomaly * deg2rad,
+		noKozai: tle.MeanMotion / xpdotp,
+		nodeo:   tle.RAAN * deg2rad,
+	}
+	if err := s.init(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Propagate returns the TEME position and velocity at the given time.
+func (s *Satellite) Propagate(t time.Time) (pos, vel Vector, err error) {
+	return s.PropagateMinutes(t.Sub(s.TLE.Epoch).Minutes())
+}
+
+// PropagateMinutes returns the TEME position and velocity at the given minutes since the element set epoch.
+func (s *Satellite) PropagateMinutes(tsince float64) (pos, vel Vector, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.propagate(tsince)
+}
+
+// Period returns the orbital period.
+func (s *Satellite) Period() time.Duration {
+	return time.Duration(twoPi / s.no * float64(time.Minute))
+}
+
+func gstime(jdut1 float64) float64 {
+	tut1 := (jdut1 - 2451545.0) / 36525.0
+	temp := -6.2e-6*tut1*tut1*tut1 + 0.093104*tut1*tut1 + (876600.0*3600+8640184.812866)*tut1 + 67310.54841
+	temp = math.Mod(temp*deg2rad/240.0, twoPi)
+	if temp < 0 {
+		temp += twoPi
+	}
+	return temp
+}
+
+func (s *Satellite) init() error {
+	const ss = 78.0/EarthRadiusKm + 1.0
+	qzms2t := math.Pow((120.0-78.0)/EarthRadiusKm, 4)
+
+	// initl
+	eccsq := s.ecco * s.ecco
+	omeosq := 1.0 - eccsq
+	rteosq := math.Sqrt(omeosq)
+	cosio := math.Cos(s.inclo)
+	cosio2 := cosio * cosio
+
+	ak := math.Pow(xke/s.noKozai, x2o3)
+	d1 := 0.75 * j2 * (3.0*cosio2 - 1.0) / (rteosq * omeosq)
+	del := d1 / (ak * ak)
+	adel := ak * (1.0 - del*del - del*(1.0/3.0+134.0*del*del/81.0))
+	del = d1 / (adel * adel)
+	s.no = s.noKozai / (1.0 + del)
+
+	ao := math.Pow(xke/s.no, x2o3)
+	sinio := math.Sin(s.inclo)
+	po := ao * omeosq
+	con42 := 1.0 - 5.0*cosio2
+	s.con41 = -con42 - cosio2 - cosio2
+	posq := po * po
+	rp := ao * (1.0 - s.ecco)
+	s.gsto = gstime(s.epoch + 2433281.5)
+
+	s.a = math.Pow(s.no/xke, -x2o3)
+
+	if omeosq < 0 && s.no < 0 {
+		return nil
+	}
+
+	s.isimp = rp < 220.0/EarthRadiusKm+1.0
+
+	sfour := ss
+	qzms24 := qzms2t
+	perige := (rp - 1.0) * EarthRadiusKm
+
+	// For perigees below 156 km, s and qoms2t are altered.
+	if perige < 156.0 {
+		sfour = perige - 78.0
+		if perige < 98.0 {
+			sfour = 20.0
+		}
+		qzms24 = math.Pow((120.0-sfour)/EarthRadiusKm, 4)
+		sfour = sfour/EarthRadiusKm + 1.0
+	}
+	pinvsq := 1.0 / posq
+
+	tsi := 1.0 / (ao - sfour)
+	s.eta = ao * s.ecco * tsi
+	etasq := s.eta * s.eta
+	eeta := s.ecco * s.eta
+	psisq := math.Abs(1.0 - etasq)
+	coef := qzms24 * math.Pow(tsi, 4)
+	coef1 := coef / math.Pow(psisq, 3.5)
+	cc2 := coef1 * s.no * (ao*(1.0+1.5*etasq+eeta*(4.0+etasq)) +
+		0.375*j2*tsi/psisq*s.con41*(8.0+3.0*etasq*(8.0+etasq)))
+	s.cc1 = s.bstar * cc2
+	var cc3 float64
+	if s.ecco > 1.0e-4 {
+		cc3 = -2.0 * coef * tsi * j3oj2 * s.no * sinio / s.ecco
+	}
+	s.x1mth2 = 1.0 - cosio2
+	s.cc4 = 2.0 * s.no * coef1 * ao * omeosq * (s.eta*(2.0+0.5*etasq) + s.ecco*(0.5+2.0*etasq) -
+		j2*tsi/(ao*psisq)*(-3.0*s.con41*(1.0-2.0*eeta+etasq*(1.5-0.5*eeta))+
+			0.75*s.x1mth2*(2.0*etasq-eeta*(1.0+etasq))*math.Cos(2.0*s.argpo)))
+	s.cc5 = 2.0 * coef1 * ao * omeosq * (1.0 + 2.75*(etasq+eeta) + eeta*etasq)
+	cosio4 := cosio2 * cosio2
+	temp1 := 1.5 * j2 * pinvsq * s.no
+	temp2 := 0.5 * temp1 * j2 * pinvsq
+	temp3 := -0.46875 * j4 * pinvsq * pinvsq * s.no
+	s.mdot = s.no + 0.5*temp1*rteosq*s.con41 + 0.0625*temp2*rteosq*(13.0-78.0*cosio2+137.0*cosio4)
+	s.argpdot = -0.5*temp1*con42 + 0.0625*temp2*(7.0-114.0*cosio2+395.0*cosio4) +
+		temp3*(3.0-36.0*cosio2+49.0*cosio4)
+	xhdot1 := -temp1 * cosio
+	s.nodedot = xhdot1 + (0.5*temp2*(4.0-19.0*cosio2)+2.0*temp3*(3.0-7.0*cosio2))*cosio
+	xpidot := s.argpdot + s.nodedot
+	s.omgcof = s.bstar * cc3 * math.Cos(s.argpo)
+	if s.ecco > 1.0e-4 {
+		s.xmcof = -x2o3 * coef * s.bstar / eeta
+	}
+	s.nodecf = 3.5 * omeosq * xhdot1 * s.cc1
+	s.t2cof = 1.5 * s.cc1
+	if math.Abs(cosio+1.0) > 1.5e-12 {
+		s.xlcof = -0.25 * j3oj2 * sinio * (3.0 + 5.0*cosio) / (1.0 + cosio)
+	} else {
+		s.xlcof = -0.25 * j3oj2 * sinio * (3.0 + 5.0*cosio) / temp4
+	}
+	s.aycof = -0.5 * j3oj2 * sinio
+	delmotemp := 1.0 + s.eta*math.Cos(s.mo)
+	s.delmo = delmotemp * delmotemp * delmotemp
+	s.sinmao = math.Sin(s.mo)
+	s.x7thm1 = 7.0*cosio2 - 1.0
+
+	// Deep space initialization for periods of 225 minutes or more.
+	if twoPi/s.no >= 225.0 {
+		s.deep = true
+		s.isimp = true
+		s.initDeepSpace(eccsq, xpidot)
+	}
+
+	if !s.isimp {
+		cc1sq := s.cc1 * s.cc1
+		s.d2 = 4.0 * ao * tsi * cc1sq
+		temp := s.d2 * tsi * s.cc1 / 3.0
+		s.d3 = (17.0*ao + sfour) * temp
+		s.d4 = 0.5 * temp * ao * tsi * (221.0*ao + 31.0*sfour) * s.cc1
+		s.t3cof = s.d2 + 2.0*cc1sq
+		s.t4cof = 0.25 * (3.0*s.d3 + s.cc1*(12.0*s.d2+10.0*cc1sq))
+		s.t5cof = 0.2 * (3.0*s.d4 + 12.0*s.cc1*s.d3 + 6.0*s.d2*s.d2 + 15.0*cc1sq*(2.0*s.d2+cc1sq))
+	}
+
+	_, _, err := s.propagate(0)
+	return err
+}
+
+func (s *Satellite) propagate(t float64) (pos, vel Vector, err error) {
+	// Update for secular gravity and atmospheric drag.
+	xmdf := s.mo + s.mdot*t
+	argpdf := s.argpo + s.argpdot*t
+	nodedf := s.nodeo + s.nodedot*t
+	argpm := argpdf
+	mm := xmdf
+	t2 := t * t
+	nodem := nodedf + s.nodecf*t2
+	tempa := 1.0 - s.cc1*t
+	tempe := s.bstar * s.cc4 * t
+	templ := s.t2cof * t2
+
+	if !s.isimp {
+		delomg := s.omgcof * t
+		delmtemp := 1.0 + s.eta*math.Cos(xmdf)
+		delm := s.xmcof * (delmtemp*delmtemp*delmtemp - s.delmo)
+		temp := delomg + delm
+		mm = xmdf + temp
+		argpm = argpdf - temp
+		t3 := t2 * t
+		t4 := t3 * t
+		tempa = tempa - s.d2*t2 - s.d3*t3 - s.d4*t4
+		tempe = tempe + s.bstar*s.cc5*(math.Sin(mm)-s.sinmao)
+		templ = templ + s.t3cof*t3 + t4*(s.t4cof+t*s.t5cof)
+	}
+
+	nm := s.no
+	em := s.ecco
+	inclm := s.inclo
+	if s.deep {
+		em, argpm, inclm, mm, nodem, nm = s.ds.space(s, t, em, argpm, inclm, mm, nodem)
+	}
+
+	if nm <= 0.0 {
+		return pos, vel, ErrMeanMotion
+	}
+	am := math.Pow(xke/nm, x2o3) * tempa * tempa
+	nm = xke / math.Pow(am, 1.5)
+	em = em - tempe
+
+	if em >= 1.0 || em < -0.001 {
+		return pos, vel, ErrEccentricity
+	}
+	if em < 1.0e-6 {
+		em = 1.0e-6
+	}
+	mm = mm + s.no*templ
+	xlm := mm + argpm + nodem
+
+	nodem = math.Mod(nodem, twoPi)
+	argpm = math.Mod(argpm, twoPi)
+	xlm = math.Mod(xlm, twoPi)
+	mm = math.Mod(xlm-argpm-nodem, twoPi)
+
+	sinim := math.Sin(inclm)
+	cosim := math.Cos(inclm)
+
+	// Add lunar-solar periodics.
+	ep := em
+	xincp := inclm
+	argpp := argpm
+	nodep := nodem
+	mp := mm
+	sinip := sinim
+	cosip := cosim
+	if s.deep {
+		ep, xincp, nodep, argpp, mp = s.ds.periodics(t, ep, xincp, nodep, argpp, mp)
+		if xincp < 0.0 {
+			xincp = -xincp
+			nodep = nodep + math.Pi
+			argpp = argpp - math.Pi
+		}
+		if ep < 0.0 || ep > 1.0 {
+			return pos, vel, ErrPerturbedEccentricity
+		}
+
+		sinip = math.Sin(xincp)
+		cosip = math.Cos(xincp)
+		s.aycof = -0.5 * j3oj2 * sinip
+		if math.Abs(cosip+1.0) > 1.5e-12 {
+			s.xlcof = -0.25 * j3oj2 * sinip * (3.0 + 5.0*cosip) / (1.0 + cosip)
+		} else {
+			s.xlcof = -0.25 * j3oj2 * sinip * (3.0 + 5.0*cosip) / temp4
+		}
+	}
+
+	// Long period periodics.
+	axnl := ep * math.Cos(argpp)
+	temp := 1.0 / (am * (1.0 - ep*ep))
+	aynl := ep*math.Sin(argpp) + temp*s.aycof
+	xl := mp + argpp + nodep + temp*s.xlcof*axnl
+
+	// Solve Kepler's equation.
+	u := math.Mod(xl-nodep, twoPi)
+	eo1 := u
+	tem5 := 9999.9
+	var sineo1, coseo1 float64
+	for ktr := 1; math.Abs(tem5) >= 1.0e-12 && ktr <= 10; ktr++ {
+		sineo1 = math.Sin(eo1)
+		coseo1 = math.Cos(eo1)
+		tem5 = 1.0 - coseo1*axnl - sineo1*aynl
+		tem5 = (u - aynl*coseo1 + axnl*sineo1 - eo1) / tem5
+		if math.Abs(tem5) >= 0.95 {
+			if tem5 > 0.0 {
+				tem5 = 0.95
+			} else {
+				tem5 = -0.95
+			}
+		}
+		eo1 = eo1 + tem5
+	}
+
+	// Short period preliminary quantities.
+	ecose := axnl*coseo1 + aynl*sineo1
+	esine := axnl*sineo1 - aynl*coseo1
+	el2 := axnl*axnl + aynl*aynl
+	pl := am * (1.0 - el2)
+	if pl < 0.0 {
+		return pos, vel, ErrSemiLatusRectum
+	}
+
+	rl := am * (1.0 - ecose)
+	rdotl := math.Sqrt(am) * esine / rl
+	rvdotl := math.Sqrt(pl) / rl
+	betal := math.Sqrt(1.0 - el2)
+	temp = esine / (1.0 + betal)
+	sinu := am / rl * (sineo1 - aynl - axnl*temp)
+	cosu := am / rl * (coseo1 - axnl + aynl*temp)
+	su := math.Atan2(sinu, cosu)
+	sin2u := (cosu + cosu) * sinu
+	cos2u := 1.0 - 2.0*sinu*sinu
+	temp = 1.0 / pl
+	temp1 := 0.5 * j2 * temp
+	temp2 := temp1 * temp
+
+	if s.deep {
+		cosisq := cosip * cosip
+		s.con41 = 3.0*cosisq - 1.0
+		s.x1mth2 = 1.0 - cosisq
+		s.x7thm1 = 7.0*cosisq - 1.0
+	}
+
+	// Update for short period periodics.
+	mrt := rl*(1.0-1.5*temp2*betal*s.con41) + 0.5*temp1*s.x1mth2*cos2u
+	su = su - 0.25*temp2*s.x7thm1*sin2u
+	xnode := nodep + 1.5*temp2*cosip*sin2u
+	xinc := xincp + 1.5*temp2*cosip*sinip*cos2u
+	mvt := rdotl - nm*temp1*s.x1mth2*sin2u/xke
+	rvdot := rvdotl + nm*temp1*(s.x1mth2*cos2u+1.5*s.con41)/xke
+
+	// Orientation vectors.
+	sinsu := math.Sin(su)
+	cossu := math.Cos(su)
+	snod := math.Sin(xnode)
+	cnod := math.Cos(xnode)
+	sini := math.Sin(xinc)
+	cosi := math.Cos(xinc)
+	xmx := -snod * cosi
+	xmy := cnod * cosi
+	ux := xmx*sinsu + cnod*cossu
+	uy := xmy*sinsu + snod*cossu
+	uz := sini * sinsu
+	vx := xmx*cossu - cnod*sinsu
+	vy := xmy*cossu - snod*sinsu
+	vz := sini * cossu
+
+	mr := mrt * EarthRadiusKm
+	pos = Vector{mr * ux, mr * uy, mr * uz}
+	vel = Vector{
+		(mvt*ux + rvdot*vx) * vkmpersec,
+		(mvt*uy + rvdot*vy) * vkmpersec,
+		(mvt*uz + rvdot*vz) * vkmpersec,
+	}
+
+	if mrt < 1.0 {
+		return pos, vel, ErrDecayed
+	}
+	return pos, vel, nil
+}
@@ -0,0 +1,108 @@
+package sgp4
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+type state struct {
+	tsince float64
+	pos    Vector
+	vel    Vector
+}
+
+// Reference states from the verification output (tcppver.out) of Vallado et al., "Revisiting Spacetrack
+// Report #3".
+func TestPropagate(t *testing.T) {
+	tests := []struct {
+		name   string
+		line1  string
+		line2  string
+		states []state
+	}{
+		{
+			name:  "near earth",
+			line1: "1 00005U 58002B   00179.78495062  .00000023  00000-0  28098-4 0  4753",
+			line2: "2 00005  34.2682 348.7242 1859667 331.7664  19.3264 10.82419157413667",
+			states: []state{
+				{0, Vector{7022.46529266, -1400.08296755, 0.03995155}, Vector{1.893841015, 6.405893759, 4.534807250}},
+				{360, Vector{-7154.03120202, -3783.17682504, -3536.19412294},
+					Vector{4.741887409, -4.151817765, -2.093935425}},
+				{720, Vector{-7134.59340119, 6531.68641334, 3260.27186483},
+					Vector{-4.113793027, -2.911922039, -2.557327851}},
+				{1080, Vector{5568.53901181, 4492.06992591, 3863.87641983},
+					Vector{-4.209106476, 5.159719888, 2.744852980}},
+				{1440, Vector{-938.55923943, -6268.18748831, -4294.02924751},
+					Vector{7.536105209, -0.427127707, 0.989878080}},
+			},
+		},
+		{
+			name:  "deep space molniya",
+			line1: "1 08195U 75081A   06176.33215444  .00000099  00000-0  11873-3 0   813",
+			line2: "2 08195  64.1586 279.0717 6877146 264.7651  20.2257  2.00491383225656",
+			states: []state{
+				{0, Vector{2349.89483350, -14785.93811562, 0.02119378}, Vector{2.721488096, -3.256811655, 4.498416672}},
+				{120, Vector{15223.91713658, -17852.95881467, 25280.39558932},
+					Vector{1.079041732, 0.875187372, 2.485682813}},
+			},
+		},
+		{
+			name:  "deep space 12h resonance",
+			line1: "1 28129U 03058A   06175.57071136 -.00000104  00000-0  10000-3 0   459",
+			line2: "2 28129  54.7298 324.8098 0048506 266.2640  93.1663  2.00562768 18443",
+			states: []state{
+				{0, Vector{21707.46412351, -15318.61752390, 0.13551152}, Vector{1.304029214, 1.816904974, 3.161919976}},
+			},
+		},
+	}
+
+	const (
+		posTolerance = 1e-4 // km
+		velTolerance = 1e-7 // km/s
+	)
+	for _, tt := range tests {
+		tle, err := ParseTLE("", tt.line1, tt.line2)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		sat, err := NewSatellite(tle)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		for _, s := range tt.states {
+			pos, vel, err := sat.PropagateMinutes(s.tsince)
+			if err != nil {
+				t.Errorf("%s at %v: %v", tt.name, s.tsince, err)
+				continue
+			}
+			if d := diff(pos, s.pos); d > posTolerance {
+				t.Errorf("%s at %v: position %+v, want %+v (%v km off)", tt.name, s.tsince, pos, s.pos, d)
+			}
+			if d := diff(vel, s.vel); d > velTolerance {
+				t.Errorf("%s at %v: velocity %+v, want %+v (%v km/s off)", tt.name, s.tsince, vel, s.vel, d)
+			}
+		}
+	}
+}
+
+func TestPropagateTime(t *testing.T) {
+	tle, err := ParseTLE("", "1 00005U 58002B   00179.78495062  .00000023  00000-0  28098-4 0  4753",
+		"2 00005  34.2682 348.7242 1859667 331.7664  19.3264 10.82419157413667")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sat, err := NewSatellite(tle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p1, _, _ := sat.Propagate(tle.Epoch.Add(6 * time.Hour))
+	p2, _, _ := sat.PropagateMinutes(360)
+	if d := diff(p1, p2); d > 1e-6 {
+		t.Errorf("Propagate and PropagateMinutes differ by %v km", d)
+	}
+}
+
+func diff(a, b Vector) float64 {
+	return math.Max(math.Abs(a.X-b.X), math.Max(math.Abs(a.Y-b.Y), math.Abs(a.Z-b.Z)))
+}
@@ -0,0 +1,250 @@
+package sgp4
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TLE is a parsed two-line element set. Angles are in degrees, mean motion in revolutions per day.
+type TLE struct {
+	Name           string
+	SatNum         int
+	Classification byte
+	IntlDesignator string
+	Epoch          time.Time
+	// First and second time derivative of the mean motion divided by 2 and 6 (rev/day^2 and rev/day^3).
+	MeanMotionDot  float64
+	MeanMotionDDot float64
+	BStar          float64
+	EphemerisType  int
+	ElementSetNum  int
+
+	Inclination  float64
+	RAAN         float64
+	Eccentricity float64
+	ArgPerigee   float64
+	MeanAnomaly  float64
+	MeanMotion   float64
+	RevNum       int
+
+	Line1 string
+	Line2 string
+
+	// Julian date of the epoch, kept separately as converting back from Epoch loses precision.
+	epochJD float64
+}
+
+// ParseTLE parses a two-line element set. The name is optional.
+func ParseTLE(name, line1, line2 string) (*TLE, error) {
+	line1 = strings.TrimRight(line1, " \r\n")
+	line2 = strings.TrimRight(line2, " \r\n")
+
+	if len(line1) < 68 || line1[0] != '1' {
+		return nil, fmt.Errorf("tle: invalid line 1: %q", line1)
+	}
+	if len(line2) < 68 || line2[0] != '2' {
+		return nil, fmt.Errorf("tle: invalid line 2: %q", line2)
+	}
+	for i, l := range []string{line1, line2} {
+		if len(l) >= 69 && !checksumOk(l) {
+			return nil, fmt.Errorf("tle: checksum error in line %d: %q", i+1, l)
+		}
+	}
+
+	t := &TLE{
+		Name:  strings.TrimSpace(strings.TrimPrefix(name, "0 ")),
+		Line1: line1,
+		Line2: line2,
+	}
+	p := &fieldParser{}
+
+	t.SatNum = p.satNum(line1[2:7])
+	if n := p.satNum(line2[2:7]); p.err == nil && n != t.SatNum {
+		return nil, fmt.Errorf("tle: satellite numbers of the lines differ: %d and %d", t.SatNum, n)
+	}
+	t.Classification = line1[7]
+	t.IntlDesignator = strings.TrimSpace(line1[9:17])
+
+	year := p.int(line1[18:20])
+	if year < 57 {
+		year += 2000
+	} else {
+		year += 1900
+	}
+	days := p.float(line1[20:32])
+	jan1 := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	t.Epoch = jan1.Add(time.Duration((days - 1) * 86400 * 1e9))
+	t.epochJD = julianDate(jan1) + days - 1
+
+	t.MeanMotionDot = p.float(line1[33:43])
+	t.MeanMotionDDot = p.exp(line1[44:52])
+	t.BStar = p.exp(line1[53:61])
+	t.EphemerisType = p.intOrZero(line1[62:63])
+	t.ElementSetNum = p.intOrZero(line1[64:68])
+
+	t.Inclination = p.float(line2[8:16])
+	t.RAAN = p.float(line2[17:25])
+	t.Eccentricity = p.float("." + strings.TrimSpace(line2[26:33]))
+	t.ArgPerigee = p.float(line2[34:42])
+	t.MeanAnomaly = p.float(line2[43:51])
+	t.MeanMotion = p.float(line2[52:63])
+	t.RevNum = p.intOrZero(line2[63:68])
+
+	if p.err != nil {
+		return nil, p.err
+	}
+	return t, nil
+}
+
+// ParseCatalog reads element sets in the two-line or three-line (Celestrak) format. Blank lines are ignored.
+func ParseCatalog(r io.Reader) ([]*TLE, error) {
+	var res []*TLE
+	var name, line1 string
+
+	sc := bufio.NewScanner(r)
+	lineNr := 0
+	for sc.Scan() {
+		lineNr++
+		l := strings.TrimRight(sc.Text(), " \r")
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(l, "1 ") && line1 == "":
+			line1 = l
+		case strings.HasPrefix(l, "2 ") && line1 != "":
+			t, err := ParseTLE(name, line1, l)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNr, err)
+			}
+			res = append(res, t)
+			name, line1 = "", ""
+		case line1 == "":
+			name = l
+		default:
+			return nil, fmt.Errorf("tle: line %d: expected line 2, got %q", lineNr, l)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if line1 != "" {
+		return nil, fmt.Errorf("tle: missing line 2 after %q", line1)
+	}
+	return res, nil
+}
+
+func LoadCatalog(filename string) ([]*TLE, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ParseCatalog(f)
+}
+
+// FindSatNum returns the element set with the given catalog number from the list.
+func FindSatNum(tles []*TLE, satNum int) (*TLE, bool) {
+	for _, t := range tles {
+		if t.SatNum == satNum {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+func checksumOk(l string) bool {
+	sum := 0
+	for _, c := range l[:68] {
+		switch {
+		case c >= '0' && c <= '9':
+			sum += int(c - '0')
+		case c == '-':
+			sum++
+		}
+	}
+	return int(l[68]-'0') == sum%10
+}
+
+// Collects the first parse error so fields can be parsed without checking each one.
+type fieldParser struct {
+	err error
+}
+
+func (p *fieldParser) fail(s string, err error) {
+	if p.err == nil {
+		p.err = fmt.Errorf("tle: invalid field %q: %w", s, err)
+	}
+}
+
+func (p *fieldParser) float(s string) float64 {
+	s = strings.TrimSpace(s)
+	// Fields like " .00000023" and "-.00000023" are valid, but "+.0" is used too.
+	v, err := strconv.ParseFloat(strings.TrimPrefix(s, "+"), 64)
+	if err != nil {
+		p.fail(s, err)
+	}
+	return v
+}
+
+func (p *fieldParser) int(s string) int {
+	v, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		p.fail(s, err)
+	}
+	return v
+}
+
+func (p *fieldParser) intOrZero(s string) int {
+	if strings.TrimSpace(s) == "" {
+		return 0
+	}
+	return p.int(s)
+}
+
+// Parses the Alpha-5 catalog number format too, where the first digit is replaced by a letter.
+func (p *fieldParser) satNum(s string) int {
+	s = strings.TrimSpace(s)
+	if s != "" && s[0] >= 'A' && s[0] <= 'Z' {
+		c := s[0]
+		// I and O are skipped to avoid confusion with 1 and 0.
+		v := int(c-'A') + 10
+		if c > 'I' {
+			v--
+		}
+		if c > 'O' {
+			v--
+		}
+		return v*10000 + p.int(s[1:])
+	}
+	return p.int(s)
+}
+
+// Parses fields with an implied leading decimal point and exponent, like " 28098-4" or "-11606-4".
+func (p *fieldParser) exp(s string) float64 {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0
+	}
+	sign := ""
+	if s[0] == '-' || s[0] == '+' {
+		sign = s[:1]
+		s = s[1:]
+	}
+	i := strings.LastIndexAny(s, "+-")
+	if i <= 0 {
+		return p.float(sign + "." + s)
+	}
+	return p.float(sign + "." + s[:i] + "e" + s[i:])
+}
+
+func julianDate(t time.Time) float64 {
+	return float64(t.Unix())/86400 + float64(t.Nanosecond())/86400e9 + 2440587.5
+}
@@ -0,0 +1,205 @@
+package sgp4
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	issLine1 = "1 25544U 98067A   08264.51782528 -.00002182  00000-0 -11606-4 0  2927"
+	issLine2 = "2 25544  51.6416 247.4627 0006703 130.5360 325.0288 15.72125391563537"
+)
+
+// Replaces the checksum of the line with the correct one.
+func withChecksum(l string) string {
+	sum := 0
+	for _, c := range l[:68] {
+		switch {
+		case c >= '0' && c <= '9':
+			sum += int(c - '0')
+		case c == '-':
+			sum++
+		}
+	}
+	return l[:68] + fmt.Sprint(sum%10)
+}
+
+// Replaces the catalog number of the lines.
+func withSatNum(line1, line2, satNum string) (string, string) {
+	return withChecksum(line1[:2] + satNum + line1[7:]), withChecksum(line2[:2] + satNum + line2[7:])
+}
+
+// Changes the checksum of the line to a wrong one.
+func badChecksum(l string) string {
+	return l[:68] + fmt.Sprint((int(l[68]-'0')+1)%10)
+}
+
+func TestParseTLE(t *testing.T) {
+	tle, err := ParseTLE("0 ISS (ZARYA)", issLine1, issLine2+"\r\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := TLE{
+		Name:           "ISS (ZARYA)",
+		SatNum:         25544,
+		Classification: 'U',
+		IntlDesignator: "98067A",
+		Epoch:          time.Date(2008, 9, 20, 12, 25, 40, 104192000, time.UTC),
+		MeanMotionDot:  -0.00002182,
+		BStar:          -0.11606e-4,
+		ElementSetNum:  292,
+		Inclination:    51.6416,
+		RAAN:           247.4627,
+		Eccentricity:   0.0006703,
+		ArgPerigee:     130.5360,
+		MeanAnomaly:    325.0288,
+		MeanMotion:     15.72125391,
+		RevNum:         56353,
+		Line1:          issLine1,
+		Line2:          issLine2,
+	}
+	if d := tle.Epoch.Sub(want.Epoch); d < -time.Microsecond || d > time.Microsecond {
+		t.Errorf("epoch %v, want %v", tle.Epoch, want.Epoch)
+	}
+	if jd := julianDate(want.Epoch); math.Abs(tle.epochJD-jd) > 1e-9 {
+		t.Errorf("epoch julian date %v, want %v", tle.epochJD, jd)
+	}
+	got := *tle
+	got.Epoch = want.Epoch
+	got.epochJD = 0
+	if got != want {
+		t.Errorf("parsed\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestParseTLEErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		line1, line2 string
+		err          bool
+	}{
+		{"valid", issLine1, issLine2, false},
+		{"without checksums", issLine1[:68], issLine2[:68], false},
+		{"bad checksum in line 1", badChecksum(issLine1), issLine2, true},
+		{"bad checksum in line 2", issLine1, badChecksum(issLine2), true},
+		{"short line", issLine1[:60], issLine2, true},
+		{"swapped lines", issLine2, issLine1, true},
+		{"different satellites", issLine1, withChecksum(issLine2[:2] + "25545" + issLine2[7:]), true},
+		{"invalid inclination", issLine1, withChecksum(issLine2[:8] + " 51.64x6" + issLine2[16:]), true},
+		{"invalid epoch", withChecksum(issLine1[:18] + "0826x" + issLine1[23:]), issLine2, true},
+		{"invalid b*", withChecksum(issLine1[:53] + "-11a06-4" + issLine1[61:]), issLine2, true},
+	}
+	for _, tt := range tests {
+		if _, err := ParseTLE("", tt.line1, tt.line2); (err != nil) != tt.err {
+			t.Errorf("%s: error %v", tt.name, err)
+		}
+	}
+}
+
+func TestParseTLEFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		satNum string
+		year   string
+		want   int
+		epoch  int
+	}{
+		{"five digits", "99999", "08", 99999, 2008},
+		{"leading zeros", "00005", "99", 5, 1999},
+		{"first year of the 1900s", "00001", "57", 1, 1957},
+		{"last year of the 2000s", "00001", "56", 1, 2056},
+		// Alpha-5 numbers, without I and O.
+		{"alpha-5 A", "A0001", "08", 100001, 2008},
+		{"alpha-5 H", "H9999", "08", 179999, 2008},
+		{"alpha-5 J", "J2345", "08", 182345, 2008},
+		{"alpha-5 P", "P0000", "08", 230000, 2008},
+		{"alpha-5 Z", "Z9999", "08", 339999, 2008},
+	}
+	for _, tt := range tests {
+		l1, l2 := withSatNum(issLine1, issLine2, tt.satNum)
+		l1 = withChecksum(l1[:18] + tt.year + l1[20:])
+		tle, err := ParseTLE("", l1, l2)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if tle.SatNum != tt.want || tle.Epoch.Year() != tt.epoch {
+			t.Errorf("%s: number %d, epoch %v, want %d, %d", tt.name, tle.SatNum, tle.Epoch, tt.want, tt.epoch)
+		}
+	}
+}
+
+func TestParseCatalog(t *testing.T) {
+	alphaLine1, alphaLine2 := withSatNum(issLine1, issLine2, "A0001")
+	catalog := strings.Join([]string{
+		"ISS (ZARYA)",
+		issLine1,
+		issLine2,
+		"",
+		// Two-line element sets have no name.
+		"1 00005U 58002B   00179.78495062  .00000023  00000-0  28098-4 0  4753",
+		"2 00005  34.2682 348.7242 1859667 331.7664  19.3264 10.82419157413667",
+		"0 ALPHA-5 SAT   ",
+		alphaLine1 + "\r",
+		alphaLine2 + "\r",
+	}, "\n")
+
+	tles, err := ParseCatalog(strings.NewReader(catalog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		name   string
+		satNum int
+	}{
+		{"ISS (ZARYA)", 25544},
+		{"", 5},
+		{"ALPHA-5 SAT", 100001},
+	}
+	if len(tles) != len(want) {
+		t.Fatalf("%d element sets", len(tles))
+	}
+	for i, w := range want {
+		if tles[i].Name != w.name || tles[i].SatNum != w.satNum {
+			t.Errorf("element set %d: %q %d, want %q %d", i, tles[i].Name, tles[i].SatNum, w.name, w.satNum)
+		}
+	}
+	if tle, ok := FindSatNum(tles, 100001); !ok || tle != tles[2] {
+		t.Errorf("FindSatNum returned %v, %v", tle, ok)
+	}
+	if _, ok := FindSatNum(tles, 25545); ok {
+		t.Error("FindSatNum found a missing satellite")
+	}
+
+	filename := filepath.Join(t.TempDir(), "catalog.txt")
+	if err := os.WriteFile(filename, []byte(catalog), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if tles, err := LoadCatalog(filename); err != nil || len(tles) != 3 {
+		t.Errorf("LoadCatalog returned %d element sets, %v", len(tles), err)
+	}
+}
+
+func TestParseCatalogErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		catalog []string
+		err     string
+	}{
+		{"bad checksum", []string{"ISS", issLine1, badChecksum(issLine2)}, "line 3: tle: checksum error in line 2"},
+		{"missing line 2", []string{"ISS", issLine1}, "missing line 2"},
+		{"two line 1s", []string{issLine1, issLine1, issLine2}, "line 2: expected line 2"},
+		{"name between the lines", []string{issLine1, "ISS", issLine2}, "line 2: expected line 2"},
+	}
+	for _, tt := range tests {
+		_, err := ParseCatalog(strings.NewReader(strings.Join(tt.catalog, "\n")))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: error %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
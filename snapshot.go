@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/nonoo/jampec/fits"
+	"gocv.io/x/gocv"
+)
+
+// Pending snapshot writes, waited for on exit.
+var snapshotWaitGroup sync.WaitGroup
+
+// Starts saving the next frames as FITS files.
+func (s *camStruct) startSnapshot() {
+	if s.snapshotsLeft > 0 {
+		return
+	}
+	if err := os.MkdirAll(s.config.Snapshot.Dir, 0755); err != nil {
+		log.Error("cam ", s.nr, " can't create snapshot directory: ", err)
+		return
+	}
+	s.snapshotsLeft = s.config.Snapshot.Count
+}
+
+// Writes img, which is a copy of the original frame, in the background.
+func (s *camStruct) saveSnapshot(img *gocv.Mat, td *trackData) {
+	s.snapshotsLeft--
+	fitsImg, err := matToFITSImage(*img)
+	img.Close()
+	if err != nil {
+		log.Error("cam ", s.nr, " can't save snapshot: ", err)
+		return
+	}
+	h := s.snapshotHeader(td)
+	filename := filepath.Join(s.config.Snapshot.Dir, fmt.Sprintf("cam%d-%s-%06d.fits", s.nr,
+		td.frameTime.UTC().Format("20060102-150405.000"), td.frameNr))
+
+	snapshotWaitGroup.Add(1)
+	go func() {
+		defer snapshotWaitGroup.Done()
+		if err := fits.WriteFile(filename, fitsImg, h); err != nil {
+			log.Error("cam ", s.nr, " can't save snapshot: ", err)
+			return
+		}
+		log.Print("cam ", s.nr, " saved ", filename)
+	}()
+}
+
+func (s *camStruct) snapshotHeader(td *trackData) *fits.Header {
+	h := &fits.Header{}
+	h.Set("DATE-OBS", td.frameTime, "UTC capture time of the frame")
+	if s.config.Snapshot.Exposure > 0 {
+		h.Set("EXPTIME", s.config.Snapshot.Exposure, "exposure time in seconds")
+	}
+	camera := s.config.Snapshot.Camera
+	if camera == "" {
+		camera = fmt.Sprint("cam ", s.nr)
+	}
+	h.Set("INSTRUME", camera, "camera")
+	h.Set("CREATOR", "jampec", "")
+	h.Set("FRAME", td.frameNr, "frame number of the capture")
+
+	h.Set("SITELAT", config.Site.Latitude, "site latitude in degrees")
+	h.Set("SITELONG", config.Site.Longitude, "site longitude in degrees, positive east")
+	h.Set("SITEELEV", config.Site.Altitude, "site altitude in meters")
+
+	if pos, ok := s.mountPosition(); ok {
+		h.Set("CENTAZ", pos.Az, "mount azimuth in degrees")
+		h.Set("CENTALT", pos.El, "mount elevation in degrees")
+	}
+
+	if s.target != nil {
+		h.Set("OBJECT", s.target.TLE.Name, "")
+		h.Set("NORADID", s.target.TLE.SatNum, "NORAD catalog number of the target")
+		if s.targetUp {
+			h.Set("OBJCTAZ", s.targetPrediction.Az, "predicted target azimuth in degrees")
+			h.Set("OBJCTALT", s.targetPrediction.El, "predicted target elevation in degrees")
+		}
+	}
+
+	h.Set("TRKSTATE", s.trackState.String(), "target state")
+	if !td.trackerRect.Empty() {
+		h.Set("TRKALGO", s.trackerAlgorithm, "tracker algorithm")
+		h.Set("TRKX", td.center.X, "tracked position in pixels from the left")
+		h.Set("TRKY", td.center.Y, "tracked position in pixels from the top")
+	}
+	if td.centroid != nil {
+		h.Set("TRKFLUX", td.centroid.Flux, "centroid flux")
+		h.Set("TRKSNR", td.centroid.SNR, "centroid SNR")
+	}
+	return h
+}
+
+// Converts 8 bit mono or BGR and 16 bit mono images.
+func matToFITSImage(img gocv.Mat) (fits.Image, error) {
+	size := img.Size()
+	res := fits.Image{Width: size[1], Height: size[0], Planes: 1}
+	data := img.ToBytes()
+
+	switch img.Type() {
+	case gocv.MatTypeCV8UC1:
+		res.Data8 = data
+	case gocv.MatTypeCV8UC3:
+		res.Planes = 3
+		// BGR to RGB.
+		for i := 0; i+2 < len(data); i += 3 {
+			data[i], data[i+2] = data[i+2], data[i]
+		}
+		res.Data8 = data
+	case gocv.MatTypeCV16UC1:
+		res.Data16 = make([]uint16, len(data)/2)
+		for i := range res.Data16 {
+			res.Data16[i] = binary.LittleEndian.Uint16(data[i*2:])
+		}
+	default:
+		return res, fmt.Errorf("unsupported image type %d", img.Type())
+	}
+	return res, nil
+}
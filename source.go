@@ -0,0 +1,360 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nonoo/jampec/ser"
+	"gocv.io/x/gocv"
+)
+
+// frameSource provides the frames of a camera.
+type frameSource interface {
+	// Read reads the next frame into img. Returns io.EOF at the end of non-looping file sources.
+	Read(img *gocv.Mat) error
+	Close() error
+}
+
+const defaultSourceFPS = 25
+
+func validSourceType(t string) bool {
+	return t == "device" || t == "file" || t == "pipeline" || t == "sequence" || t == "ser"
+}
+
+func openSource(config DevConfig) (frameSource, error) {
+	c := config.Source
+	switch c.Type {
+	case "device":
+		vc, err := gocv.VideoCaptureDevice(config.DevNum)
+		if err != nil {
+			return nil, fmt.Errorf("can't open video capture device %d", config.DevNum)
+		}
+		return &captureSource{cap: vc}, nil
+	case "pipeline":
+		vc, err := gocv.VideoCaptureFileWithAPI(c.Path, captureAPI(c.API))
+		if err != nil {
+			return nil, fmt.Errorf("can't open pipeline %q", c.Path)
+		}
+		return &captureSource{cap: vc}, nil
+	case "file":
+		vc, err := gocv.VideoCaptureFileWithAPI(c.Path, captureAPI(c.API))
+		if err != nil {
+			return nil, fmt.Errorf("can't open video file %s", c.Path)
+		}
+		fps := vc.Get(gocv.VideoCaptureFPS)
+		if fps <= 0 {
+			fps = c.FPS
+		}
+		s := &captureSource{
+			cap:   vc,
+			file:  true,
+			loop:  c.Loop,
+			start: c.Start,
+			pacer: newPacer(fps, c.Fast),
+		}
+		s.seek()
+		return s, nil
+	case "sequence":
+		return newSequenceSource(c.Path, c.FPS, c.Fast, c.Loop, c.Start)
+	case "ser":
+		return newSERSource(c.Path, c.FPS, c.Fast, c.Loop, c.Start)
+	}
+	return nil, fmt.Errorf("unknown source type %q", c.Type)
+}
+
+func captureAPI(api string) gocv.VideoCaptureAPI {
+	switch api {
+	case "gstreamer":
+		return gocv.VideoCaptureGstreamer
+	case "ffmpeg":
+		return gocv.VideoCaptureFFmpeg
+	}
+	return gocv.VideoCaptureAny
+}
+
+// Reads frames from a device, a pipeline or a video file.
+type captureSource struct {
+	cap *gocv.VideoCapture
+
+	// Only used by video files.
+	file  bool
+	loop  bool
+	start float64
+	pacer *pacer
+}
+
+func (s *captureSource) Read(img *gocv.Mat) error {
+	if !s.file {
+		if !s.cap.Read(img) {
+			return errors.New("error reading camera")
+		}
+		return nil
+	}
+
+	if !s.cap.Read(img) || img.Empty() {
+		if !s.loop {
+			return io.EOF
+		}
+		s.seek()
+		if !s.cap.Read(img) {
+			return errors.New("error reading video file")
+		}
+	}
+	s.pacer.wait()
+	return nil
+}
+
+func (s *captureSource) seek() {
+	s.cap.Set(gocv.VideoCapturePosMsec, s.start*1000)
+	s.pacer.reset()
+}
+
+func (s *captureSource) Close() error {
+	return s.cap.Close()
+}
+
+// Reads the numbered image files of a directory in order.
+type sequenceSource struct {
+	files      []string
+	startIndex int
+	index      int
+	loop       bool
+	pacer      *pacer
+}
+
+var sequenceImageExts = map[string]bool{
+	".png": true, ".jpg": true, ".jpeg": true, ".bmp": true, ".tif": true, ".tiff": true, ".pgm": true,
+	".ppm": true,
+}
+
+// The last number in the file name is the frame number.
+var sequenceNumberRegexp = regexp.MustCompile(`(\d+)\D*$`)
+
+func newSequenceSource(dir string, fps float64, fast, loop bool, start float64) (*sequenceSource, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	type frame struct {
+		name string
+		nr   int
+	}
+	var frames []frame
+	for _, e := range entries {
+		if e.IsDir() || !sequenceImageExts[strings.ToLower(filepath.Ext(e.Name()))] {
+			continue
+		}
+		f := frame{name: e.Name(), nr: -1}
+		if m := sequenceNumberRegexp.FindStringSubmatch(strings.TrimSuffix(e.Name(), filepath.Ext(e.Name()))); m != nil {
+			f.nr, _ = strconv.Atoi(m[1])
+		}
+		frames = append(frames, f)
+	}
+	if len(frames) == 0 {
+		return nil, fmt.Errorf("no image files in %s", dir)
+	}
+	sort.SliceStable(frames, func(i, j int) bool {
+		if frames[i].nr != frames[j].nr {
+			return frames[i].nr < frames[j].nr
+		}
+		return frames[i].name < frames[j].name
+	})
+
+	if fps <= 0 {
+		fps = defaultSourceFPS
+	}
+	s := &sequenceSource{
+		startIndex: int(start * fps),
+		loop:       loop,
+		pacer:      newPacer(fps, fast),
+	}
+	for _, f := range frames {
+		s.files = append(s.files, filepath.Join(dir, f.name))
+	}
+	if s.startIndex >= len(s.files) {
+		return nil, fmt.Errorf("start position is after the last frame of %s", dir)
+	}
+	s.index = s.startIndex
+	return s, nil
+}
+
+func (s *sequenceSource) Read(img *gocv.Mat) error {
+	if s.index >= len(s.files) {
+		if !s.loop {
+			return io.EOF
+		}
+		s.index = s.startIndex
+		s.pacer.reset()
+	}
+
+	m := gocv.IMRead(s.files[s.index], gocv.IMReadColor)
+	defer m.Close()
+	if m.Empty() {
+		return fmt.Errorf("can't read %s", s.files[s.index])
+	}
+	m.CopyTo(img)
+	s.index++
+
+	s.pacer.wait()
+	return nil
+}
+
+func (s *sequenceSource) Close() error {
+	return nil
+}
+
+// Reads the frames of a SER file.
+type serSource struct {
+	r          *ser.Reader
+	buf        []byte
+	startIndex int
+	index      int
+	loop       bool
+	pacer      *pacer
+}
+
+func newSERSource(path string, fps float64, fast, loop bool, start float64) (*serSource, error) {
+	r, err := ser.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	h := r.Header()
+	if r.FrameCount() == 0 {
+		r.Close()
+		return nil, fmt.Errorf("no frames in %s", path)
+	}
+
+	// The frame rate comes from the timestamps if the file has them.
+	first, ok1 := r.Timestamp(0)
+	last, ok2 := r.Timestamp(r.FrameCount() - 1)
+	if ok1 && ok2 && last.After(first) {
+		fps = float64(r.FrameCount()-1) / last.Sub(first).Seconds()
+	}
+	if fps <= 0 {
+		fps = defaultSourceFPS
+	}
+	s := &serSource{
+		r:          r,
+		startIndex: int(start * fps),
+		loop:       loop,
+		pacer:      newPacer(fps, fast),
+	}
+	if s.startIndex >= r.FrameCount() {
+		r.Close()
+		return nil, fmt.Errorf("start position is after the last frame of %s", path)
+	}
+	s.index = s.startIndex
+	log.Print("opened ", path, ": ", h.Width, "x", h.Height, " ", h.ColorID, " ", h.PixelDepth, " bit, ",
+		r.FrameCount(), " frames at ", strconv.FormatFloat(fps, 'f', 2, 64), " fps")
+	return s, nil
+}
+
+func (s *serSource) Read(img *gocv.Mat) error {
+	if s.index >= s.r.FrameCount() {
+		if !s.loop {
+			return io.EOF
+		}
+		s.index = s.startIndex
+		s.pacer.reset()
+	}
+
+	var err error
+	s.buf, err = s.r.Frame(s.index, s.buf)
+	if err != nil {
+		return err
+	}
+	if err = serFrameToMat(s.r.Header(), s.buf, img); err != nil {
+		return err
+	}
+	s.index++
+
+	s.pacer.wait()
+	return nil
+}
+
+func (s *serSource) Close() error {
+	return s.r.Close()
+}
+
+// Converts a SER frame to an 8 bit BGR image.
+func serFrameToMat(h ser.Header, data []byte, img *gocv.Mat) error {
+	if h.BytesPerValue() == 2 {
+		// Keeping the most significant 8 bits.
+		shift := uint(h.PixelDepth - 8)
+		for i := 0; i < len(data)/2; i++ {
+			data[i] = byte((uint16(data[i*2]) | uint16(data[i*2+1])<<8) >> shift)
+		}
+		data = data[:len(data)/2]
+	}
+
+	mt := gocv.MatTypeCV8UC1
+	if h.ColorID.Planes() == 3 {
+		mt = gocv.MatTypeCV8UC3
+	}
+	m, err := gocv.NewMatFromBytes(h.Height, h.Width, mt, data)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	// OpenCV names the Bayer patterns by the second row, so they are shifted by one compared to SER.
+	switch h.ColorID {
+	case ser.BayerRGGB:
+		gocv.CvtColor(m, img, gocv.ColorBayerBGToBGR)
+	case ser.BayerGRBG:
+		gocv.CvtColor(m, img, gocv.ColorBayerGBToBGR)
+	case ser.BayerGBRG:
+		gocv.CvtColor(m, img, gocv.ColorBayerGRToBGR)
+	case ser.BayerBGGR:
+		gocv.CvtColor(m, img, gocv.ColorBayerRGToBGR)
+	case ser.RGB:
+		gocv.CvtColor(m, img, gocv.ColorBGRToRGB)
+	case ser.BGR:
+		m.CopyTo(img)
+	default:
+		// Other Bayer patterns are shown without debayering.
+		gocv.CvtColor(m, img, gocv.ColorGrayToBGR)
+	}
+	return nil
+}
+
+// Delays the frames of file sources to be returned at the recorded frame rate.
+type pacer struct {
+	interval time.Duration
+	fast     bool
+	start    time.Time
+	frames   int
+}
+
+func newPacer(fps float64, fast bool) *pacer {
+	if fps <= 0 {
+		fps = defaultSourceFPS
+	}
+	return &pacer{interval: time.Duration(float64(time.Second) / fps), fast: fast}
+}
+
+func (p *pacer) reset() {
+	p.start = time.Time{}
+	p.frames = 0
+}
+
+func (p *pacer) wait() {
+	if p.fast {
+		return
+	}
+	if p.start.IsZero() {
+		p.start = time.Now()
+	}
+	// Sleeping until the scheduled time of the frame, so processing delays don't accumulate.
+	time.Sleep(time.Until(p.start.Add(time.Duration(p.frames) * p.interval)))
+	p.frames++
+}
@@ -0,0 +1,223 @@
+package track
+
+import (
+	"image"
+	"math"
+	"sort"
+)
+
+type AcquisitionConfig struct {
+	// Area limits of a moving blob in pixels. Zero MaxArea means no limit.
+	MinArea int `json:"minArea"`
+	MaxArea int `json:"maxArea"`
+	// Only blobs closer than this many pixels to the predicted position are considered. Zero, or no
+	// prediction means the whole frame.
+	SearchRadius float64 `json:"searchRadius"`
+	// A candidate has to be followed through this many consecutive frames to be accepted.
+	ConfirmFrames int `json:"confirmFrames"`
+	// Maximum distance in pixels between the expected and the detected position of a candidate on
+	// consecutive frames.
+	MaxJump float64 `json:"maxJump"`
+	// Candidates slower than this in pixels per second are ignored.
+	MinSpeed float64 `json:"minSpeed"`
+	// Maximum angle in degrees between the motion of a candidate and the predicted motion of the target.
+	MaxDirectionError float64 `json:"maxDirectionError"`
+	// Candidates moving together with the star field, within this many pixels per second, are ignored.
+	StarFieldTolerance float64 `json:"starFieldTolerance"`
+}
+
+func (c *AcquisitionConfig) SetDefaults() {
+	if c.MinArea == 0 {
+		c.MinArea = 1
+	}
+	if c.ConfirmFrames == 0 {
+		c.ConfirmFrames = 5
+	}
+	if c.MaxJump == 0 {
+		c.MaxJump = 20
+	}
+	if c.MinSpeed == 0 {
+		c.MinSpeed = 2
+	}
+	if c.MaxDirectionError == 0 {
+		c.MaxDirectionError = 30
+	}
+	if c.StarFieldTolerance == 0 {
+		c.StarFieldTolerance = 3
+	}
+}
+
+// Blob is a region of the foreground mask.
+type Blob struct {
+	X, Y   float64
+	Bounds image.Rectangle
+	Area   int
+}
+
+// AcquisitionHint is the predicted position and velocity of the target on the frame, in pixels and pixels
+// per second.
+type AcquisitionHint struct {
+	X, Y   float64
+	VX, VY float64
+}
+
+// Candidate is a moving blob which is followed through frames.
+type Candidate struct {
+	Blob
+	VX, VY float64
+	// Number of consecutive frames the candidate was found on.
+	Frames int
+}
+
+// Acquirer finds the target among the moving blobs of consecutive frames. Blobs are associated to
+// candidates by their expected position, and a candidate is accepted when it was followed for enough frames,
+// and moves like the target is expected to move.
+type Acquirer struct {
+	cfg        AcquisitionConfig
+	candidates []*Candidate
+}
+
+func NewAcquirer(cfg AcquisitionConfig) *Acquirer {
+	cfg.SetDefaults()
+	return &Acquirer{cfg: cfg}
+}
+
+func (a *Acquirer) Reset() {
+	a.candidates = nil
+}
+
+// Candidates returns the currently followed candidates.
+func (a *Acquirer) Candidates() []Candidate {
+	res := make([]Candidate, len(a.candidates))
+	for i, c := range a.candidates {
+		res[i] = *c
+	}
+	return res
+}
+
+// Update processes the blobs of a new frame, dt seconds after the previous one. The hint is optional.
+// Returns the bounds of the accepted candidate.
+func (a *Acquirer) Update(blobs []Blob, hint *AcquisitionHint, dt float64) (image.Rectangle, bool) {
+	var filtered []Blob
+	for _, b := range blobs {
+		if b.Area < a.cfg.MinArea || (a.cfg.MaxArea > 0 && b.Area > a.cfg.MaxArea) {
+			continue
+		}
+		if hint != nil && a.cfg.SearchRadius > 0 && math.Hypot(b.X-hint.X, b.Y-hint.Y) > a.cfg.SearchRadius {
+			continue
+		}
+		filtered = append(filtered, b)
+	}
+
+	a.associate(filtered, dt)
+
+	vx, vy, starField := a.starFieldVelocity()
+
+	var best *Candidate
+	bestDist := math.Inf(1)
+	for _, c := range a.candidates {
+		if c.Frames < a.cfg.ConfirmFrames {
+			continue
+		}
+		speed := math.Hypot(c.VX, c.VY)
+		if speed < a.cfg.MinSpeed {
+			continue
+		}
+		if starField && math.Hypot(c.VX-vx, c.VY-vy) <= a.cfg.StarFieldTolerance {
+			continue
+		}
+		dist := 0.0
+		if hint != nil {
+			if hintSpeed := math.Hypot(hint.VX, hint.VY); hintSpeed >= a.cfg.MinSpeed {
+				cos := (c.VX*hint.VX + c.VY*hint.VY) / (speed * hintSpeed)
+				if math.Acos(math.Max(-1, math.Min(1, cos)))*180/math.Pi > a.cfg.MaxDirectionError {
+					continue
+				}
+			}
+			dist = math.Hypot(c.X-hint.X, c.Y-hint.Y)
+		}
+		if best == nil || dist < bestDist || (dist == bestDist && c.Frames > best.Frames) {
+			best = c
+			bestDist = dist
+		}
+	}
+	if best == nil {
+		return image.Rectangle{}, false
+	}
+	return best.Bounds, true
+}
+
+// Greedily matches the blobs to the nearest expected candidate positions. Candidates without a match are
+// dropped, blobs without a match start new candidates.
+func (a *Acquirer) associate(blobs []Blob, dt float64) {
+	type pair struct {
+		c, b int
+		dist float64
+	}
+	var pairs []pair
+	for ci, c := range a.candidates {
+		ex, ey := c.X+c.VX*dt, c.Y+c.VY*dt
+		for bi, b := range blobs {
+			if d := math.Hypot(b.X-ex, b.Y-ey); d <= a.cfg.MaxJump {
+				pairs = append(pairs, pair{c: ci, b: bi, dist: d})
+			}
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].dist < pairs[j].dist })
+
+	cUsed := make([]bool, len(a.candidates))
+	bUsed := make([]bool, len(blobs))
+	var res []*Candidate
+	for _, p := range pairs {
+		if cUsed[p.c] || bUsed[p.b] {
+			continue
+		}
+		cUsed[p.c] = true
+		bUsed[p.b] = true
+
+		c := a.candidates[p.c]
+		b := blobs[p.b]
+		if dt > 0 {
+			vx, vy := (b.X-c.X)/dt, (b.Y-c.Y)/dt
+			if c.Frames > 1 {
+				// Averaging smooths the velocity of blobs which change shape between frames.
+				vx, vy = (c.VX+vx)/2, (c.VY+vy)/2
+			}
+			c.VX, c.VY = vx, vy
+		}
+		c.Blob = b
+		c.Frames++
+		res = append(res, c)
+	}
+	for bi, b := range blobs {
+		if !bUsed[bi] {
+			res = append(res, &Candidate{Blob: b, Frames: 1})
+		}
+	}
+	a.candidates = res
+}
+
+// Returns the median velocity of the candidates followed for at least two frames. When most moving blobs are
+// stars, because the mount moves, this is the velocity of the star field. Needs at least three candidates.
+func (a *Acquirer) starFieldVelocity() (vx, vy float64, ok bool) {
+	var xs, ys []float64
+	for _, c := range a.candidates {
+		if c.Frames >= 2 {
+			xs = append(xs, c.VX)
+			ys = append(ys, c.VY)
+		}
+	}
+	if len(xs) < 3 {
+		return 0, 0, false
+	}
+	return median(xs), median(ys), true
+}
+
+func median(v []float64) float64 {
+	sort.Float64s(v)
+	n := len(v)
+	if n%2 == 1 {
+		return v[n/2]
+	}
+	return (v[n/2-1] + v[n/2]) / 2
+}
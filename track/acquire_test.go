@@ -0,0 +1,246 @@
+package track
+
+import (
+	"image"
+	"math"
+	"testing"
+)
+
+// Frame interval of the synthetic blob sequences.
+const acquireTestDt = 0.1
+
+// An object moving with constant velocity in pixels per second.
+type acqObject struct {
+	x, y   float64
+	vx, vy float64
+	// The object is missing from this frame, if it's not zero.
+	gap int
+}
+
+func (o acqObject) at(frame int) (x, y float64) {
+	t := float64(frame) * acquireTestDt
+	return o.x + o.vx*t, o.y + o.vy*t
+}
+
+// Returns the 3x3 blob of the object on the frame.
+func (o acqObject) blob(frame int) Blob {
+	x, y := o.at(frame)
+	p := image.Pt(int(x), int(y))
+	return Blob{X: x, Y: y, Bounds: image.Rectangle{p.Sub(image.Pt(1, 1)), p.Add(image.Pt(2, 2))}, Area: 9}
+}
+
+func (o acqObject) hint(frame int) *AcquisitionHint {
+	x, y := o.at(frame)
+	return &AcquisitionHint{X: x, Y: y, VX: o.vx, VY: o.vy}
+}
+
+// Five stars drifting together, like when the mount moves.
+func starField(vx, vy float64) []acqObject {
+	var stars []acqObject
+	for i := 0; i < 5; i++ {
+		stars = append(stars, acqObject{x: 20 + 40*float64(i), y: 30 + 25*float64(i%3), vx: vx, vy: vy})
+	}
+	return stars
+}
+
+func TestAcquirer(t *testing.T) {
+	target := acqObject{x: 100, y: 100, vx: 30, vy: -10}
+	tests := []struct {
+		name    string
+		cfg     AcquisitionConfig
+		objects []acqObject
+		// The hint follows this object, if it's not nil.
+		hint   *acqObject
+		frames int
+		// The first frame a candidate is accepted on, -1 if none.
+		acceptFrame int
+		// Index of the accepted object.
+		want int
+	}{
+		{
+			name:        "confirmed after confirm frames",
+			objects:     []acqObject{target},
+			frames:      10,
+			acceptFrame: 4,
+		},
+		{
+			name:        "confirmation restarts after a gap",
+			objects:     []acqObject{{x: 100, y: 100, vx: 30, vy: -10, gap: 3}},
+			frames:      10,
+			acceptFrame: 8,
+		},
+		{
+			name:        "more confirm frames",
+			cfg:         AcquisitionConfig{ConfirmFrames: 8},
+			objects:     []acqObject{target},
+			frames:      10,
+			acceptFrame: 7,
+		},
+		{
+			name:        "jump too large",
+			cfg:         AcquisitionConfig{MaxJump: 2},
+			objects:     []acqObject{target},
+			frames:      10,
+			acceptFrame: -1,
+		},
+		{
+			name:        "slower than min speed",
+			objects:     []acqObject{{x: 100, y: 100, vx: 1, vy: 1}},
+			frames:      10,
+			acceptFrame: -1,
+		},
+		{
+			name:        "lower min speed",
+			cfg:         AcquisitionConfig{MinSpeed: 1},
+			objects:     []acqObject{{x: 100, y: 100, vx: 1, vy: 1}},
+			frames:      10,
+			acceptFrame: 4,
+		},
+		{
+			name:        "too large",
+			cfg:         AcquisitionConfig{MaxArea: 8},
+			objects:     []acqObject{target},
+			frames:      10,
+			acceptFrame: -1,
+		},
+		{
+			name:        "star field rejected",
+			objects:     starField(10, 5),
+			frames:      10,
+			acceptFrame: -1,
+		},
+		{
+			name:        "target moving across the star field",
+			objects:     append(starField(10, 5), target),
+			frames:      10,
+			acceptFrame: 4,
+			want:        5,
+		},
+		{
+			name:        "target slower than star field tolerance relative to the stars",
+			objects:     append(starField(10, 5), acqObject{x: 100, y: 100, vx: 12, vy: 5}),
+			frames:      10,
+			acceptFrame: -1,
+		},
+		{
+			name:        "direction matches the hint",
+			objects:     []acqObject{target},
+			hint:        &acqObject{x: 100, y: 100, vx: 25, vy: -20},
+			frames:      10,
+			acceptFrame: 4,
+		},
+		{
+			name:        "direction doesn't match the hint",
+			objects:     []acqObject{target},
+			hint:        &acqObject{x: 100, y: 100, vx: -10, vy: -30},
+			frames:      10,
+			acceptFrame: -1,
+		},
+		{
+			name:        "wider max direction error",
+			cfg:         AcquisitionConfig{MaxDirectionError: 100},
+			objects:     []acqObject{target},
+			hint:        &acqObject{x: 100, y: 100, vx: -10, vy: -30},
+			frames:      10,
+			acceptFrame: 4,
+		},
+		{
+			name:        "slow hint doesn't constrain direction",
+			objects:     []acqObject{target},
+			hint:        &acqObject{x: 100, y: 100, vx: -1},
+			frames:      10,
+			acceptFrame: 4,
+		},
+		{
+			// Different velocities, otherwise they would be a star field.
+			name: "closest to the hint",
+			objects: []acqObject{
+				{x: 50, y: 150, vx: 40, vy: -10},
+				target,
+				{x: 150, y: 50, vx: 50, vy: -15},
+			},
+			hint:        &acqObject{x: 105, y: 95, vx: 30, vy: -10},
+			frames:      10,
+			acceptFrame: 4,
+			want:        1,
+		},
+		{
+			name:        "outside the search radius",
+			cfg:         AcquisitionConfig{SearchRadius: 20},
+			objects:     []acqObject{target},
+			hint:        &acqObject{x: 130, y: 100, vx: 30, vy: -10},
+			frames:      10,
+			acceptFrame: -1,
+		},
+		{
+			name: "inside the search radius",
+			cfg:  AcquisitionConfig{SearchRadius: 20},
+			objects: []acqObject{
+				{x: 150, y: 100, vx: 30, vy: -10},
+				target,
+			},
+			hint:        &acqObject{x: 110, y: 100, vx: 30, vy: -10},
+			frames:      10,
+			acceptFrame: 4,
+			want:        1,
+		},
+	}
+	for _, tt := range tests {
+		a := NewAcquirer(tt.cfg)
+		for f := 0; f < tt.frames; f++ {
+			var blobs []Blob
+			for _, o := range tt.objects {
+				if o.gap == 0 || o.gap != f {
+					blobs = append(blobs, o.blob(f))
+				}
+			}
+			var hint *AcquisitionHint
+			if tt.hint != nil {
+				hint = tt.hint.hint(f)
+			}
+			r, ok := a.Update(blobs, hint, acquireTestDt)
+			if wantOk := tt.acceptFrame >= 0 && f >= tt.acceptFrame; ok != wantOk {
+				t.Errorf("%s: frame %d: accepted %v", tt.name, f, ok)
+				break
+			}
+			if ok {
+				if want := tt.objects[tt.want].blob(f).Bounds; r != want {
+					t.Errorf("%s: frame %d: accepted %v, want %v", tt.name, f, r, want)
+					break
+				}
+			}
+		}
+	}
+}
+
+func TestAcquirerCandidates(t *testing.T) {
+	a := NewAcquirer(AcquisitionConfig{})
+	stars := starField(10, 5)
+	for f := 0; f < 3; f++ {
+		var blobs []Blob
+		for _, o := range append(stars, acqObject{x: 100, y: 100, vx: 30, vy: -10}) {
+			blobs = append(blobs, o.blob(f))
+		}
+		a.Update(blobs, nil, acquireTestDt)
+	}
+	cs := a.Candidates()
+	if len(cs) != 6 {
+		t.Fatalf("%d candidates", len(cs))
+	}
+	for _, c := range cs {
+		if c.Frames != 3 {
+			t.Errorf("candidate at %v, %v followed for %d frames", c.X, c.Y, c.Frames)
+		}
+		if math.Abs(c.VX-10) > 1e-9 && math.Abs(c.VX-30) > 1e-9 {
+			t.Errorf("candidate at %v, %v velocity %v, %v", c.X, c.Y, c.VX, c.VY)
+		}
+	}
+	if vx, vy, ok := a.starFieldVelocity(); !ok || math.Abs(vx-10) > 1e-9 || math.Abs(vy-5) > 1e-9 {
+		t.Errorf("star field velocity %v, %v, %v", vx, vy, ok)
+	}
+
+	a.Reset()
+	if len(a.Candidates()) != 0 {
+		t.Error("candidates after reset")
+	}
+}
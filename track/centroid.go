@@ -0,0 +1,258 @@
+// Package track contains the tracking algorithms which are implemented in pure Go.
+package track
+
+import (
+	"image"
+	"math"
+)
+
+type CentroidConfig struct {
+	// Pixels brighter than the background by ThresholdSigma times the background noise belong to a target.
+	ThresholdSigma float64 `json:"thresholdSigma"`
+	// If set, it's used as a fixed threshold instead of the background based one.
+	Threshold int `json:"threshold"`
+	// Area limits of a target in pixels. Zero MaxArea means no limit.
+	MinArea int     `json:"minArea"`
+	MaxArea int     `json:"maxArea"`
+	MinSNR  float64 `json:"minSNR"`
+	// Size of the search window around the last position in pixels.
+	SearchSize int `json:"searchSize"`
+}
+
+func (c *CentroidConfig) SetDefaults() {
+	if c.ThresholdSigma == 0 {
+		c.ThresholdSigma = 5
+	}
+	if c.MinArea == 0 {
+		c.MinArea = 1
+	}
+	if c.MinSNR == 0 {
+		c.MinSNR = 5
+	}
+	if c.SearchSize == 0 {
+		c.SearchSize = 100
+	}
+}
+
+// Centroid is a detected point source.
+type Centroid struct {
+	// Intensity weighted center in pixel coordinates. The center of the top left pixel is 0.5, 0.5.
+	X, Y float64
+	// Background subtracted sum of the pixel values.
+	Flux float64
+	SNR  float64
+	Area int
+	// Bounding box of the pixels above the threshold.
+	Bounds image.Rectangle
+
+	// Background level and noise of the search window.
+	Background float64
+	Noise      float64
+}
+
+// Quantization noise of 8 bit images, used as the noise floor so flat or binary images can be handled.
+const minNoise = 0.5
+
+// FindCentroids returns the point sources inside the window of img which match the config.
+func FindCentroids(img *image.Gray, window image.Rectangle, cfg CentroidConfig) []Centroid {
+	window = window.Intersect(img.Rect)
+	if window.Empty() {
+		return nil
+	}
+
+	bg, noise := background(img, window)
+	threshold := bg + cfg.ThresholdSigma*noise
+	if cfg.Threshold > 0 {
+		threshold = float64(cfg.Threshold)
+	}
+
+	w, h := window.Dx(), window.Dy()
+	visited := make([]bool, w*h)
+	var stack []image.Point
+	var res []Centroid
+
+	for y := window.Min.Y; y < window.Max.Y; y++ {
+		for x := window.Min.X; x < window.Max.X; x++ {
+			i := (y-window.Min.Y)*w + x - window.Min.X
+			if visited[i] || float64(img.GrayAt(x, y).Y) <= threshold {
+				continue
+			}
+
+			// Flood filling the 8-connected component of the pixel.
+			c := Centroid{Bounds: image.Rect(x, y, x+1, y+1), Background: bg, Noise: noise}
+			var sx, sy float64
+			visited[i] = true
+			stack = append(stack[:0], image.Pt(x, y))
+			for len(stack) > 0 {
+				p := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+
+				v := float64(img.GrayAt(p.X, p.Y).Y) - bg
+				c.Flux += v
+				sx += v * (float64(p.X) + 0.5)
+				sy += v * (float64(p.Y) + 0.5)
+				c.Area++
+				c.Bounds = c.Bounds.Union(image.Rect(p.X, p.Y, p.X+1, p.Y+1))
+
+				for dy := -1; dy <= 1; dy++ {
+					for dx := -1; dx <= 1; dx++ {
+						n := image.Pt(p.X+dx, p.Y+dy)
+						if !n.In(window) {
+							continue
+						}
+						ni := (n.Y-window.Min.Y)*w + n.X - window.Min.X
+						if visited[ni] || float64(img.GrayAt(n.X, n.Y).Y) <= threshold {
+							continue
+						}
+						visited[ni] = true
+						stack = append(stack, n)
+					}
+				}
+			}
+
+			if c.Area < cfg.MinArea || (cfg.MaxArea > 0 && c.Area > cfg.MaxArea) || c.Flux <= 0 {
+				continue
+			}
+			c.X = sx / c.Flux
+			c.Y = sy / c.Flux
+			// CCD equation with unit gain.
+			c.SNR = c.Flux / math.Sqrt(c.Flux+float64(c.Area)*noise*noise)
+			if c.SNR < cfg.MinSNR {
+				continue
+			}
+			res = append(res, c)
+		}
+	}
+	return res
+}
+
+// Returns the median and the noise estimated from the median absolute deviation of the pixels in window.
+func background(img *image.Gray, window image.Rectangle) (median, noise float64) {
+	var hist [256]int
+	for y := window.Min.Y; y < window.Max.Y; y++ {
+		row := img.Pix[img.PixOffset(window.Min.X, y):img.PixOffset(window.Max.X, y)]
+		for _, v := range row {
+			hist[v]++
+		}
+	}
+	n := window.Dx() * window.Dy()
+	m := histMedian(hist[:], n)
+
+	var devHist [256]int
+	for v, c := range hist {
+		d := v - m
+		if d < 0 {
+			d = -d
+		}
+		devHist[d] += c
+	}
+	mad := histMedian(devHist[:], n)
+
+	noise = 1.4826 * float64(mad)
+	if noise < minNoise {
+		noise = minNoise
+	}
+	return float64(m), noise
+}
+
+func histMedian(hist []int, n int) int {
+	sum := 0
+	for v, c := range hist {
+		sum += c
+		if sum*2 >= n {
+			return v
+		}
+	}
+	return len(hist) - 1
+}
+
+// CentroidTracker follows a single point source through frames by searching for it around its last position.
+type CentroidTracker struct {
+	cfg  CentroidConfig
+	last Centroid
+
+	// Expected position on the next update, set by Predict.
+	predicted    bool
+	predX, predY float64
+}
+
+func NewCentroidTracker(cfg CentroidConfig) *CentroidTracker {
+	cfg.SetDefaults()
+	return &CentroidTracker{cfg: cfg}
+}
+
+// Init selects the brightest point source inside rect.
+func (t *CentroidTracker) Init(img *image.Gray, rect image.Rectangle) bool {
+	cs := FindCentroids(img, rect, t.cfg)
+	if len(cs) == 0 {
+		return false
+	}
+	best := cs[0]
+	for _, c := range cs[1:] {
+		if c.Flux > best.Flux {
+			best = c
+		}
+	}
+	t.last = best
+	t.predicted = false
+	return true
+}
+
+// Predict sets the expected position of the target on the next update. The search window is centered on it
+// instead of the last position.
+func (t *CentroidTracker) Predict(x, y float64) {
+	t.predicted = true
+	t.predX = x
+	t.predY = y
+}
+
+// Update searches for the point source closest to its expected position. On failure the last position is
+// kept, so the target is found again if it reappears nearby.
+func (t *CentroidTracker) Update(img *image.Gray) (Centroid, bool) {
+	x, y := t.last.X, t.last.Y
+	if t.predicted {
+		x, y = t.predX, t.predY
+		t.predicted = false
+	}
+	cs := FindCentroids(img, t.SearchWindowAt(x, y), t.cfg)
+	if len(cs) == 0 {
+		return Centroid{}, false
+	}
+	best := cs[0]
+	bestDist := math.Inf(1)
+	for _, c := range cs {
+		if d := math.Hypot(c.X-x, c.Y-y); d < bestDist {
+			best = c
+			bestDist = d
+		}
+	}
+	t.last = best
+	return best, true
+}
+
+// SearchWindow returns the window which is searched on the next update.
+func (t *CentroidTracker) SearchWindow() image.Rectangle {
+	if t.predicted {
+		return t.SearchWindowAt(t.predX, t.predY)
+	}
+	return t.SearchWindowAt(t.last.X, t.last.Y)
+}
+
+// SearchWindowAt returns the search window centered on the given position. The window is at least three
+// times the size of the target, so it's not lost when it's larger than the configured search size.
+func (t *CentroidTracker) SearchWindowAt(x, y float64) image.Rectangle {
+	size := t.cfg.SearchSize
+	if s := 3 * t.last.Bounds.Dx(); s > size {
+		size = s
+	}
+	if s := 3 * t.last.Bounds.Dy(); s > size {
+		size = s
+	}
+	min := image.Pt(int(math.Round(x))-size/2, int(math.Round(y))-size/2)
+	return image.Rectangle{Min: min, Max: min.Add(image.Pt(size, size))}
+}
+
+// Last returns the last found point source.
+func (t *CentroidTracker) Last() Centroid {
+	return t.last
+}
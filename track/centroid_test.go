@@ -0,0 +1,226 @@
+package track
+
+import (
+	"image"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// A point source with a Gaussian profile. The center is in pixel coordinates, where the center of the top
+// left pixel is 0.5, 0.5.
+type spot struct {
+	x, y      float64
+	amplitude float64
+	sigma     float64
+}
+
+// Renders the spots on a background with Gaussian noise, clipped to 8 bits.
+func renderSpots(w, h int, bg, noise float64, spots ...spot) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	rnd := rand.New(rand.NewSource(1))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := bg + noise*rnd.NormFloat64()
+			for _, s := range spots {
+				dx, dy := float64(x)+0.5-s.x, float64(y)+0.5-s.y
+				v += s.amplitude * math.Exp(-(dx*dx+dy*dy)/(2*s.sigma*s.sigma))
+			}
+			img.Pix[img.PixOffset(x, y)] = uint8(math.Max(0, math.Min(255, math.Round(v))))
+		}
+	}
+	return img
+}
+
+// Fills the rectangle of img with v.
+func fillRect(img *image.Gray, r image.Rectangle, v uint8) *image.Gray {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.Pix[img.PixOffset(x, y)] = v
+		}
+	}
+	return img
+}
+
+func TestFindCentroids(t *testing.T) {
+	var defaults CentroidConfig
+	defaults.SetDefaults()
+	lowSNR := defaults
+	lowSNR.MinSNR = 1
+
+	full := image.Rect(0, 0, 100, 80)
+	tests := []struct {
+		name   string
+		img    *image.Gray
+		window image.Rectangle
+		cfg    CentroidConfig
+		// Expected centroids, only the position is checked.
+		want []spot
+		tol  float64
+		// Additional checks of the first centroid.
+		check func(t *testing.T, c Centroid)
+	}{
+		{
+			name:   "subpixel offset",
+			img:    renderSpots(100, 80, 20, 2, spot{50.3, 40.7, 150, 1.5}),
+			window: full,
+			cfg:    defaults,
+			want:   []spot{{x: 50.3, y: 40.7}},
+			tol:    0.05,
+		},
+		{
+			name:   "bright background",
+			img:    renderSpots(100, 80, 180, 3, spot{20.6, 30.2, 60, 1.5}),
+			window: full,
+			cfg:    defaults,
+			want:   []spot{{x: 20.6, y: 30.2}},
+			tol:    0.15,
+			check: func(t *testing.T, c Centroid) {
+				if math.Abs(c.Background-180) > 1 || math.Abs(c.Noise-3) > 0.5 {
+					t.Errorf("background %v, noise %v", c.Background, c.Noise)
+				}
+				// The threshold is at a quarter of the peak, which keeps 75% of the flux of
+				// 2*pi*sigma^2*amplitude.
+				if want := 0.75 * 2 * math.Pi * 1.5 * 1.5 * 60; math.Abs(c.Flux-want) > 0.1*want {
+					t.Errorf("flux %v, want about %v", c.Flux, want)
+				}
+			},
+		},
+		{
+			name:   "snr",
+			img:    fillRect(renderSpots(100, 80, 10, 0), image.Rect(10, 20, 13, 23), 30),
+			window: full,
+			cfg:    defaults,
+			want:   []spot{{x: 11.5, y: 21.5}},
+			check: func(t *testing.T, c Centroid) {
+				// 9 pixels 20 above the background, with the noise floor of 0.5.
+				if c.Flux != 180 || c.Area != 9 || c.Noise != minNoise {
+					t.Errorf("flux %v, area %v, noise %v", c.Flux, c.Area, c.Noise)
+				}
+				if want := 180 / math.Sqrt(180+9*0.25); math.Abs(c.SNR-want) > 1e-9 {
+					t.Errorf("snr %v, want %v", c.SNR, want)
+				}
+				if c.Bounds != image.Rect(10, 20, 13, 23) {
+					t.Errorf("bounds %v", c.Bounds)
+				}
+			},
+		},
+		{
+			name:   "below snr threshold",
+			img:    fillRect(renderSpots(100, 80, 10, 0), image.Rect(40, 40, 41, 41), 13),
+			window: full,
+			cfg:    defaults,
+		},
+		{
+			name:   "above lowered snr threshold",
+			img:    fillRect(renderSpots(100, 80, 10, 0), image.Rect(40, 40, 41, 41), 13),
+			window: full,
+			cfg:    lowSNR,
+			want:   []spot{{x: 40.5, y: 40.5}},
+			check: func(t *testing.T, c Centroid) {
+				if want := 3 / math.Sqrt(3+0.25); math.Abs(c.SNR-want) > 1e-9 {
+					t.Errorf("snr %v, want %v", c.SNR, want)
+				}
+			},
+		},
+		{
+			name:   "saturated",
+			img:    renderSpots(100, 80, 20, 2, spot{30.4, 60.8, 1000, 2}),
+			window: full,
+			cfg:    defaults,
+			want:   []spot{{x: 30.4, y: 60.8}},
+			tol:    0.05,
+			check: func(t *testing.T, c Centroid) {
+				if c.Bounds.Dx() < 9 || c.Bounds.Dy() < 9 {
+					t.Errorf("bounds %v of a saturated spot", c.Bounds)
+				}
+			},
+		},
+		{
+			// Only the half inside the window is measured, so the centroid moves inside.
+			name:   "clipped at the window edge",
+			img:    renderSpots(100, 80, 20, 2, spot{50, 40.5, 150, 1.5}),
+			window: image.Rect(0, 0, 50, 80),
+			cfg:    defaults,
+			want:   []spot{{x: 49, y: 40.5}},
+			tol:    0.25,
+			check: func(t *testing.T, c Centroid) {
+				if c.Bounds.Max.X != 50 || c.X >= 50 {
+					t.Errorf("centroid %v, bounds %v outside the window", c.X, c.Bounds)
+				}
+			},
+		},
+		{
+			name:   "window outside the image",
+			img:    renderSpots(100, 80, 20, 2, spot{95, 75, 150, 1.5}),
+			window: image.Rect(60, 60, 160, 160),
+			cfg:    defaults,
+			want:   []spot{{x: 95, y: 75}},
+			tol:    0.1,
+		},
+		{
+			name: "two spots",
+			img: renderSpots(100, 80, 20, 2, spot{20.5, 20.5, 100, 1.5},
+				spot{70.25, 60.75, 100, 1.5}),
+			window: full,
+			cfg:    defaults,
+			want:   []spot{{x: 20.5, y: 20.5}, {x: 70.25, y: 60.75}},
+			tol:    0.1,
+		},
+		{
+			name:   "empty",
+			img:    renderSpots(100, 80, 20, 2),
+			window: full,
+			cfg:    defaults,
+		},
+	}
+	for _, tt := range tests {
+		cs := FindCentroids(tt.img, tt.window, tt.cfg)
+		if len(cs) != len(tt.want) {
+			t.Errorf("%s: found %d centroids, want %d: %+v", tt.name, len(cs), len(tt.want), cs)
+			continue
+		}
+		for i, c := range cs {
+			if math.Abs(c.X-tt.want[i].x) > tt.tol || math.Abs(c.Y-tt.want[i].y) > tt.tol {
+				t.Errorf("%s: centroid at %.3f, %.3f, want %v, %v", tt.name, c.X, c.Y, tt.want[i].x,
+					tt.want[i].y)
+			}
+		}
+		if tt.check != nil && len(cs) > 0 {
+			t.Run(tt.name, func(t *testing.T) {
+				tt.check(t, cs[0])
+			})
+		}
+	}
+}
+
+func TestCentroidTracker(t *testing.T) {
+	tr := NewCentroidTracker(CentroidConfig{SearchSize: 20})
+	// The brightest source is selected.
+	img := renderSpots(100, 80, 20, 2, spot{30, 30, 60, 1.5}, spot{35, 35, 150, 1.5})
+	if !tr.Init(img, image.Rect(20, 20, 50, 50)) {
+		t.Fatal("init failed")
+	}
+	if c := tr.Last(); math.Abs(c.X-35) > 0.1 || math.Abs(c.Y-35) > 0.1 {
+		t.Fatalf("init selected %v, %v", c.X, c.Y)
+	}
+
+	// The source moved out of the search window, but is found at the predicted position.
+	img = renderSpots(100, 80, 20, 2, spot{55, 40, 150, 1.5})
+	tr.Predict(54, 41)
+	if w := tr.SearchWindow(); !image.Pt(55, 40).In(w) {
+		t.Errorf("search window %v", w)
+	}
+	c, ok := tr.Update(img)
+	if !ok || math.Abs(c.X-55) > 0.1 || math.Abs(c.Y-40) > 0.1 {
+		t.Errorf("update found %v, %v, %v", c.X, c.Y, ok)
+	}
+
+	// Lost, the last position is kept.
+	if _, ok := tr.Update(renderSpots(100, 80, 20, 2)); ok {
+		t.Error("found a source on an empty image")
+	}
+	if c := tr.Last(); math.Abs(c.X-55) > 0.1 {
+		t.Errorf("last position %v", c.X)
+	}
+}
@@ -0,0 +1,347 @@
+package track
+
+import (
+	"errors"
+	"math"
+)
+
+type KalmanModel string
+
+const (
+	KalmanModelConstantVelocity     = KalmanModel("cv")
+	KalmanModelConstantAcceleration = KalmanModel("ca")
+)
+
+type KalmanConfig struct {
+	Model KalmanModel `json:"model"`
+	// Spectral density of the white noise acceleration (constant velocity model) or jerk (constant
+	// acceleration model), in pixels^2/s^3 or pixels^2/s^5.
+	ProcessNoise float64 `json:"processNoise"`
+	// Standard deviation of the measured position in pixels.
+	MeasurementNoise float64 `json:"measurementNoise"`
+	// The filter only predicts this many seconds without measurements, then it's reset.
+	MaxCoastTime float64 `json:"maxCoastTime"`
+}
+
+func (c *KalmanConfig) SetDefaults() {
+	if c.Model == "" {
+		c.Model = KalmanModelConstantVelocity
+	}
+	if c.ProcessNoise == 0 {
+		c.ProcessNoise = 100
+	}
+	if c.MeasurementNoise == 0 {
+		c.MeasurementNoise = 1
+	}
+	if c.MaxCoastTime == 0 {
+		c.MaxCoastTime = 2
+	}
+}
+
+func (c KalmanConfig) Validate() error {
+	if c.Model != KalmanModelConstantVelocity && c.Model != KalmanModelConstantAcceleration {
+		return errors.New("unknown kalman filter model " + string(c.Model))
+	}
+	return nil
+}
+
+// Standard deviation of the initial velocity and acceleration, large enough to be quickly overridden by
+// measurements.
+const (
+	initialVelocityStd     = 200
+	initialAccelerationStd = 200
+)
+
+// KalmanState is the filtered state of the target in pixel coordinates.
+type KalmanState struct {
+	X, Y   float64
+	VX, VY float64
+	// Always zero with the constant velocity model.
+	AX, AY float64
+	// Covariance of the state vector, ordered as X, Y, VX, VY, AX, AY. It's 4x4 with the constant velocity
+	// model.
+	Covariance [][]float64
+	// Seconds since the last measurement.
+	CoastTime float64
+}
+
+// PositionStd returns the standard deviation of the position along the major axis of its error ellipse.
+func (s KalmanState) PositionStd() float64 {
+	a, b, d := s.Covariance[0][0], s.Covariance[0][1], s.Covariance[1][1]
+	// Largest eigenvalue of the 2x2 position covariance.
+	l := (a+d)/2 + math.Sqrt((a-d)*(a-d)/4+b*b)
+	return math.Sqrt(l)
+}
+
+// Kalman is a linear Kalman filter over the position of the target, with independent but identical models on
+// the two axes.
+type Kalman struct {
+	cfg KalmanConfig
+	// Number of derivatives in the state, 2 for constant velocity, 3 for constant acceleration.
+	order int
+
+	initialized bool
+	x           []float64
+	p           matrix
+	coastTime   float64
+}
+
+func NewKalman(cfg KalmanConfig) *Kalman {
+	cfg.SetDefaults()
+	k := &Kalman{cfg: cfg, order: 2}
+	if cfg.Model == KalmanModelConstantAcceleration {
+		k.order = 3
+	}
+	return k
+}
+
+func (k *Kalman) Reset() {
+	k.initialized = false
+	k.x = nil
+	k.p = nil
+	k.coastTime = 0
+}
+
+// Initialized returns true if the filter has a state, so it was corrected at least once since the last reset.
+func (k *Kalman) Initialized() bool {
+	return k.initialized
+}
+
+// Predict advances the state by dt seconds. The filter is reset if it's been predicting without measurements
+// for longer than the configured coast time.
+func (k *Kalman) Predict(dt float64) {
+	if !k.initialized || dt <= 0 {
+		return
+	}
+	k.coastTime += dt
+	if k.coastTime > k.cfg.MaxCoastTime {
+		k.Reset()
+		return
+	}
+
+	f := k.transition(dt)
+	k.x = f.mulVec(k.x)
+	k.p = f.mul(k.p).mul(f.transpose()).add(k.processNoise(dt))
+}
+
+// Correct updates the state with a measured position.
+func (k *Kalman) Correct(x, y float64) {
+	if !k.initialized {
+		k.init(x, y)
+		return
+	}
+	k.coastTime = 0
+
+	n := len(k.x)
+	r := k.cfg.MeasurementNoise * k.cfg.MeasurementNoise
+	// The measurement matrix selects the first two state elements, so S = HPH' + R is the top left of P.
+	s := [2][2]float64{{k.p[0][0] + r, k.p[0][1]}, {k.p[1][0], k.p[1][1] + r}}
+	det := s[0][0]*s[1][1] - s[0][1]*s[1][0]
+	if det == 0 {
+		return
+	}
+	si := [2][2]float64{{s[1][1] / det, -s[0][1] / det}, {-s[1][0] / det, s[0][0] / det}}
+
+	// K = PH'S^-1, PH' is the first two columns of P.
+	gain := newMatrix(n, 2)
+	for i := 0; i < n; i++ {
+		for j := 0; j < 2; j++ {
+			gain[i][j] = k.p[i][0]*si[0][j] + k.p[i][1]*si[1][j]
+		}
+	}
+
+	innov := [2]float64{x - k.x[0], y - k.x[1]}
+	for i := 0; i < n; i++ {
+		k.x[i] += gain[i][0]*innov[0] + gain[i][1]*innov[1]
+	}
+
+	// P = (I - KH)P
+	ikh := identity(n)
+	for i := 0; i < n; i++ {
+		ikh[i][0] -= gain[i][0]
+		ikh[i][1] -= gain[i][1]
+	}
+	k.p = ikh.mul(k.p)
+	k.p.symmetrize()
+}
+
+// Distance returns the Mahalanobis distance of a measured position from the predicted one, in standard
+// deviations. Measurements which are far from the prediction are not consistent with the motion model.
+func (k *Kalman) Distance(x, y float64) float64 {
+	if !k.initialized {
+		return 0
+	}
+	r := k.cfg.MeasurementNoise * k.cfg.MeasurementNoise
+	s := [2][2]float64{{k.p[0][0] + r, k.p[0][1]}, {k.p[1][0], k.p[1][1] + r}}
+	det := s[0][0]*s[1][1] - s[0][1]*s[1][0]
+	if det <= 0 {
+		return 0
+	}
+	dx, dy := x-k.x[0], y-k.x[1]
+	d2 := (s[1][1]*dx*dx - (s[0][1]+s[1][0])*dx*dy + s[0][0]*dy*dy) / det
+	return math.Sqrt(d2)
+}
+
+// State returns a copy of the current state. Only valid if the filter is initialized.
+func (k *Kalman) State() KalmanState {
+	if !k.initialized {
+		return KalmanState{}
+	}
+	s := KalmanState{
+		X:          k.x[0],
+		Y:          k.x[1],
+		VX:         k.x[2],
+		VY:         k.x[3],
+		Covariance: k.p.clone(),
+		CoastTime:  k.coastTime,
+	}
+	if k.order == 3 {
+		s.AX = k.x[4]
+		s.AY = k.x[5]
+	}
+	return s
+}
+
+func (k *Kalman) init(x, y float64) {
+	n := 2 * k.order
+	k.x = make([]float64, n)
+	k.x[0] = x
+	k.x[1] = y
+
+	r := k.cfg.MeasurementNoise * k.cfg.MeasurementNoise
+	k.p = newMatrix(n, n)
+	k.p[0][0], k.p[1][1] = r, r
+	k.p[2][2], k.p[3][3] = initialVelocityStd*initialVelocityStd, initialVelocityStd*initialVelocityStd
+	if k.order == 3 {
+		k.p[4][4], k.p[5][5] = initialAccelerationStd*initialAccelerationStd, initialAccelerationStd*initialAccelerationStd
+	}
+	k.coastTime = 0
+	k.initialized = true
+}
+
+// The state vector is ordered by derivative, then by axis: X, Y, VX, VY, AX, AY.
+func (k *Kalman) transition(dt float64) matrix {
+	n := 2 * k.order
+	f := identity(n)
+	for i := 0; i < n-2; i++ {
+		f[i][i+2] = dt
+	}
+	if k.order == 3 {
+		f[0][4] = dt * dt / 2
+		f[1][5] = dt * dt / 2
+	}
+	return f
+}
+
+// Discretized continuous white noise model of the highest derivative.
+func (k *Kalman) processNoise(dt float64) matrix {
+	var q1 [][]float64
+	dt2 := dt * dt
+	dt3 := dt2 * dt
+	if k.order == 2 {
+		q1 = [][]float64{
+			{dt3 / 3, dt2 / 2},
+			{dt2 / 2, dt},
+		}
+	} else {
+		dt4 := dt3 * dt
+		dt5 := dt4 * dt
+		q1 = [][]float64{
+			{dt5 / 20, dt4 / 8, dt3 / 6},
+			{dt4 / 8, dt3 / 3, dt2 / 2},
+			{dt3 / 6, dt2 / 2, dt},
+		}
+	}
+
+	n := 2 * k.order
+	q := newMatrix(n, n)
+	for i := 0; i < k.order; i++ {
+		for j := 0; j < k.order; j++ {
+			v := q1[i][j] * k.cfg.ProcessNoise
+			q[2*i][2*j] = v
+			q[2*i+1][2*j+1] = v
+		}
+	}
+	return q
+}
+
+type matrix [][]float64
+
+func newMatrix(rows, cols int) matrix {
+	m := make(matrix, rows)
+	for i := range m {
+		m[i] = make([]float64, cols)
+	}
+	return m
+}
+
+func identity(n int) matrix {
+	m := newMatrix(n, n)
+	for i := range m {
+		m[i][i] = 1
+	}
+	return m
+}
+
+func (m matrix) mul(o matrix) matrix {
+	res := newMatrix(len(m), len(o[0]))
+	for i := range m {
+		for j := range o[0] {
+			var v float64
+			for k := range o {
+				v += m[i][k] * o[k][j]
+			}
+			res[i][j] = v
+		}
+	}
+	return res
+}
+
+func (m matrix) mulVec(v []float64) []float64 {
+	res := make([]float64, len(m))
+	for i := range m {
+		for j := range v {
+			res[i] += m[i][j] * v[j]
+		}
+	}
+	return res
+}
+
+func (m matrix) add(o matrix) matrix {
+	res := newMatrix(len(m), len(m[0]))
+	for i := range m {
+		for j := range m[i] {
+			res[i][j] = m[i][j] + o[i][j]
+		}
+	}
+	return res
+}
+
+func (m matrix) transpose() matrix {
+	res := newMatrix(len(m[0]), len(m))
+	for i := range m {
+		for j := range m[i] {
+			res[j][i] = m[i][j]
+		}
+	}
+	return res
+}
+
+func (m matrix) clone() [][]float64 {
+	res := newMatrix(len(m), len(m[0]))
+	for i := range m {
+		copy(res[i], m[i])
+	}
+	return res
+}
+
+// Removes the asymmetry caused by rounding errors.
+func (m matrix) symmetrize() {
+	for i := range m {
+		for j := i + 1; j < len(m); j++ {
+			v := (m[i][j] + m[j][i]) / 2
+			m[i][j] = v
+			m[j][i] = v
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package track
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// Frame interval of the synthetic tracks.
+const kalmanTestDt = 1.0 / 30
+
+// A synthetic track with constant acceleration.
+type kalmanTrack struct {
+	x, y   float64
+	vx, vy float64
+	ax, ay float64
+}
+
+func (tr kalmanTrack) at(t float64) (x, y, vx, vy float64) {
+	return tr.x + tr.vx*t + tr.ax*t*t/2, tr.y + tr.vy*t + tr.ay*t*t/2, tr.vx + tr.ax*t, tr.vy + tr.ay*t
+}
+
+// Feeds the filter with noisy measurements of the track for the given number of frames, and returns the
+// time of the last one.
+func feedKalman(k *Kalman, tr kalmanTrack, frames int, noise float64, rnd *rand.Rand) float64 {
+	var t float64
+	for i := 0; i < frames; i++ {
+		t = float64(i) * kalmanTestDt
+		if i > 0 {
+			k.Predict(kalmanTestDt)
+		}
+		x, y, _, _ := tr.at(t)
+		k.Correct(x+noise*rnd.NormFloat64(), y+noise*rnd.NormFloat64())
+	}
+	return t
+}
+
+// Checks that the error of the estimate is within 3 standard deviations of the filter's covariance, and that
+// the standard deviation is below maxStd.
+func checkKalmanEstimate(t *testing.T, name string, s KalmanState, i int, est, want, maxStd float64) {
+	t.Helper()
+	std := math.Sqrt(s.Covariance[i][i])
+	if math.Abs(est-want) > 3*std || std > maxStd {
+		t.Errorf("%s: state %d is %.3f, want %.3f, std %.3f", name, i, est, want, std)
+	}
+}
+
+func TestKalmanConvergence(t *testing.T) {
+	cv := kalmanTrack{x: 10, y: 200, vx: 30, vy: -20}
+	ca := kalmanTrack{x: 10, y: 200, vx: 5, vy: -10, ax: 40, ay: 20}
+	tests := []struct {
+		name  string
+		model KalmanModel
+		track kalmanTrack
+	}{
+		{"cv model on cv track", KalmanModelConstantVelocity, cv},
+		{"ca model on cv track", KalmanModelConstantAcceleration, cv},
+		{"ca model on ca track", KalmanModelConstantAcceleration, ca},
+	}
+	for _, tt := range tests {
+		k := NewKalman(KalmanConfig{Model: tt.model, ProcessNoise: 1, MeasurementNoise: 0.5})
+		last := feedKalman(k, tt.track, 150, 0.5, rand.New(rand.NewSource(1)))
+
+		s := k.State()
+		x, y, vx, vy := tt.track.at(last)
+		checkKalmanEstimate(t, tt.name, s, 0, s.X, x, 0.3)
+		checkKalmanEstimate(t, tt.name, s, 1, s.Y, y, 0.3)
+		checkKalmanEstimate(t, tt.name, s, 2, s.VX, vx, 2)
+		checkKalmanEstimate(t, tt.name, s, 3, s.VY, vy, 2)
+		if tt.model == KalmanModelConstantAcceleration {
+			checkKalmanEstimate(t, tt.name, s, 4, s.AX, tt.track.ax, 10)
+			checkKalmanEstimate(t, tt.name, s, 5, s.AY, tt.track.ay, 10)
+		} else if s.AX != 0 || s.AY != 0 || len(s.Covariance) != 4 {
+			t.Errorf("%s: acceleration %v, %v, %d rows of covariance", tt.name, s.AX, s.AY, len(s.Covariance))
+		}
+		// The filter averages the measurement noise.
+		if std := s.PositionStd(); std >= 0.5 {
+			t.Errorf("%s: position std %v", tt.name, std)
+		}
+	}
+}
+
+func TestKalmanCoasting(t *testing.T) {
+	for _, model := range []KalmanModel{KalmanModelConstantVelocity, KalmanModelConstantAcceleration} {
+		k := NewKalman(KalmanConfig{Model: model, ProcessNoise: 1, MeasurementNoise: 0.5, MaxCoastTime: 1})
+		tr := kalmanTrack{x: 10, y: 200, vx: 30, vy: -20}
+		last := feedKalman(k, tr, 150, 0.5, rand.New(rand.NewSource(2)))
+
+		// The prediction continues on the track, and gets more uncertain.
+		std := k.State().PositionStd()
+		for i := 1; i <= 27; i++ {
+			k.Predict(kalmanTestDt)
+			s := k.State()
+			x, y, _, _ := tr.at(last + float64(i)*kalmanTestDt)
+			if math.Abs(s.X-x) > 3*math.Sqrt(s.Covariance[0][0]) || math.Abs(s.Y-y) > 3*math.Sqrt(s.Covariance[1][1]) {
+				t.Errorf("%s: coasting position %.3f, %.3f, want %.3f, %.3f", model, s.X, s.Y, x, y)
+			}
+			if s.PositionStd() <= std {
+				t.Errorf("%s: position std %v didn't grow from %v", model, s.PositionStd(), std)
+			}
+			std = s.PositionStd()
+			if want := float64(i) * kalmanTestDt; math.Abs(s.CoastTime-want) > 1e-9 {
+				t.Errorf("%s: coast time %v, want %v", model, s.CoastTime, want)
+			}
+		}
+
+		// A measurement ends coasting.
+		x, y, _, _ := tr.at(last + 28*kalmanTestDt)
+		k.Predict(kalmanTestDt)
+		k.Correct(x, y)
+		if s := k.State(); s.CoastTime != 0 {
+			t.Errorf("%s: coast time %v after a measurement", model, s.CoastTime)
+		}
+
+		// The filter is reset after the maximum coast time.
+		for i := 0; i < 29; i++ {
+			k.Predict(kalmanTestDt)
+		}
+		if !k.Initialized() {
+			t.Fatalf("%s: reset before the maximum coast time", model)
+		}
+		k.Predict(2 * kalmanTestDt)
+		if k.Initialized() {
+			t.Fatalf("%s: not reset after the maximum coast time", model)
+		}
+		k.Predict(kalmanTestDt)
+		if k.Initialized() || k.Distance(1000, 1000) != 0 {
+			t.Errorf("%s: predicting after reset", model)
+		}
+
+		// The next measurement initializes it again, without velocity.
+		k.Correct(50, 60)
+		if s := k.State(); !k.Initialized() || s.X != 50 || s.Y != 60 || s.VX != 0 || s.VY != 0 {
+			t.Errorf("%s: state %+v after reinit", model, s)
+		}
+	}
+}
+
+func TestKalmanGating(t *testing.T) {
+	const gate = 5
+	k := NewKalman(KalmanConfig{ProcessNoise: 1, MeasurementNoise: 0.5})
+	tr := kalmanTrack{x: 10, y: 200, vx: 30, vy: -20}
+	rnd := rand.New(rand.NewSource(3))
+	last := feedKalman(k, tr, 150, 0.5, rnd)
+
+	// Measurements are only used if they are within the gate, like the target state machine does. Every
+	// fifth one is an outlier, like a star passing by.
+	rejected := 0
+	for i := 1; i <= 60; i++ {
+		t0 := last + float64(i)*kalmanTestDt
+		x, y, _, _ := tr.at(t0)
+		x += 0.5 * rnd.NormFloat64()
+		y += 0.5 * rnd.NormFloat64()
+		outlier := i%5 == 0
+		if outlier {
+			x += 15
+			y -= 10
+		}
+		k.Predict(kalmanTestDt)
+		d := k.Distance(x, y)
+		if (d > gate) != outlier {
+			t.Errorf("frame %d: distance %.2f, outlier %v", i, d, outlier)
+		}
+		if d > gate {
+			rejected++
+			continue
+		}
+		k.Correct(x, y)
+	}
+	if rejected != 12 {
+		t.Errorf("%d outliers rejected", rejected)
+	}
+	// The outliers didn't pull the estimate.
+	s := k.State()
+	x, y, vx, vy := tr.at(last + 60*kalmanTestDt)
+	checkKalmanEstimate(t, "gated", s, 0, s.X, x, 0.3)
+	checkKalmanEstimate(t, "gated", s, 1, s.Y, y, 0.3)
+	checkKalmanEstimate(t, "gated", s, 2, s.VX, vx, 2)
+	checkKalmanEstimate(t, "gated", s, 3, s.VY, vy, 2)
+}
+
+func TestKalmanConfig(t *testing.T) {
+	var c KalmanConfig
+	c.SetDefaults()
+	if err := c.Validate(); err != nil || c.Model != KalmanModelConstantVelocity {
+		t.Errorf("defaults %+v, %v", c, err)
+	}
+	c.Model = "xyz"
+	if err := c.Validate(); err == nil {
+		t.Error("unknown model accepted")
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/nonoo/jampec/track"
+	"gocv.io/x/gocv"
+	"gocv.io/x/gocv/contrib"
+)
+
+// tracker is implemented by the object tracking algorithms. The OpenCV trackers satisfy it as they are, our
+// own algorithms only have to implement the same methods.
+type tracker interface {
+	// Init starts tracking the given rectangle on img. Returns false if the rectangle can't be tracked.
+	Init(img gocv.Mat, rect image.Rectangle) bool
+	// Update locates the tracked object on img. Returns false if it was lost.
+	Update(img gocv.Mat) (image.Rectangle, bool)
+	Close() error
+}
+
+const defaultTrackerAlgorithm = "csrt"
+
+// Available algorithms, in the order they are switched through from the keyboard.
+var trackerAlgorithms = []string{"csrt", "kcf", "mil", "centroid"}
+
+func newTracker(algorithm string, cfg DevConfig) (tracker, error) {
+	switch algorithm {
+	case "centroid":
+		return newCentroidTracker(cfg.Tracker.Centroid), nil
+	case "csrt":
+		return contrib.NewTrackerCSRT(), nil
+	case "kcf":
+		return contrib.NewTrackerKCF(), nil
+	case "mil":
+		return gocv.NewTrackerMIL(), nil
+	}
+	return nil, fmt.Errorf("unknown tracker algorithm %q", algorithm)
+}
+
+func validTrackerAlgorithm(algorithm string) bool {
+	for _, a := range trackerAlgorithms {
+		if a == algorithm {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the algorithm which follows the given one in trackerAlgorithms.
+func nextTrackerAlgorithm(algorithm string) string {
+	for i, a := range trackerAlgorithms {
+		if a == algorithm {
+			return trackerAlgorithms[(i+1)%len(trackerAlgorithms)]
+		}
+	}
+	return trackerAlgorithms[0]
+}
+
+// The OpenCV trackers can't find features in rectangles of a few pixels, which is usually the size of a
+// satellite. This grows the rectangle around its center to at least minSize, keeping it inside the image.
+func growTrackerRect(rect image.Rectangle, minSize int, imgSize image.Point) image.Rectangle {
+	grow := func(min, max, limit int) (int, int) {
+		if max-min >= minSize {
+			return min, max
+		}
+		c := (min + max) / 2
+		min = c - minSize/2
+		max = min + minSize
+		if min < 0 {
+			min, max = 0, minSize
+		}
+		if max > limit {
+			min, max = limit-minSize, limit
+			if min < 0 {
+				min = 0
+			}
+		}
+		return min, max
+	}
+	rect.Min.X, rect.Max.X = grow(rect.Min.X, rect.Max.X, imgSize.X)
+	rect.Min.Y, rect.Max.Y = grow(rect.Min.Y, rect.Max.Y, imgSize.Y)
+	return rect
+}
+
+// centroidTracker adapts track.CentroidTracker to the tracker interface.
+type centroidTracker struct {
+	t    *track.CentroidTracker
+	gray gocv.Mat
+	last track.Centroid
+}
+
+func newCentroidTracker(cfg track.CentroidConfig) *centroidTracker {
+	return &centroidTracker{
+		t:    track.NewCentroidTracker(cfg),
+		gray: gocv.NewMat(),
+	}
+}
+
+func (t *centroidTracker) Init(img gocv.Mat, rect image.Rectangle) bool {
+	if !t.t.Init(t.toGray(img), rect) {
+		return false
+	}
+	t.last = t.t.Last()
+	return true
+}
+
+func (t *centroidTracker) Update(img gocv.Mat) (image.Rectangle, bool) {
+	c, ok := t.t.Update(t.toGray(img))
+	if !ok {
+		return image.Rectangle{}, false
+	}
+	t.last = c
+	// Some margin around the pixels makes the rectangle visible on the overlay.
+	return c.Bounds.Inset(-3), true
+}
+
+func (t *centroidTracker) Close() error {
+	return t.gray.Close()
+}
+
+// Centers the search window of the next update on the given position.
+func (t *centroidTracker) predict(x, y float64) {
+	t.t.Predict(x, y)
+}
+
+// Returns the last found centroid.
+func (t *centroidTracker) centroid() track.Centroid {
+	return t.last
+}
+
+func (t *centroidTracker) toGray(img gocv.Mat) *image.Gray {
+	if img.Channels() == 1 {
+		img.CopyTo(&t.gray)
+	} else {
+		gocv.CvtColor(img, &t.gray, gocv.ColorBGRToGray)
+	}
+	return &image.Gray{
+		Pix:    t.gray.ToBytes(),
+		Stride: t.gray.Cols(),
+		Rect:   image.Rect(0, 0, t.gray.Cols(), t.gray.Rows()),
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"time"
+
+	"github.com/nonoo/jampec/guide"
+	"github.com/nonoo/jampec/track"
+)
+
+type trackState int
+
+const (
+	// Nothing is tracked.
+	trackStateIdle = trackState(iota)
+	// Automatic acquisition is looking for the target.
+	trackStateAcquiring
+	// The tracker follows the target, and its measurements are accepted.
+	trackStateTracking
+	// The tracker lost the target or its measurements were rejected, the position is predicted by the filter.
+	trackStateCoasting
+	// The filter stopped predicting, the tracker is stopped.
+	trackStateLost
+)
+
+func (t trackState) String() string {
+	switch t {
+	case trackStateIdle:
+		return "IDLE"
+	case trackStateAcquiring:
+		return "ACQUIRING"
+	case trackStateTracking:
+		return "TRACKING"
+	case trackStateCoasting:
+		return "COASTING"
+	case trackStateLost:
+		return "LOST"
+	}
+	return "UNKNOWN"
+}
+
+// Returns true if the position of the target is known, so the mount can be guided by it.
+func (t trackState) locked() bool {
+	return t == trackStateTracking || t == trackStateCoasting
+}
+
+// Returns false if the tracker's measurement is too weak, or it's not consistent with the motion of the
+// target, so the tracker has probably drifted onto noise or another object. Called by the tracker loop.
+func (s *camStruct) acceptMeasurement(filter *track.Kalman, center guide.Point, centroid *track.Centroid) bool {
+	cfg := s.config.TrackState
+	if centroid != nil && centroid.SNR < cfg.MinSNR {
+		return false
+	}
+	return !filter.Initialized() || filter.Distance(center.X, center.Y) <= cfg.Gate
+}
+
+func (s *camStruct) setTrackState(state trackState) {
+	if state == s.trackState {
+		return
+	}
+	log.Print("cam ", s.nr, " ", s.trackState, " -> ", state)
+	bus.Publish(trackStateChangedEvent{cam: s.nr, from: s.trackState, to: state, time: time.Now()})
+	s.trackState = state
+	s.trackStateSince = time.Now()
+}
+
+// Steps the target state machine using the result of the tracker on the last frame. It's not stepped
+// during calibration, as the mount moves the target faster than the motion model allows.
+func (s *camStruct) updateTrackState(td *trackData) {
+	if s.calibration != nil {
+		return
+	}
+
+	switch {
+	case s.acquisition != nil:
+		if t := s.config.TrackState.AcquireTimeout; s.trackState == trackStateAcquiring && t > 0 &&
+			time.Since(s.trackStateSince).Seconds() > t {
+
+			log.Print("cam ", s.nr, " acquisition timed out")
+			s.stopAcquisition()
+			s.setTrackState(trackStateIdle)
+			return
+		}
+		s.setTrackState(trackStateAcquiring)
+	case td.trackerActive && !td.rect.Empty():
+		s.setTrackState(trackStateTracking)
+	case td.trackerActive && td.filterValid:
+		s.setTrackState(trackStateCoasting)
+	case td.trackerActive:
+		// The filter gave up predicting, so the tracker is following something which doesn't move like the
+		// target, or nothing at all.
+		s.setTrackState(trackStateLost)
+		s.selectedRect = image.Rectangle{}
+		s.reinitTrackerChan <- &image.Rectangle{}
+	case s.trackState == trackStateLost:
+		if s.config.TrackState.Reacquire &&
+			time.Since(s.trackStateSince).Seconds() >= s.config.TrackState.ReacquireDelay {
+
+			s.toggleAcquisition()
+			s.setTrackState(trackStateAcquiring)
+		}
+	default:
+		s.setTrackState(trackStateIdle)
+	}
+}
+
+func (s *camStruct) trackStateColor() color.RGBA {
+	switch s.trackState {
+	case trackStateTracking:
+		if s.controlActive {
+			return s.controlActiveTrackerRectColor
+		}
+		return s.trackerRectColor
+	case trackStateIdle:
+		return s.trackerRectColor
+	}
+	return s.selectedRectColor
+}
@@ -0,0 +1,286 @@
+'use strict';
+
+const logMaxLines = 500;
+// Jog commands are repeated while the button is held, the mount stops if they don't arrive.
+const jogRepeatMs = 300;
+
+const cams = new Map();
+let ws = null;
+
+function send(cmd) {
+	if (ws && ws.readyState === WebSocket.OPEN) {
+		ws.send(JSON.stringify(cmd));
+	}
+}
+
+function connect() {
+	ws = new WebSocket((location.protocol === 'https:' ? 'wss://' : 'ws://') + location.host + '/ws');
+	const conn = document.getElementById('connection');
+	ws.onopen = () => {
+		conn.textContent = 'connected';
+		conn.className = 'good';
+	};
+	ws.onclose = () => {
+		conn.textContent = 'disconnected';
+		conn.className = 'bad';
+		setTimeout(connect, 2000);
+	};
+	ws.onmessage = (e) => {
+		const msg = JSON.parse(e.data);
+		switch (msg.type) {
+		case 'status':
+			msg.cams.forEach(updateCam);
+			updateMount();
+			break;
+		case 'log':
+			appendLog(msg.lines);
+			break;
+		case 'activated':
+			updateActivated(msg.activated);
+			break;
+		case 'mount':
+			updateMountTelemetry(msg.mount);
+			break;
+		case 'trackState':
+			appendLog(['cam ' + msg.trackState.cam + ': ' + msg.trackState.from + ' -> ' + msg.trackState.to]);
+			break;
+		case 'error':
+			appendLog(['error: ' + msg.error]);
+			break;
+		}
+	};
+}
+
+function fmt(v, digits) {
+	return v === undefined || v === null ? '–' : v.toFixed(digits);
+}
+
+function createCam(st) {
+	const el = document.getElementById('cam-template').content.firstElementChild.cloneNode(true);
+	const cam = {nr: st.cam, el: el, status: st};
+	el.querySelector('.name').textContent = 'cam ' + st.cam + ' (dev ' + st.devNum + ')';
+	el.querySelector('img').src = '/cam/' + st.cam + '/mjpeg';
+	el.querySelectorAll('.cam-buttons button').forEach((b) => {
+		b.onclick = () => send({cmd: b.dataset.cmd, cam: cam.nr});
+	});
+	setupSelection(cam);
+	document.getElementById('cams').appendChild(el);
+
+	const opt = document.createElement('option');
+	opt.value = st.cam;
+	opt.textContent = 'cam ' + st.cam;
+	document.getElementById('jog-cam').appendChild(opt);
+
+	cams.set(st.cam, cam);
+	return cam;
+}
+
+function updateCam(st) {
+	const cam = cams.get(st.cam) || createCam(st);
+	cam.status = st;
+	cam.el.classList.toggle('active', st.active);
+	cam.el.querySelector('.state').textContent = st.state + ' ' + st.tracker.toUpperCase();
+
+	const info = [];
+	if (st.mount) {
+		info.push('mount az ' + fmt(st.mount.az, 2) + ' el ' + fmt(st.mount.el, 2));
+	}
+	if (st.target) {
+		info.push((st.target.satNum ? st.target.satNum + ' ' : '') + st.target.name +
+			(st.target.up ? ' az ' + fmt(st.target.az, 2) + ' el ' + fmt(st.target.el, 2) : ' below horizon'));
+	}
+	if (st.recording) {
+		info.push('REC' + (st.droppedFrames > 0 ? ' ' + st.droppedFrames + ' dropped' : ''));
+	}
+	cam.el.querySelector('.cam-info').textContent = info.join(' | ');
+
+	const buttons = cam.el.querySelectorAll('.cam-buttons button');
+	buttons.forEach((b) => {
+		const on = {
+			activate: st.active,
+			showOriginal: st.showOriginalImage,
+			acquire: st.acquiring,
+			calibrate: st.calibrating,
+			record: st.recording,
+		}[b.dataset.cmd];
+		b.classList.toggle('on', !!on);
+	});
+}
+
+// Drawing a rectangle on the video selects the target, right click cancels.
+function setupSelection(cam) {
+	const img = cam.el.querySelector('img');
+	const canvas = cam.el.querySelector('canvas');
+	const ctx = canvas.getContext('2d');
+	let start = null;
+	let end = null;
+
+	// Returns the position in image pixels.
+	const pos = (e) => {
+		const r = canvas.getBoundingClientRect();
+		const w = cam.status.width || img.naturalWidth;
+		const h = cam.status.height || img.naturalHeight;
+		return {
+			x: Math.round((e.clientX - r.left) * w / r.width),
+			y: Math.round((e.clientY - r.top) * h / r.height),
+		};
+	};
+	const draw = () => {
+		canvas.width = cam.status.width || img.naturalWidth;
+		canvas.height = cam.status.height || img.naturalHeight;
+		ctx.clearRect(0, 0, canvas.width, canvas.height);
+		if (start && end) {
+			ctx.strokeStyle = 'red';
+			ctx.lineWidth = 2;
+			ctx.strokeRect(start.x, start.y, end.x - start.x, end.y - start.y);
+		}
+	};
+
+	canvas.onmousedown = (e) => {
+		if (e.button !== 0) {
+			return;
+		}
+		start = end = pos(e);
+		draw();
+	};
+	canvas.onmousemove = (e) => {
+		if (start) {
+			end = pos(e);
+			draw();
+		}
+	};
+	canvas.onmouseup = (e) => {
+		if (e.button !== 0 || !start) {
+			return;
+		}
+		end = pos(e);
+		const rect = [Math.min(start.x, end.x), Math.min(start.y, end.y), Math.max(start.x, end.x),
+			Math.max(start.y, end.y)];
+		if (rect[2] > rect[0] && rect[3] > rect[1]) {
+			send({cmd: 'select', cam: cam.nr, rect: rect});
+		}
+		start = end = null;
+		draw();
+	};
+	canvas.onmouseleave = () => {
+		start = end = null;
+		draw();
+	};
+	canvas.oncontextmenu = (e) => {
+		e.preventDefault();
+		send({cmd: 'cancel', cam: cam.nr});
+	};
+}
+
+function jogCam() {
+	return parseInt(document.getElementById('jog-cam').value, 10);
+}
+
+function updateActivated(a) {
+	const cam = cams.get(a.cam);
+	if (cam) {
+		cam.status.active = a.active;
+		cam.el.classList.toggle('active', a.active);
+	}
+	appendLog(['cam ' + a.cam + (a.active ? ' activated' : ' deactivated')]);
+}
+
+function updateMountTelemetry(m) {
+	const cam = cams.get(m.cam);
+	if (cam) {
+		cam.mount = m;
+		updateMount();
+	}
+}
+
+// Shows the last mount poll, or the position of the last frame's status until a poll arrives.
+function updateMount() {
+	const cam = cams.get(jogCam());
+	const el = document.getElementById('mount-pos');
+	const m = cam && (cam.mount || cam.status.mount);
+	if (m && m.error) {
+		el.textContent = 'mount error: ' + m.error;
+	} else if (m) {
+		el.textContent = 'az ' + fmt(m.az, 3) + ' el ' + fmt(m.el, 3);
+	} else {
+		el.textContent = 'az – el –';
+	}
+}
+
+function setupJog() {
+	let timer = null;
+	const stop = () => {
+		if (timer) {
+			clearInterval(timer);
+			timer = null;
+			send({cmd: 'jog', cam: jogCam(), az: 0, el: 0});
+		}
+	};
+	document.querySelectorAll('#jog button').forEach((b) => {
+		const az = parseFloat(b.dataset.az);
+		const el = parseFloat(b.dataset.el);
+		b.onpointerdown = (e) => {
+			e.preventDefault();
+			stop();
+			if (az === 0 && el === 0) {
+				send({cmd: 'jog', cam: jogCam(), az: 0, el: 0});
+				return;
+			}
+			const rate = parseFloat(document.getElementById('jog-rate').value);
+			const jog = () => send({cmd: 'jog', cam: jogCam(), az: az * rate, el: el * rate});
+			jog();
+			timer = setInterval(jog, jogRepeatMs);
+		};
+		b.onpointerup = stop;
+		b.onpointerleave = stop;
+	});
+	window.addEventListener('blur', stop);
+}
+
+function appendLog(lines) {
+	const el = document.getElementById('log');
+	const atBottom = el.scrollTop + el.clientHeight >= el.scrollHeight - 5;
+	const all = (el.textContent ? el.textContent.split('\n') : []).concat(lines);
+	el.textContent = all.slice(-logMaxLines).join('\n');
+	if (atBottom) {
+		el.scrollTop = el.scrollHeight;
+	}
+}
+
+function formatTime(t) {
+	return t.replace('T', ' ').replace(/\.\d+/, '').replace('Z', '');
+}
+
+async function loadPasses() {
+	const tbody = document.querySelector('#passes tbody');
+	try {
+		const resp = await fetch('/api/passes?hours=24');
+		if (!resp.ok) {
+			throw new Error(await resp.text());
+		}
+		const passes = await resp.json();
+		tbody.textContent = '';
+		passes.forEach((p) => {
+			const tr = document.createElement('tr');
+			[p.satNum + ' ' + p.name, formatTime(p.aos.time), fmt(p.tca.el, 1) + '°', formatTime(p.los.time),
+				p.visible ? 'yes' : 'no'].forEach((v) => {
+				const td = document.createElement('td');
+				td.textContent = v;
+				tr.appendChild(td);
+			});
+			tbody.appendChild(tr);
+		});
+		if (passes.length === 0) {
+			tbody.innerHTML = '<tr><td colspan="5">No passes, or no target is set.</td></tr>';
+		}
+	} catch (err) {
+		appendLog(['can\'t load passes: ' + err.message]);
+	}
+}
+
+document.getElementById('passes-refresh').onclick = loadPasses;
+document.getElementById('jog-cam').onchange = updateMount;
+setupJog();
+connect();
+loadPasses();
+setInterval(loadPasses, 5 * 60 * 1000);
@@ -0,0 +1,253 @@
+// Package websocket implements the server side of the WebSocket protocol (RFC 6455), enough for exchanging
+// text messages with browsers. Extensions and subprotocols are not supported.
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	opContinuation = 0x0
+	OpText         = 0x1
+	OpBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+const handshakeGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Messages bigger than this are rejected.
+const MaxMessageSize = 1 << 20
+
+var (
+	ErrClosed        = errors.New("websocket: connection closed")
+	ErrMessageTooBig = errors.New("websocket: message too big")
+	ErrProtocolError = errors.New("websocket: protocol error")
+	errBadHandshake  = errors.New("websocket: bad handshake")
+	errNotHijackable = errors.New("websocket: response can't be hijacked")
+)
+
+type Conn struct {
+	conn net.Conn
+	br   *bufio.Reader
+
+	writeMutex sync.Mutex
+	closed     bool
+}
+
+func headerContains(h http.Header, name, value string) bool {
+	for _, v := range h[name] {
+		for _, s := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(s), value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CheckOrigin returns true if the request was sent by a page served from the same host, or from one of the
+// allowed origins (like "http://example.com:8080"), so other sites can't open connections with the
+// browser's credentials. Requests without an Origin header are not sent by browsers, and they are allowed.
+func CheckOrigin(r *http.Request, allowed []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, a := range allowed {
+		if strings.EqualFold(strings.TrimSuffix(a, "/"), origin) {
+			return true
+		}
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// Upgrade upgrades the HTTP connection to a WebSocket connection. On error an HTTP error response is sent.
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	key := r.Header.Get("Sec-Websocket-Key")
+	if r.Method != http.MethodGet || !headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") || r.Header.Get("Sec-Websocket-Version") != "13" ||
+		key == "" {
+
+		http.Error(w, "bad websocket handshake", http.StatusBadRequest)
+		return nil, errBadHandshake
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket not supported", http.StatusInternalServerError)
+		return nil, errNotHijackable
+	}
+	conn, brw, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	h := sha1.Sum([]byte(key + handshakeGUID))
+	resp := "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(h[:]) + "\r\n\r\n"
+	if _, err := conn.Write([]byte(resp)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &Conn{conn: conn, br: brw.Reader}, nil
+}
+
+// ReadMessage returns the next text or binary message. Control frames are handled internally. Returns
+// ErrClosed if the peer closed the connection.
+func (c *Conn) ReadMessage() (opcode int, data []byte, err error) {
+	var msgOpcode int
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+		switch op {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			c.writeFrame(opClose, payload)
+			c.conn.Close()
+			return 0, nil, ErrClosed
+		case opContinuation:
+			if msgOpcode == 0 {
+				return 0, nil, ErrProtocolError
+			}
+		case OpText, OpBinary:
+			if msgOpcode != 0 {
+				return 0, nil, ErrProtocolError
+			}
+			msgOpcode = op
+		default:
+			return 0, nil, ErrProtocolError
+		}
+
+		if len(data)+len(payload) > MaxMessageSize {
+			return 0, nil, ErrMessageTooBig
+		}
+		data = append(data, payload...)
+		if fin {
+			return msgOpcode, data, nil
+		}
+	}
+}
+
+func (c *Conn) readFrame() (fin bool, opcode int, payload []byte, err error) {
+	var h [2]byte
+	if _, err = io.ReadFull(c.br, h[:]); err != nil {
+		return
+	}
+	fin = h[0]&0x80 != 0
+	opcode = int(h[0] & 0x0f)
+	if h[0]&0x70 != 0 {
+		err = ErrProtocolError
+		return
+	}
+	// Clients have to mask their frames.
+	if h[1]&0x80 == 0 {
+		err = ErrProtocolError
+		return
+	}
+
+	length := uint64(h[1] & 0x7f)
+	switch length {
+	case 126:
+		var b [2]byte
+		if _, err = io.ReadFull(c.br, b[:]); err != nil {
+			return
+		}
+		length = uint64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		if _, err = io.ReadFull(c.br, b[:]); err != nil {
+			return
+		}
+		length = binary.BigEndian.Uint64(b[:])
+	}
+	// Control frames can't be fragmented, and their payload is at most 125 bytes.
+	if opcode&0x8 != 0 && (!fin || length > 125) {
+		err = ErrProtocolError
+		return
+	}
+	if length > MaxMessageSize {
+		err = ErrMessageTooBig
+		return
+	}
+
+	var mask [4]byte
+	if _, err = io.ReadFull(c.br, mask[:]); err != nil {
+		return
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(c.br, payload); err != nil {
+		return
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return
+}
+
+// WriteMessage sends a text or binary message. It can be called concurrently with ReadMessage.
+func (c *Conn) WriteMessage(opcode int, data []byte) error {
+	return c.writeFrame(opcode, data)
+}
+
+func (c *Conn) writeFrame(opcode int, data []byte) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	if c.closed {
+		return ErrClosed
+	}
+
+	b := make([]byte, 0, len(data)+10)
+	b = append(b, 0x80|byte(opcode))
+	switch {
+	case len(data) < 126:
+		b = append(b, byte(len(data)))
+	case len(data) <= 0xffff:
+		b = append(b, 126, byte(len(data)>>8), byte(len(data)))
+	default:
+		b = append(b, 127)
+		var l [8]byte
+		binary.BigEndian.PutUint64(l[:], uint64(len(data)))
+		b = append(b, l[:]...)
+	}
+	b = append(b, data...)
+	_, err := c.conn.Write(b)
+	if opcode == opClose {
+		c.closed = true
+	}
+	return err
+}
+
+// SetWriteDeadline sets the deadline of future writes, so slow peers can't block the writer forever.
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+// Close sends a close frame and closes the connection.
+func (c *Conn) Close() error {
+	c.writeFrame(opClose, []byte{0x03, 0xe8}) // Normal closure.
+	return c.conn.Close()
+}
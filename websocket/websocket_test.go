@@ -0,0 +1,368 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCheckOrigin(t *testing.T) {
+	tests := []struct {
+		host    string
+		origin  string
+		allowed []string
+		want    bool
+	}{
+		{"localhost:8080", "", nil, true},
+		{"localhost:8080", "http://localhost:8080", nil, true},
+		{"192.168.1.2:8080", "http://192.168.1.2:8080", nil, true},
+		{"Jampec.lan:8080", "https://jampec.lan:8080", nil, true},
+		{"localhost:8080", "http://localhost:8081", nil, false},
+		{"localhost:8080", "http://evil.example.com", nil, false},
+		{"localhost:8080", "null", nil, false},
+		{"localhost:8080", "http://dashboard.lan", []string{"http://dashboard.lan/"}, true},
+		{"localhost:8080", "http://dashboard.lan:8000", []string{"http://dashboard.lan"}, false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/ws", nil)
+		r.Host = tt.host
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+		if got := CheckOrigin(r, tt.allowed); got != tt.want {
+			t.Errorf("CheckOrigin(host %s, origin %q, allowed %v) = %v, want %v", tt.host, tt.origin, tt.allowed,
+				got, tt.want)
+		}
+	}
+}
+
+// Connects to a test server, and returns the client side and the upgraded server side of the connection.
+func dialTest(t *testing.T) (net.Conn, *bufio.Reader, *Conn) {
+	t.Helper()
+	connChan := make(chan *Conn, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := Upgrade(w, r)
+		if err != nil {
+			t.Error(err)
+		}
+		connChan <- c
+	}))
+	t.Cleanup(srv.Close)
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	req := "GET /ws HTTP/1.1\r\nHost: " + srv.Listener.Addr().String() + "\r\nUpgrade: websocket\r\n" +
+		"Connection: keep-alive, Upgrade\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\n" +
+		"Sec-WebSocket-Version: 13\r\n\r\n"
+	if _, err := conn.Write([]byte(req)); err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The example key and accept value of the RFC.
+	if resp.StatusCode != http.StatusSwitchingProtocols ||
+		resp.Header.Get("Sec-Websocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("handshake response %v %v", resp.Status, resp.Header)
+	}
+	c := <-connChan
+	if c == nil {
+		t.FailNow()
+	}
+	t.Cleanup(func() { c.conn.Close() })
+	// Fails instead of blocking if a frame is not rejected.
+	c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return conn, br, c
+}
+
+// A frame sent by the client.
+type testFrame struct {
+	fin     bool
+	op      byte
+	payload []byte
+	// Frames are masked unless this is set.
+	unmasked bool
+	// The length in the header if it's not zero, the payload is not sent then.
+	length uint64
+}
+
+func (f testFrame) encode() []byte {
+	b := []byte{f.op}
+	if f.fin {
+		b[0] |= 0x80
+	}
+	var mask byte
+	if !f.unmasked {
+		mask = 0x80
+	}
+	l := uint64(len(f.payload))
+	if f.length > 0 {
+		l = f.length
+	}
+	switch {
+	case l < 126:
+		b = append(b, mask|byte(l))
+	case l <= 0xffff:
+		b = append(b, mask|126, byte(l>>8), byte(l))
+	default:
+		b = append(b, mask|127)
+		var lb [8]byte
+		binary.BigEndian.PutUint64(lb[:], l)
+		b = append(b, lb[:]...)
+	}
+	if f.length > 0 {
+		return b
+	}
+	key := []byte{0x12, 0x34, 0x56, 0x78}
+	if !f.unmasked {
+		b = append(b, key...)
+	}
+	for i, v := range f.payload {
+		if !f.unmasked {
+			v ^= key[i%4]
+		}
+		b = append(b, v)
+	}
+	return b
+}
+
+// Reads an unmasked server frame.
+func readServerFrame(t *testing.T, conn net.Conn, br *bufio.Reader) (fin bool, op byte, payload []byte) {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	var h [2]byte
+	if _, err := io.ReadFull(br, h[:]); err != nil {
+		t.Fatal(err)
+	}
+	if h[1]&0x80 != 0 {
+		t.Fatal("masked server frame")
+	}
+	l := uint64(h[1] & 0x7f)
+	switch l {
+	case 126:
+		var b [2]byte
+		io.ReadFull(br, b[:])
+		l = uint64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		io.ReadFull(br, b[:])
+		l = binary.BigEndian.Uint64(b[:])
+	}
+	payload = make([]byte, l)
+	if _, err := io.ReadFull(br, payload); err != nil {
+		t.Fatal(err)
+	}
+	return h[0]&0x80 != 0, h[0] & 0x0f, payload
+}
+
+func TestReadMessage(t *testing.T) {
+	text := func(s string) []byte { return []byte(s) }
+	long := bytes.Repeat([]byte("0123456789"), 7000)
+	tests := []struct {
+		name   string
+		frames []testFrame
+		op     int
+		data   []byte
+		err    error
+		// Control frames sent back by the server.
+		replies []testFrame
+	}{
+		{
+			name:   "7 bit length",
+			frames: []testFrame{{fin: true, op: OpText, payload: text("hello")}},
+			op:     OpText,
+			data:   text("hello"),
+		},
+		{
+			name:   "empty",
+			frames: []testFrame{{fin: true, op: OpBinary}},
+			op:     OpBinary,
+		},
+		{
+			name:   "16 bit length",
+			frames: []testFrame{{fin: true, op: OpBinary, payload: long[:126]}},
+			op:     OpBinary,
+			data:   long[:126],
+		},
+		{
+			name:   "64 bit length",
+			frames: []testFrame{{fin: true, op: OpText, payload: long}},
+			op:     OpText,
+			data:   long,
+		},
+		{
+			name: "fragmented with a ping",
+			frames: []testFrame{
+				{op: OpText, payload: text("hel")},
+				{op: opContinuation, payload: text("lo ")},
+				{fin: true, op: opPing, payload: text("ping")},
+				{fin: true, op: opContinuation, payload: text("world")},
+			},
+			op:      OpText,
+			data:    text("hello world"),
+			replies: []testFrame{{fin: true, op: opPong, payload: text("ping")}},
+		},
+		{
+			name: "pong ignored",
+			frames: []testFrame{
+				{fin: true, op: opPong, payload: text("x")},
+				{fin: true, op: OpText, payload: text("a")},
+			},
+			op:   OpText,
+			data: text("a"),
+		},
+		{
+			name:    "close",
+			frames:  []testFrame{{fin: true, op: opClose, payload: []byte{0x03, 0xe9}}},
+			err:     ErrClosed,
+			replies: []testFrame{{fin: true, op: opClose, payload: []byte{0x03, 0xe9}}},
+		},
+		{
+			name:   "unmasked",
+			frames: []testFrame{{fin: true, op: OpText, payload: text("hello"), unmasked: true}},
+			err:    ErrProtocolError,
+		},
+		{
+			name:   "reserved bits",
+			frames: []testFrame{{fin: true, op: 0x40 | OpText, payload: text("hello")}},
+			err:    ErrProtocolError,
+		},
+		{
+			name:   "unknown opcode",
+			frames: []testFrame{{fin: true, op: 0x3, payload: text("hello")}},
+			err:    ErrProtocolError,
+		},
+		{
+			name:   "continuation without a message",
+			frames: []testFrame{{fin: true, op: opContinuation, payload: text("hello")}},
+			err:    ErrProtocolError,
+		},
+		{
+			name: "new message inside a fragmented one",
+			frames: []testFrame{
+				{op: OpText, payload: text("hel")},
+				{fin: true, op: OpText, payload: text("lo")},
+			},
+			err: ErrProtocolError,
+		},
+		{
+			name:   "fragmented ping",
+			frames: []testFrame{{op: opPing, payload: text("ping")}},
+			err:    ErrProtocolError,
+		},
+		{
+			name:   "fragmented close",
+			frames: []testFrame{{op: opClose}},
+			err:    ErrProtocolError,
+		},
+		{
+			name:   "long ping",
+			frames: []testFrame{{fin: true, op: opPing, payload: long[:126]}},
+			err:    ErrProtocolError,
+		},
+		{
+			name:   "frame too big",
+			frames: []testFrame{{fin: true, op: OpBinary, length: MaxMessageSize + 1}},
+			err:    ErrMessageTooBig,
+		},
+		{
+			name: "message too big",
+			frames: []testFrame{
+				{op: OpBinary, payload: long},
+				{fin: true, op: opContinuation, payload: make([]byte, MaxMessageSize-len(long)+1)},
+			},
+			err: ErrMessageTooBig,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, br, c := dialTest(t)
+			go func() {
+				for _, f := range tt.frames {
+					if _, err := conn.Write(f.encode()); err != nil {
+						return
+					}
+				}
+			}()
+
+			op, data, err := c.ReadMessage()
+			if err != tt.err || op != tt.op || !bytes.Equal(data, tt.data) {
+				t.Errorf("read %d, %d bytes, %v, want %d, %d bytes, %v", op, len(data), err, tt.op,
+					len(tt.data), tt.err)
+			}
+			for _, want := range tt.replies {
+				fin, op, payload := readServerFrame(t, conn, br)
+				if fin != want.fin || op != want.op || !bytes.Equal(payload, want.payload) {
+					t.Errorf("reply %v, %x, %q, want %v, %x, %q", fin, op, payload, want.fin, want.op,
+						want.payload)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteMessage(t *testing.T) {
+	conn, br, c := dialTest(t)
+	for _, n := range []int{0, 125, 126, 0xffff, 0x10000} {
+		data := bytes.Repeat([]byte{'x'}, n)
+		go c.WriteMessage(OpText, data)
+		fin, op, payload := readServerFrame(t, conn, br)
+		if !fin || op != OpText || !bytes.Equal(payload, data) {
+			t.Errorf("%d bytes: read %v, %x, %d bytes", n, fin, op, len(payload))
+		}
+	}
+
+	c.Close()
+	if fin, op, payload := readServerFrame(t, conn, br); !fin || op != opClose ||
+		!bytes.Equal(payload, []byte{0x03, 0xe8}) {
+		t.Errorf("close frame %v, %x, % x", fin, op, payload)
+	}
+	if err := c.WriteMessage(OpText, []byte("x")); err != ErrClosed {
+		t.Errorf("write after close: %v", err)
+	}
+}
+
+func TestBadHandshake(t *testing.T) {
+	valid := func() *http.Request {
+		r := httptest.NewRequest("GET", "/ws", nil)
+		r.Header.Set("Connection", "Upgrade")
+		r.Header.Set("Upgrade", "websocket")
+		r.Header.Set("Sec-WebSocket-Version", "13")
+		r.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		return r
+	}
+	tests := []struct {
+		name   string
+		modify func(r *http.Request)
+		status int
+	}{
+		{"post", func(r *http.Request) { r.Method = "POST" }, http.StatusBadRequest},
+		{"no upgrade", func(r *http.Request) { r.Header.Del("Upgrade") }, http.StatusBadRequest},
+		{"no connection upgrade", func(r *http.Request) { r.Header.Set("Connection", "keep-alive") },
+			http.StatusBadRequest},
+		{"old version", func(r *http.Request) { r.Header.Set("Sec-WebSocket-Version", "8") },
+			http.StatusBadRequest},
+		{"no key", func(r *http.Request) { r.Header.Del("Sec-WebSocket-Key") }, http.StatusBadRequest},
+		// The recorder can't be hijacked.
+		{"not hijackable", func(r *http.Request) {}, http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		r := valid()
+		tt.modify(r)
+		w := httptest.NewRecorder()
+		if c, err := Upgrade(w, r); err == nil || c != nil || w.Code != tt.status {
+			t.Errorf("%s: upgrade %v, status %d, want %d", tt.name, err, w.Code, tt.status)
+		}
+	}
+}